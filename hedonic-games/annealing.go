@@ -0,0 +1,383 @@
+// annealing.go - имитация отжига для сэмплера Гиббса ML-модели
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// ScheduleKind - закон изменения обратной температуры β
+type ScheduleKind string
+
+const (
+	ScheduleList        ScheduleKind = "list"        // явный список β (Betas)
+	ScheduleGeometric   ScheduleKind = "geometric"   // β растёт в геометрической прогрессии от BetaStart до BetaEnd
+	ScheduleLinear      ScheduleKind = "linear"      // β растёт линейно от BetaStart до BetaEnd
+	ScheduleLogarithmic ScheduleKind = "logarithmic" // β = BetaStart·log2(step+2), не выше BetaEnd
+	ScheduleAdaptive    ScheduleKind = "adaptive"    // β подстраивается под долю принятых ходов
+)
+
+// ParseScheduleKind разбирает имя расписания ("" - расписания нет, фиксированная β)
+func ParseScheduleKind(name string) (ScheduleKind, error) {
+	switch kind := ScheduleKind(name); kind {
+	case "", ScheduleList, ScheduleGeometric, ScheduleLinear, ScheduleLogarithmic, ScheduleAdaptive:
+		return kind, nil
+	}
+	return "", fmt.Errorf("неизвестное расписание отжига %q", name)
+}
+
+// ConvergenceCriterion - условия остановки прохода отжига (0 - условие не проверяется).
+// Счётчики идут через температуры и сбрасываются в начале каждого прохода
+type ConvergenceCriterion struct {
+	PlateauSweeps int     // лучшая цель не выросла больше чем на Tolerance за столько проходов Гиббса
+	Tolerance     float64 // минимальный рост цели, который считается улучшением
+	StableSweeps  int     // ни один узел не сменил сообщество столько проходов подряд
+}
+
+// AnnealingSchedule - расписание отжига
+type AnnealingSchedule struct {
+	Kind          ScheduleKind
+	Betas         []float64 // для ScheduleList
+	BetaStart     float64   // начальная β (для геометрического, логарифмического и адаптивного > 0)
+	BetaEnd       float64   // конечная β (для адаптивного - верхняя граница)
+	Steps         int       // число температур (для списка - len(Betas))
+	SweepsPerStep int       // проходов Гиббса на одной температуре
+	BurnIn        int       // проходов на каждой температуре до учёта цели
+
+	// ScheduleAdaptive: после каждой температуры β умножается на
+	// exp(AdaptRate·(доля принятых - TargetAcceptance)): много принятых ходов - охлаждаем, мало - греем
+	TargetAcceptance float64
+	AdaptRate        float64
+
+	// Досрочный уход на следующую температуру: StepPlateau проходов подряд без
+	// улучшения лучшей цели, но не раньше StepMinSweeps проходов (0 - не уходить)
+	StepPlateau   int
+	StepMinSweeps int
+
+	Convergence ConvergenceCriterion
+
+	// Повторный нагрев: после окончания прохода (расписание пройдено или сошлось)
+	// отжиг до Reheats раз начинается заново с лучшего разбиения. Новый проход
+	// стартует с β = BetaStart + ReheatFraction·(β_конца - BetaStart)
+	// (0 - полный нагрев, для списка - пропуск доли начальных β)
+	Reheats        int
+	ReheatFraction float64
+}
+
+// DefaultAnnealingSchedule - расписание вида kind до betaEnd за 10 температур
+func DefaultAnnealingSchedule(kind ScheduleKind, betaEnd float64) AnnealingSchedule {
+	return AnnealingSchedule{
+		Kind:             kind,
+		BetaStart:        betaEnd / 100,
+		BetaEnd:          betaEnd,
+		Steps:            10,
+		SweepsPerStep:    10,
+		TargetAcceptance: 0.2,
+		AdaptRate:        1.0,
+		ReheatFraction:   0.5,
+	}
+}
+
+// LegacySchedule - расписание прежнего MaximumLikelihoodImproved: список β,
+// на каждой температуре burnIn проходов прогрева и до sweeps проходов,
+// уход дальше после 6 проходов без улучшения (но не раньше 12-го)
+func LegacySchedule(betas []float64, sweeps, burnIn int) AnnealingSchedule {
+	return AnnealingSchedule{
+		Kind:          ScheduleList,
+		Betas:         betas,
+		Steps:         len(betas),
+		SweepsPerStep: sweeps,
+		BurnIn:        burnIn,
+		StepPlateau:   6,
+		StepMinSweeps: 12,
+	}
+}
+
+// Validate проверяет согласованность параметров
+func (s AnnealingSchedule) Validate() error {
+	if _, err := ParseScheduleKind(string(s.Kind)); err != nil || s.Kind == "" {
+		return fmt.Errorf("неизвестное расписание отжига %q", s.Kind)
+	}
+	switch {
+	case s.Kind == ScheduleList && len(s.Betas) == 0:
+		return fmt.Errorf("отжиг: пустой список β")
+	case s.Kind != ScheduleList && s.Steps < 1:
+		return fmt.Errorf("отжиг: нужна хотя бы одна температура")
+	case s.SweepsPerStep < 0 || s.BurnIn < 0:
+		return fmt.Errorf("отжиг: отрицательное число проходов")
+	case s.Kind != ScheduleList && s.Kind != ScheduleLinear && s.BetaStart <= 0:
+		return fmt.Errorf("отжиг %s: начальная β должна быть положительной", s.Kind)
+	case s.Kind == ScheduleAdaptive && (s.TargetAcceptance <= 0 || s.TargetAcceptance >= 1):
+		return fmt.Errorf("отжиг: целевая доля принятых ходов %g вне (0, 1)", s.TargetAcceptance)
+	case s.ReheatFraction < 0 || s.ReheatFraction > 1:
+		return fmt.Errorf("отжиг: доля нагрева %g вне [0, 1]", s.ReheatFraction)
+	}
+	return nil
+}
+
+// steps - число температур одного прохода
+func (s AnnealingSchedule) steps() int {
+	if s.Kind == ScheduleList {
+		return len(s.Betas)
+	}
+	return s.Steps
+}
+
+// beta - β на шаге step прохода, начатого с start; prev и acceptance -
+// β и доля принятых ходов предыдущей температуры (нужны адаптивному расписанию)
+func (s AnnealingSchedule) beta(step int, start, prev, acceptance float64) float64 {
+	t := 0.0
+	if s.Steps > 1 {
+		t = float64(step) / float64(s.Steps-1)
+	}
+	switch s.Kind {
+	case ScheduleList:
+		return s.Betas[step]
+	case ScheduleGeometric:
+		return start * math.Pow(s.BetaEnd/start, t)
+	case ScheduleLinear:
+		return start + (s.BetaEnd-start)*t
+	case ScheduleLogarithmic:
+		return math.Min(start*math.Log2(float64(step)+2), s.BetaEnd)
+	case ScheduleAdaptive:
+		if step == 0 {
+			return start
+		}
+		return math.Min(prev*math.Exp(s.AdaptRate*(acceptance-s.TargetAcceptance)), s.BetaEnd)
+	}
+	return s.BetaEnd
+}
+
+// TemperatureStats - статистика одной температуры отжига
+type TemperatureStats struct {
+	Pass           int     // номер прохода (0 - первый, далее - после нагревов)
+	Step           int     // номер температуры в проходе
+	Beta           float64 // обратная температура
+	Sweeps         int     // выполнено проходов Гиббса (с прогревом)
+	Proposals      int     // предложено переходов (по одному на узел за проход)
+	Accepted       int     // переходов со сменой сообщества
+	AcceptanceRate float64 // Accepted / Proposals
+	MeanObjective  float64 // средняя цель после проходов без прогрева
+	BestObjective  float64 // лучшая цель на этой температуре
+}
+
+// Anneal - отжиг от разбиения partition по расписанию (partition меняется).
+// Переходы - проходы Гиббса selectNewCommunityImproved, а при ml.Constraints -
+// ConstrainedGibbsSweep. Цель (ml.Objective), профильное правдоподобие и,
+// для EM, выведенное α пишутся в историю после каждого прохода без прогрева
+func (ml *MLModel) Anneal(partition *Partition, schedule AnnealingSchedule) (*GibbsSamplingResult, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	return ml.anneal(newAnnealState(ml, partition, schedule), schedule, nil)
+}
+
+// AnnealState - состояние отжига между проходами Гиббса: позиция в циклах
+// (нагрев, температура, проход), их счётчики и результат на этот момент.
+// С него anneal продолжает ровно с того же места (см. checkpoint.go)
+type AnnealState struct {
+	Result    *GibbsSamplingResult
+	Partition *Partition // текущее разбиение
+
+	Pass, Step, Sweep int  // следующий проход Гиббса
+	InPass, InStep    bool // нагрев и температура уже начаты (их счётчики заведены)
+
+	Start      float64 // начальная β нагрева
+	LastBeta   float64 // β предыдущей температуры
+	Acceptance float64 // доля принятых ходов предыдущей температуры
+	Plateau    int
+	Stable     int
+	SinceBest  int
+	Reason     string
+	Stats      TemperatureStats // статистика текущей температуры
+
+	// Параметры модели на этот момент: β задаёт температура, α, Pin, Pout меняет EM
+	Beta, Alpha, Pin, Pout float64
+}
+
+// newAnnealState - состояние перед первым проходом отжига
+func newAnnealState(ml *MLModel, partition *Partition, schedule AnnealingSchedule) *AnnealState {
+	return &AnnealState{
+		Result: &GibbsSamplingResult{
+			BestPartition: partition.Clone(),
+			BestObjective: math.Inf(-1),
+			OptimalAlpha:  ml.Alpha,
+			StopReason:    "schedule",
+		},
+		Partition: partition,
+		Start:     schedule.BetaStart,
+	}
+}
+
+// anneal - отжиг с состояния st (расписание уже проверено). save, если задан,
+// получает состояние перед каждым проходом Гиббса
+func (ml *MLModel) anneal(st *AnnealState, schedule AnnealingSchedule, save func(*AnnealState)) (*GibbsSamplingResult, error) {
+	res := st.Result
+	for ; st.Pass <= schedule.Reheats; st.Pass++ {
+		if !st.InPass {
+			if st.Pass > 0 {
+				st.Partition = res.BestPartition.Clone()
+				res.Reheats++
+			}
+			st.Step, st.LastBeta, st.Acceptance = 0, st.Start, 0.0
+			if st.Pass > 0 && schedule.Kind == ScheduleList {
+				st.Step = int(schedule.ReheatFraction * float64(len(schedule.Betas)-1))
+			}
+			st.Plateau, st.Stable = 0, 0
+			st.Reason = "schedule"
+			st.InPass = true
+		}
+
+	temperatures:
+		for ; st.Step < schedule.steps(); st.Step++ {
+			if !st.InStep {
+				ml.Beta = schedule.beta(st.Step, st.Start, st.LastBeta, st.Acceptance)
+				st.Stats = TemperatureStats{Pass: st.Pass, Step: st.Step, Beta: ml.Beta, BestObjective: math.Inf(-1)}
+				st.Sweep, st.SinceBest = 0, 0
+				st.InStep = true
+			}
+			stats := &st.Stats
+
+			for ; st.Sweep < schedule.BurnIn+schedule.SweepsPerStep; st.Sweep++ {
+				if save != nil {
+					st.Beta, st.Alpha, st.Pin, st.Pout = ml.Beta, ml.Alpha, ml.Pin, ml.Pout
+					save(st)
+				}
+				if isCancelled(ml.Cancel) {
+					st.Reason = "cancelled"
+					ml.finishTemperature(res, stats, stats.Sweeps-min(stats.Sweeps, schedule.BurnIn))
+					st.InStep = false
+					break temperatures
+				}
+				proposals, accepted, err := ml.gibbsSweep(st.Partition)
+				if err != nil {
+					return nil, err
+				}
+				stats.Sweeps++
+				stats.Proposals += proposals
+				stats.Accepted += accepted
+				if accepted == 0 {
+					st.Stable++
+				} else {
+					st.Stable = 0
+				}
+				if st.Sweep < schedule.BurnIn {
+					continue
+				}
+
+				objective := ml.objective(st.Partition)
+				res.ObjectiveHistory = append(res.ObjectiveHistory, objective)
+				res.LikelihoodHistory = append(res.LikelihoodHistory, ml.ProfileLikelihood(st.Partition))
+				if ml.Objective == ObjectiveEM {
+					res.AlphaHistory = append(res.AlphaHistory, ml.Alpha)
+				}
+				stats.MeanObjective += objective
+				stats.BestObjective = math.Max(stats.BestObjective, objective)
+
+				if objective > res.BestObjective {
+					if objective > res.BestObjective+schedule.Convergence.Tolerance {
+						st.Plateau = 0
+					} else {
+						st.Plateau++
+					}
+					res.BestObjective = objective
+					res.BestPartition = st.Partition.Clone()
+					res.ConvergedAt = len(res.ObjectiveHistory) - 1
+					st.SinceBest = 0
+				} else {
+					st.Plateau++
+					st.SinceBest++
+				}
+
+				measured := st.Sweep - schedule.BurnIn + 1
+				c := schedule.Convergence
+				if c.PlateauSweeps > 0 && st.Plateau >= c.PlateauSweeps {
+					st.Reason = "plateau"
+				}
+				if c.StableSweeps > 0 && st.Stable >= c.StableSweeps {
+					st.Reason = "stable"
+				}
+				if st.Reason != "schedule" {
+					ml.finishTemperature(res, stats, measured)
+					st.InStep = false
+					break temperatures
+				}
+				if schedule.StepPlateau > 0 && st.SinceBest >= schedule.StepPlateau && measured >= schedule.StepMinSweeps {
+					break
+				}
+			}
+
+			ml.finishTemperature(res, stats, stats.Sweeps-min(stats.Sweeps, schedule.BurnIn))
+			st.LastBeta, st.Acceptance = stats.Beta, stats.AcceptanceRate
+			st.InStep = false
+		}
+
+		st.InPass = false
+		res.StopReason = st.Reason
+		if st.Reason == "cancelled" {
+			break
+		}
+		st.Start = schedule.BetaStart + schedule.ReheatFraction*(st.LastBeta-schedule.BetaStart)
+	}
+
+	res.TotalIterations = len(res.ObjectiveHistory)
+	res.NumCommunities = res.BestPartition.NumCommunities()
+	ml.ComputeLikelihood(res.BestPartition)
+	res.OptimalPin, res.OptimalPout = ml.Pin, ml.Pout
+	if ml.Objective == ObjectiveEM {
+		res.OptimalAlpha = ml.FitAlpha(res.BestPartition)
+	}
+	return res, nil
+}
+
+// finishTemperature дописывает статистику температуры в результат
+func (ml *MLModel) finishTemperature(res *GibbsSamplingResult, stats *TemperatureStats, measured int) {
+	if stats.Proposals > 0 {
+		stats.AcceptanceRate = float64(stats.Accepted) / float64(stats.Proposals)
+	}
+	if measured > 0 {
+		stats.MeanObjective /= float64(measured)
+	} else {
+		stats.BestObjective = math.NaN()
+	}
+	res.Temperatures = append(res.Temperatures, *stats)
+}
+
+// gibbsSweep - один проход Гиббса по узлам (или блокам ограничений);
+// для ObjectiveEM перед проходом Pin/Pout и α оцениваются по текущему разбиению.
+// Возвращает число предложенных переходов и число узлов, сменивших сообщество
+func (ml *MLModel) gibbsSweep(partition *Partition) (int, int, error) {
+	if ml.Objective == ObjectiveEM {
+		ml.FitAlpha(partition)
+	}
+	nodes := ml.G.GetNodeList()
+	if ml.Constraints != nil {
+		before := make([]int, len(nodes))
+		for i, node := range nodes {
+			before[i] = partition.Community(node)
+		}
+		if err := ml.ConstrainedGibbsSweep(partition); err != nil {
+			return 0, 0, err
+		}
+		accepted := 0
+		for i, node := range nodes {
+			if partition.Community(node) != before[i] {
+				accepted++
+			}
+		}
+		return len(nodes), accepted, nil
+	}
+
+	accepted := 0
+	st := ml.newSamplerState(partition)
+	for _, node := range nodes {
+		old := partition.Community(node)
+		block := []int{node}
+		links := st.blockLinks(block)
+		if comm := selectNewCommunityImproved(ml, st, node, links); comm != old {
+			st.moveBlock(block, links, comm)
+			accepted++
+		}
+	}
+	return len(nodes), accepted, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// TestScheduleBetas - β по шагам для каждого закона
+func TestScheduleBetas(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule AnnealingSchedule
+		want     []float64
+	}{
+		{"geometric", AnnealingSchedule{Kind: ScheduleGeometric, BetaStart: 0.01, BetaEnd: 1, Steps: 3}, []float64{0.01, 0.1, 1}},
+		{"linear", AnnealingSchedule{Kind: ScheduleLinear, BetaStart: 0, BetaEnd: 2, Steps: 5}, []float64{0, 0.5, 1, 1.5, 2}},
+		{"logarithmic", AnnealingSchedule{Kind: ScheduleLogarithmic, BetaStart: 1, BetaEnd: 2.2, Steps: 4}, []float64{1, math.Log2(3), 2, 2.2}},
+		{"list", AnnealingSchedule{Kind: ScheduleList, Betas: []float64{3, 1, 2}}, []float64{3, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.schedule.steps() != len(tt.want) {
+				t.Fatalf("температур %d, ожидалось %d", tt.schedule.steps(), len(tt.want))
+			}
+			for step, want := range tt.want {
+				if got := tt.schedule.beta(step, tt.schedule.BetaStart, 0, 0); math.Abs(got-want) > 1e-12 {
+					t.Errorf("β[%d] = %g, ожидалось %g", step, got, want)
+				}
+			}
+		})
+	}
+
+	// адаптивное: много принятых ходов - охлаждаем, мало - греем, не выше BetaEnd
+	adaptive := AnnealingSchedule{Kind: ScheduleAdaptive, BetaStart: 0.5, BetaEnd: 4, Steps: 5, TargetAcceptance: 0.2, AdaptRate: 1}
+	if got := adaptive.beta(0, 0.5, 0, 0.9); got != 0.5 {
+		t.Errorf("адаптивное β[0] = %g, ожидалось начальное 0.5", got)
+	}
+	if got := adaptive.beta(1, 0.5, 1, 0.7); math.Abs(got-math.Exp(0.5)) > 1e-12 {
+		t.Errorf("при доле принятых 0.7 β = %g, ожидалось %g", got, math.Exp(0.5))
+	}
+	if got := adaptive.beta(1, 0.5, 1, 0); got >= 1 {
+		t.Errorf("без принятых ходов β = %g должна уменьшиться", got)
+	}
+	if got := adaptive.beta(1, 0.5, 3.9, 1); got != 4 {
+		t.Errorf("β = %g выше BetaEnd", got)
+	}
+}
+
+// TestScheduleValidate - несогласованные расписания отклоняются
+func TestScheduleValidate(t *testing.T) {
+	good := DefaultAnnealingSchedule(ScheduleGeometric, 2)
+	bad := map[string]func(s *AnnealingSchedule){
+		"kind":       func(s *AnnealingSchedule) { s.Kind = "cosine" },
+		"no kind":    func(s *AnnealingSchedule) { s.Kind = "" },
+		"empty list": func(s *AnnealingSchedule) { s.Kind = ScheduleList },
+		"steps":      func(s *AnnealingSchedule) { s.Steps = 0 },
+		"sweeps":     func(s *AnnealingSchedule) { s.BurnIn = -1 },
+		"start":      func(s *AnnealingSchedule) { s.BetaStart = 0 },
+		"acceptance": func(s *AnnealingSchedule) { s.Kind, s.TargetAcceptance = ScheduleAdaptive, 1 },
+		"reheat":     func(s *AnnealingSchedule) { s.ReheatFraction = 1.5 },
+	}
+	if err := good.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, mutate := range bad {
+		s := good
+		mutate(&s)
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: расписание должно быть отклонено", name)
+		}
+	}
+	linear := good
+	linear.Kind, linear.BetaStart = ScheduleLinear, 0
+	if err := linear.Validate(); err != nil {
+		t.Errorf("линейное расписание может начинаться с β = 0: %v", err)
+	}
+}
+
+// TestAnnealBookkeeping - температуры, история и лучшее разбиение согласованы
+// с расписанием, нагревами и целью модели
+func TestAnnealBookkeeping(t *testing.T) {
+	g := LoadKarateClub()
+	schedule := DefaultAnnealingSchedule(ScheduleGeometric, 3)
+	schedule.Steps, schedule.SweepsPerStep, schedule.BurnIn, schedule.Reheats = 4, 5, 2, 1
+
+	ml := NewMLModel(g, 0.3, 1)
+	ml.SetSeed(3)
+	res, err := ml.Anneal(initializeRandomPartition(g, 4, NewRand(3)), schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StopReason != "schedule" || res.Reheats != 1 || len(res.Temperatures) != 8 {
+		t.Fatalf("остановка %q, нагревов %d, температур %d", res.StopReason, res.Reheats, len(res.Temperatures))
+	}
+	for i, stats := range res.Temperatures[:4] {
+		if want := schedule.beta(i, schedule.BetaStart, 0, 0); math.Abs(stats.Beta-want) > 1e-12 {
+			t.Errorf("температура %d: β = %g, ожидалось %g", i, stats.Beta, want)
+		}
+		if stats.Sweeps != 7 || stats.Proposals != 7*g.NumNodes() {
+			t.Errorf("температура %d: проходов %d, предложений %d", i, stats.Sweeps, stats.Proposals)
+		}
+	}
+	// второй проход начинается с середины между BetaStart и последней β
+	if want := schedule.BetaStart + 0.5*(3-schedule.BetaStart); math.Abs(res.Temperatures[4].Beta-want) > 1e-12 {
+		t.Errorf("β после нагрева %g, ожидалось %g", res.Temperatures[4].Beta, want)
+	}
+	if len(res.ObjectiveHistory) != 8*5 || len(res.LikelihoodHistory) != len(res.ObjectiveHistory) || res.TotalIterations != 40 {
+		t.Errorf("история %d/%d точек, всего %d, ожидалось 40 (прогрев не пишется)",
+			len(res.ObjectiveHistory), len(res.LikelihoodHistory), res.TotalIterations)
+	}
+	if best := slices.Max(res.ObjectiveHistory); best != res.BestObjective || res.ObjectiveHistory[res.ConvergedAt] != best {
+		t.Errorf("лучшая цель %g на проходе %d, максимум истории %g", res.BestObjective, res.ConvergedAt, best)
+	}
+	if got := ml.objective(res.BestPartition); math.Abs(got-res.BestObjective) > 1e-9 {
+		t.Errorf("цель лучшего разбиения %g, записано %g", got, res.BestObjective)
+	}
+}
+
+// TestAnnealStopping - сходимость по неподвижности и отмена
+func TestAnnealStopping(t *testing.T) {
+	g := LoadKarateClub()
+
+	cold := AnnealingSchedule{Kind: ScheduleList, Betas: []float64{50}, SweepsPerStep: 1000,
+		Convergence: ConvergenceCriterion{StableSweeps: 3}}
+	ml := NewMLModel(g, 0.3, 1)
+	ml.SetSeed(1)
+	res, err := ml.Anneal(initializeRandomPartition(g, 3, NewRand(1)), cold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StopReason != "stable" || res.TotalIterations >= 1000 {
+		t.Errorf("при β = 50 остановка %q после %d проходов, ожидалась stable", res.StopReason, res.TotalIterations)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	ml.Cancel = cancel
+	res, err = ml.Anneal(initializeRandomPartition(g, 3, NewRand(1)), DefaultAnnealingSchedule(ScheduleLinear, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StopReason != "cancelled" || res.TotalIterations != 0 || len(res.Temperatures) != 1 {
+		t.Errorf("после отмены: %q, проходов %d, температур %d", res.StopReason, res.TotalIterations, len(res.Temperatures))
+	}
+}
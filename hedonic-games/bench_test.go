@@ -0,0 +1,165 @@
+// bench_test.go - сравнение производительности Partition и прежних разбиений map[int]int
+//
+//	go test -run '^$' -bench . -benchtime 1s
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// benchGraphs - графы замеров: karate и синтетический граф из 10 000 узлов
+var benchGraphs = []string{"karate", "caveman:2000x5"}
+
+// benchCase - операция, измеряемая на старом (map) и новом (Partition) представлении
+type benchCase struct {
+	name   string
+	legacy func(m map[int]int) // nil - старой реализации нет
+	dense  func(p *Partition)
+}
+
+func benchCases(g *Graph) []benchCase {
+	ml := NewMLModel(g, 0.3, 1.0)
+	return []benchCase{
+		{
+			name:   "NumCommunities",
+			legacy: func(m map[int]int) { legacyNumCommunities(m) },
+			dense:  func(p *Partition) { p.NumCommunities() },
+		},
+		{
+			name:   "CommunityStructure",
+			legacy: func(m map[int]int) { legacyGroupByCommunity(m) },
+			dense:  func(p *Partition) { p.Groups() },
+		},
+		{
+			name:   "Clone",
+			legacy: func(m map[int]int) { legacyCopyPartition(m) },
+			dense:  func(p *Partition) { p.Clone() },
+		},
+		{
+			name:   "Modularity",
+			legacy: func(m map[int]int) { legacyModularity(g, m) },
+			dense:  func(p *Partition) { ComputeModularity(g, p) },
+		},
+		{
+			name:   "MLObjective",
+			legacy: func(m map[int]int) { legacyObjective(g, 0.3, m) },
+			dense:  func(p *Partition) { ml.ComputeObjectiveFunction(p) },
+		},
+		{
+			name: "NashDynamics",
+			dense: func(p *Partition) {
+				game := NewHedonicGame(*g, 0.3)
+				game.FindNashStablePartition_WithPotential(100, false)
+			},
+		},
+	}
+}
+
+// BenchmarkPartition - операции над равновесным разбиением: граф/операция/представление
+func BenchmarkPartition(b *testing.B) {
+	for _, spec := range benchGraphs {
+		g, _, name, err := loadGraphSpec(spec)
+		if err != nil {
+			b.Fatal(err)
+		}
+		hg := NewHedonicGame(*g, 0.3)
+		hg.SetSeed(1)
+		partition, err := hg.FindNashStablePartition_WithPotential(100, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		legacy := partition.ToMap()
+
+		b.Run(name, func(b *testing.B) {
+			for _, bc := range benchCases(g) {
+				b.Run(bc.name, func(b *testing.B) {
+					if bc.legacy != nil {
+						b.Run("map", func(b *testing.B) {
+							for b.Loop() {
+								bc.legacy(legacy)
+							}
+						})
+					}
+					b.Run("Partition", func(b *testing.B) {
+						for b.Loop() {
+							bc.dense(partition)
+						}
+					})
+				})
+			}
+		})
+	}
+}
+
+// ============================================================
+// ПРЕЖНИЕ РЕАЛИЗАЦИИ НА map[int]int (только для сравнения)
+// ============================================================
+
+func legacyGroupByCommunity(partition map[int]int) map[int][]int {
+	comms := make(map[int][]int)
+	for _, node := range slices.Sorted(maps.Keys(partition)) {
+		comm := partition[node]
+		comms[comm] = append(comms[comm], node)
+	}
+	return comms
+}
+
+func legacyNumCommunities(partition map[int]int) int {
+	comms := make(map[int]bool)
+	for _, comm := range partition {
+		comms[comm] = true
+	}
+	return len(comms)
+}
+
+func legacyCopyPartition(partition map[int]int) map[int]int {
+	res := make(map[int]int, len(partition))
+	for k, v := range partition {
+		res[k] = v
+	}
+	return res
+}
+
+// legacyModularity - модулярность перебором всех пар узлов, O(n²)
+func legacyModularity(g *Graph, partition map[int]int) float64 {
+	m := g.TotalWeight()
+	if m == 0 {
+		return 0
+	}
+
+	nodes := g.GetNodeList()
+	degree := make(map[int]float64, len(nodes))
+	for _, u := range nodes {
+		degree[u] = g.Degree(u)
+	}
+
+	Q := 0.0
+	for _, u := range nodes {
+		for _, v := range nodes {
+			if partition[u] == partition[v] {
+				Q += g.Weight(u, v) - degree[u]*degree[v]/(2*m)
+			}
+		}
+	}
+	return Q / (2 * m)
+}
+
+// legacyObjective - ComputeObjectiveFunction с пересборкой сообществ на каждом вызове
+func legacyObjective(g *Graph, alpha float64, partition map[int]int) float64 {
+	comms := legacyGroupByCommunity(partition)
+
+	var totalInnerEdges, sumNk2 float64
+	for _, comm := range SortedCommunityIDs(comms) {
+		nodes := comms[comm]
+		for i, u := range nodes {
+			for _, v := range nodes[i+1:] {
+				totalInnerEdges += g.Weight(u, v)
+			}
+		}
+		nk := float64(len(nodes))
+		sumNk2 += nk * nk
+	}
+	return totalInnerEdges - 0.5*sumNk2*alpha
+}
@@ -0,0 +1,218 @@
+// better_response.go - динамика улучшающих ответов по индивидуальной полезности агентов
+package main
+
+import (
+	"fmt"
+)
+
+// Scheduler - порядок, в котором агенты получают право хода
+type Scheduler string
+
+const (
+	SchedulerRoundRobin   Scheduler = "round-robin" // по возрастанию номера узла
+	SchedulerRandomOrder  Scheduler = "random"      // случайная перестановка в каждом раунде
+	SchedulerMaxGainFirst Scheduler = "max-gain"    // ходит агент с наибольшим выигрышем
+)
+
+// ParseScheduler разбирает имя планировщика
+func ParseScheduler(name string) (Scheduler, error) {
+	switch Scheduler(name) {
+	case SchedulerRoundRobin, SchedulerRandomOrder, SchedulerMaxGainFirst:
+		return Scheduler(name), nil
+	case "rr":
+		return SchedulerRoundRobin, nil
+	}
+	return "", fmt.Errorf("неизвестный планировщик %q (round-robin | random | max-gain)", name)
+}
+
+// MoveRecord - один ход в динамике
+type MoveRecord struct {
+	Step  int     // номер хода с начала динамики
+	Round int     // номер раунда
+	Node  int     // кто ходит
+	From  int     // откуда
+	To    int     // куда
+	Gain  float64 // прирост полезности агента
+}
+
+// FindStablePartition_BetterResponse - динамика, в которой агент переходит в другую
+// коалицию только при строгом росте своей полезности
+// u_i(S) = (друзья в S) - α·(незнакомцы в S) (или по hg.Preference), а не потенциала всего разбиения.
+// Для моделей без потенциала динамика может зацикливаться - её ограничивает maxRounds.
+// При Constraints ходят блоки обязательных связей (если выигрывает каждый член блока)
+// и только допустимыми ходами.
+// Ходы записываются в hg.Trace. Раунд - один проход по всем агентам
+// (для max-gain - не более n ходов).
+func (hg *HedonicGame) FindStablePartition_BetterResponse(maxRounds int, scheduler Scheduler) (*Partition, error) {
+	hg.RebuildAggregates()
+	hg.Trace = nil
+
+	ci, err := hg.constraintIndex()
+	if err != nil {
+		return hg.Partition, err
+	}
+	var movers [][]int
+	if ci != nil {
+		for _, rep := range ci.order {
+			movers = append(movers, ci.blocks[rep])
+		}
+	} else {
+		for _, node := range hg.G.GetNodeList() {
+			movers = append(movers, []int{node})
+		}
+	}
+	respond := func(block []int) (int, float64) {
+		if ci == nil {
+			return hg.bestUtilityResponse(block[0])
+		}
+		return hg.bestConstrainedMove(ci, block, hg.blockUtilityGain)
+	}
+	move := func(round int, block []int, comm int, gain float64) {
+		for _, node := range block {
+			hg.recordMove(round, node, comm, gain)
+		}
+	}
+
+	for round := 0; round < maxRounds && !isCancelled(hg.Cancel); round++ {
+		moved := false
+
+		switch scheduler {
+		case SchedulerMaxGainFirst:
+			for step := 0; step < len(movers); step++ {
+				var bestBlock []int
+				bestComm, bestGain := 0, 0.0
+				for _, block := range movers {
+					comm, gain := respond(block)
+					if gain > bestGain+stabilityEps {
+						bestBlock, bestComm, bestGain = block, comm, gain
+					}
+				}
+				if bestBlock == nil {
+					break
+				}
+				move(round, bestBlock, bestComm, bestGain)
+				moved = true
+			}
+
+		default:
+			order := movers
+			if scheduler == SchedulerRandomOrder {
+				order = make([][]int, len(movers))
+				copy(order, movers)
+				hg.Rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+			}
+			for _, block := range order {
+				comm, gain := respond(block)
+				if gain > stabilityEps {
+					move(round, block, comm, gain)
+					moved = true
+				}
+			}
+		}
+
+		hg.Iterations = round + 1
+		if !moved {
+			break
+		}
+	}
+
+	return hg.Partition, nil
+}
+
+func (hg *HedonicGame) recordMove(round, node, comm int, gain float64) {
+	hg.Trace = append(hg.Trace, MoveRecord{
+		Step:  len(hg.Trace),
+		Round: round,
+		Node:  node,
+		From:  hg.Partition.Community(node),
+		To:    comm,
+		Gain:  gain,
+	})
+	hg.MoveNode(node, comm)
+}
+
+// bestUtilityResponse - лучший ответ агента по его полезности среди соседних
+// коалиций и новой одиночной. Возвращает коалицию и выигрыш (0 - остаться).
+func (hg *HedonicGame) bestUtilityResponse(node int) (int, float64) {
+	if hg.Preference != nil {
+		return hg.bestPreferenceResponse(node)
+	}
+
+	links, counts := hg.communityLinkCounts(node)
+	from := hg.Partition.Community(node)
+	current := hg.utilityIn(node, from, links, counts)
+
+	bestComm, bestGain := from, 0.0
+	for _, comm := range SortedCommunityIDs(links) {
+		if comm == from {
+			continue
+		}
+		if gain := hg.utilityIn(node, comm, links, counts) - current; gain > bestGain {
+			bestComm, bestGain = comm, gain
+		}
+	}
+
+	// Уйти в одиночку: полезность 0
+	if hg.Partition.Size(from) > 1 && -current > bestGain {
+		bestComm, bestGain = hg.freshCommunityID(node), -current
+	}
+
+	return bestComm, bestGain
+}
+
+// bestPreferenceResponse - лучший ответ по произвольной Preference.
+// Кандидаты - все непустые коалиции (для анонимных и дробных предпочтений
+// выгодной может оказаться и коалиция без соседей) и одиночная.
+func (hg *HedonicGame) bestPreferenceResponse(node int) (int, float64) {
+	from := hg.Partition.Community(node)
+	current := hg.Preference.Value(&hg.G, node, hg.communityMembers(from))
+
+	bestComm, bestGain := from, 0.0
+	for _, comm := range hg.Partition.Communities() {
+		if comm == from {
+			continue
+		}
+		joined := append(hg.communityMembers(comm), node)
+		if gain := hg.Preference.Value(&hg.G, node, joined) - current; gain > bestGain {
+			bestComm, bestGain = comm, gain
+		}
+	}
+
+	if hg.Partition.Size(from) > 1 {
+		if gain := hg.Preference.Value(&hg.G, node, []int{node}) - current; gain > bestGain {
+			bestComm, bestGain = hg.freshCommunityID(node), gain
+		}
+	}
+
+	return bestComm, bestGain
+}
+
+// communityLinkCounts - вес и число рёбер от узла до каждого соседнего сообщества
+func (hg *HedonicGame) communityLinkCounts(node int) (map[int]float64, map[int]int) {
+	links := hg.communityLinks(node)
+	counts := make(map[int]int, len(links))
+	for _, neighbor := range hg.G.GetNeighbors(node) {
+		if neighbor != node {
+			counts[hg.Partition.Community(neighbor)]++
+		}
+	}
+	return links, counts
+}
+
+// utilityIn - полезность узла в сообществе comm (вместе с ним) за O(1) по агрегатам
+func (hg *HedonicGame) utilityIn(node, comm int, links map[int]float64, counts map[int]int) float64 {
+	others := hg.Partition.Size(comm)
+	if hg.Partition.Community(node) == comm {
+		others--
+	}
+	strangers := float64(others - counts[comm])
+	return links[comm] - hg.Alpha*strangers
+}
+
+// freshCommunityID - номер пустого сообщества для агента, уходящего в одиночку
+func (hg *HedonicGame) freshCommunityID(node int) int {
+	if hg.Partition.Size(node) == 0 {
+		return node
+	}
+	return max(hg.Partition.MaxCommunity(), 0) + 1
+}
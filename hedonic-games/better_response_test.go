@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// bruteForceGain - наибольший выигрыш агента от перехода в любую коалицию или в одиночку
+func bruteForceGain(hg *HedonicGame, node int) float64 {
+	groups := hg.Partition.Groups()
+	from := hg.Partition.Community(node)
+	current := hg.coalitionUtility(node, groups[from])
+	best := 0.0
+	for comm, members := range groups {
+		if comm != from {
+			best = max(best, hg.coalitionUtility(node, append(members, node))-current)
+		}
+	}
+	if len(groups[from]) > 1 {
+		best = max(best, hg.coalitionUtility(node, []int{node})-current)
+	}
+	return best
+}
+
+// TestBetterResponseReachesNashEquilibrium - при каждом планировщике динамика
+// сходится к разбиению, где ни один агент не выигрывает от перехода, а каждый
+// записанный ход строго улучшал полезность ходившего ровно на Gain
+func TestBetterResponseReachesNashEquilibrium(t *testing.T) {
+	schedulers := []Scheduler{SchedulerRoundRobin, SchedulerRandomOrder, SchedulerMaxGainFirst}
+	for trial := 0; trial < 6; trial++ {
+		rng := NewRand(int64(trial))
+		g := NewGraph()
+		for u := 0; u < 25; u++ {
+			g.AddNode(u)
+			for v := 0; v < u; v++ {
+				if rng.Float64() < 0.2 {
+					g.AddWeightedEdge(u, v, float64(1+rng.Intn(3)))
+				}
+			}
+		}
+		for _, scheduler := range schedulers {
+			t.Run(fmt.Sprintf("%d/%s", trial, scheduler), func(t *testing.T) {
+				hg := randomGame(g, 0.4, 4, rng)
+				replay := hg.Partition.Clone()
+				hg.SetSeed(int64(trial))
+				if _, err := hg.FindStablePartition_BetterResponse(500, scheduler); err != nil {
+					t.Fatal(err)
+				}
+				if hg.Iterations >= 500 {
+					t.Fatal("динамика не сошлась за 500 раундов")
+				}
+				for _, node := range g.GetNodeList() {
+					if gain := bruteForceGain(hg, node); gain > stabilityEps {
+						t.Errorf("агент %d выигрывает %g после сходимости", node, gain)
+					}
+				}
+
+				check := &HedonicGame{G: hg.G, Alpha: hg.Alpha, Partition: replay}
+				for _, move := range hg.Trace {
+					if replay.Community(move.Node) != move.From {
+						t.Fatalf("ход %d: агент %d в %d, а не в %d", move.Step, move.Node, replay.Community(move.Node), move.From)
+					}
+					groups := replay.Groups()
+					before := check.coalitionUtility(move.Node, groups[move.From])
+					after := check.coalitionUtility(move.Node, append(groups[move.To], move.Node))
+					if move.Gain <= stabilityEps || math.Abs(after-before-move.Gain) > 1e-9 {
+						t.Fatalf("ход %d: записан выигрыш %g, фактический %g", move.Step, move.Gain, after-before)
+					}
+					replay.Set(move.Node, move.To)
+				}
+				if NMI(replay, hg.Partition) < 1-1e-12 || replay.Len() != hg.Partition.Len() {
+					t.Error("повтор ходов из Trace не воспроизводит итоговое разбиение")
+				}
+			})
+		}
+	}
+}
+
+// TestParseScheduler - имена планировщиков и сокращение rr
+func TestParseScheduler(t *testing.T) {
+	for name, want := range map[string]Scheduler{"round-robin": SchedulerRoundRobin, "rr": SchedulerRoundRobin, "random": SchedulerRandomOrder, "max-gain": SchedulerMaxGainFirst} {
+		if got, err := ParseScheduler(name); err != nil || got != want {
+			t.Errorf("ParseScheduler(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseScheduler("fifo"); err == nil {
+		t.Error("неизвестный планировщик должен быть отклонён")
+	}
+}
@@ -0,0 +1,265 @@
+// checkpoint.go - контрольные точки и продолжение перебора MaximumLikelihoodImproved
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// ГСЧ СО СЧЁТЧИКОМ
+// ============================================================
+
+// countingSource - источник math/rand, считающий выданные числа. Состояние
+// самого источника не сериализуется, но восстанавливается точно: источник
+// с тем же зерном, из которого выбрано столько же чисел
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+// newCountingRand - генератор с зерном seed, из которого уже выбрано skip чисел.
+// Последовательность та же, что у NewRand(seed)
+func newCountingRand(seed int64, skip uint64) (*rand.Rand, *countingSource) {
+	s := &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+	for ; s.draws < skip; s.draws++ {
+		s.src.Uint64()
+	}
+	return rand.New(s), s
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// ============================================================
+// КОНТРОЛЬНАЯ ТОЧКА
+// ============================================================
+
+// MLCheckpoint - контрольная точка MaximumLikelihoodImproved: параметры перебора,
+// зерна запусков и по каждому запуску готовый результат или состояние отжига.
+// Хранится в gob: значения float64 (в том числе -Inf и NaN) сохраняются точно
+type MLCheckpoint struct {
+	// Граф, на котором идёт перебор (сверяется при продолжении)
+	NumNodes    int
+	NumEdges    int
+	TotalWeight float64
+
+	Alphas             []float64
+	NumInitializations int
+	Schedule           AnnealingSchedule
+	Objective          MLObjective
+	Runs               []MLRunCheckpoint // запуски α × инициализация в порядке свёртки
+}
+
+// MLRunCheckpoint - один запуск перебора
+type MLRunCheckpoint struct {
+	Alpha  float64
+	Seed   int64
+	Result *GibbsSamplingResult // итог запуска (nil - запуск не закончен)
+	State  *AnnealState         // состояние отжига перед очередным проходом (nil - запуск не начат)
+	Draws  uint64               // чисел ГСЧ запуска выбрано к State
+}
+
+// MLCheckpointOptions - куда и как часто сохранять контрольную точку
+type MLCheckpointOptions struct {
+	Path     string        // файл ("" - не сохранять)
+	Interval time.Duration // сохранять не чаще (0 - как только файл не занят другой записью); законченный запуск сохраняется сразу
+}
+
+// ResumeMaximumLikelihoodImproved продолжает перебор с контрольной точки opts.Path
+// и сохраняет дальнейшие точки туда же. Законченные запуски берутся из файла,
+// прерванные продолжаются с сохранённого прохода Гиббса, поэтому результат тот же,
+// что у непрерывного MaximumLikelihoodImproved с теми же параметрами и rng.
+// Граф должен быть тем же, что при первом запуске
+func ResumeMaximumLikelihoodImproved(g *Graph, workers int, opts MLCheckpointOptions) (*GibbsSamplingResult, error) {
+	cp, err := LoadMLCheckpoint(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if cp.NumNodes != g.NumNodes() || cp.NumEdges != g.NumEdges() || cp.TotalWeight != g.TotalWeight() {
+		return nil, fmt.Errorf("контрольная точка %s снята с другого графа (%d узлов, %d рёбер)",
+			opts.Path, cp.NumNodes, cp.NumEdges)
+	}
+	if err := cp.Schedule.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cp.Objective.CheckGraph(g); err != nil {
+		return nil, err
+	}
+	return runMaximumLikelihood(g, cp, workers, opts)
+}
+
+// LoadMLCheckpoint читает контрольную точку, записанную перебором
+func LoadMLCheckpoint(filename string) (*MLCheckpoint, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка чтения файла %s: %w", filename, err)
+	}
+	var cp MLCheckpoint
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cp); err != nil {
+		return nil, fmt.Errorf("%s: некорректная контрольная точка: %w", filename, err)
+	}
+	return &cp, nil
+}
+
+// Done - число законченных запусков
+func (cp *MLCheckpoint) Done() int {
+	done := 0
+	for _, run := range cp.Runs {
+		if run.Result != nil {
+			done++
+		}
+	}
+	return done
+}
+
+// annealRun выполняет запуск i: с начала (случайное разбиение и отжиг) или
+// с сохранённого состояния, восстановив ГСЧ и параметры модели
+func (cp *MLCheckpoint) annealRun(g *Graph, i int, saver *checkpointSaver) (*GibbsSamplingResult, error) {
+	saver.mu.Lock()
+	run := cp.Runs[i]
+	saver.mu.Unlock()
+
+	rng, src := newCountingRand(run.Seed, run.Draws)
+	ml := NewMLModel(g, run.Alpha, 0.0)
+	ml.Rng = rng
+	ml.Objective = cp.Objective
+	ml.Cancel = saver.stop
+
+	st := run.State
+	if st == nil {
+		partition := initializeRandomPartition(g, rng.Intn(5)+3, rng)
+		st = newAnnealState(ml, partition, cp.Schedule)
+	} else {
+		st = st.snapshot() // сохранённое состояние остаётся в точке, пока не заменено новым
+		ml.Beta, ml.Alpha, ml.Pin, ml.Pout = st.Beta, st.Alpha, st.Pin, st.Pout
+	}
+
+	var save func(*AnnealState)
+	if saver.opts.Path != "" {
+		save = func(st *AnnealState) { saver.save(i, st, src.draws) }
+	}
+	return ml.anneal(st, cp.Schedule, save)
+}
+
+// checkpointSaver пишет контрольную точку из воркеров перебора.
+// Запуски независимы, поэтому состояния разных запусков в одном файле
+// могут относиться к разным моментам. Точка кодируется и пишется вне mu:
+// под ним снимается только копия списка запусков, так что запись не
+// задерживает воркеры, сохраняющие состояние
+type checkpointSaver struct {
+	mu   sync.Mutex // cp, last, err
+	cp   *MLCheckpoint
+	opts MLCheckpointOptions
+	last time.Time     // время последней записи
+	stop chan struct{} // закрывается при первой ошибке и прерывает запуски
+	err  error         // первая ошибка записи или запуска
+
+	writeMu sync.Mutex // запись файла: по одной, каждая со свежей копией точки
+}
+
+func newCheckpointSaver(cp *MLCheckpoint, opts MLCheckpointOptions) *checkpointSaver {
+	return &checkpointSaver{cp: cp, opts: opts, last: time.Now(), stop: make(chan struct{})}
+}
+
+// save запоминает состояние запуска i и, если прошло opts.Interval, пишет файл.
+// Если файл уже пишет другой воркер, запись пропускается: состояние попадёт
+// в следующую
+func (s *checkpointSaver) save(i int, st *AnnealState, draws uint64) {
+	snapshot := st.snapshot()
+	s.mu.Lock()
+	s.cp.Runs[i].State, s.cp.Runs[i].Draws = snapshot, draws
+	due := s.err == nil && (s.opts.Interval <= 0 || time.Since(s.last) >= s.opts.Interval)
+	s.mu.Unlock()
+	if due && s.writeMu.TryLock() {
+		defer s.writeMu.Unlock()
+		s.write()
+	}
+}
+
+// finish записывает итог запуска i
+func (s *checkpointSaver) finish(i int, res *GibbsSamplingResult) {
+	s.mu.Lock()
+	s.cp.Runs[i].Result, s.cp.Runs[i].State, s.cp.Runs[i].Draws = res, nil, 0
+	s.mu.Unlock()
+	if s.opts.Path != "" {
+		s.writeMu.Lock()
+		defer s.writeMu.Unlock()
+		s.write()
+	}
+}
+
+// fail запоминает первую ошибку и прерывает остальные запуски
+func (s *checkpointSaver) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failLocked(err)
+}
+
+// failLocked - fail под s.mu
+func (s *checkpointSaver) failLocked(err error) {
+	if s.err == nil {
+		s.err = err
+		close(s.stop)
+	}
+}
+
+// write пишет файл через временный и переименование, чтобы сбой во время
+// записи не портил предыдущую точку (под s.writeMu). Сохранённые состояния
+// и итоги запусков после записи в точку не меняются, поэтому копии списка
+// запусков достаточно
+func (s *checkpointSaver) write() {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	cp := *s.cp
+	cp.Runs = slices.Clone(s.cp.Runs)
+	s.mu.Unlock()
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&cp)
+	if err == nil {
+		tmp := s.opts.Path + ".tmp"
+		if err = os.WriteFile(tmp, buf.Bytes(), 0644); err == nil {
+			err = os.Rename(tmp, s.opts.Path)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failLocked(fmt.Errorf("контрольная точка %s: %w", s.opts.Path, err))
+		return
+	}
+	s.last = time.Now()
+}
+
+// snapshot - копия состояния для контрольной точки. Истории и лучшее разбиение
+// не копируются: отжиг только дописывает истории и заменяет лучшее разбиение новым
+func (st *AnnealState) snapshot() *AnnealState {
+	c := *st
+	res := *st.Result
+	c.Result = &res
+	c.Partition = st.Partition.Clone()
+	return &c
+}
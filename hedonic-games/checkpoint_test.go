@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// interruptedCheckpoint пишет в path контрольную точку перебора, прерванного
+// перед проходом Гиббса stopAt второго запуска: первый запуск закончен,
+// второй прерван, остальные не начаты. Зёрна запусков - как у
+// MaximumLikelihoodImprovedCheckpointed с NewRand(seed)
+func interruptedCheckpoint(t *testing.T, g *Graph, alphas []float64, schedule AnnealingSchedule,
+	objective MLObjective, inits int, seed int64, stopAt int, path string) {
+	t.Helper()
+	rng := NewRand(seed)
+	cp := &MLCheckpoint{
+		NumNodes:           g.NumNodes(),
+		NumEdges:           g.NumEdges(),
+		TotalWeight:        g.TotalWeight(),
+		Alphas:             alphas,
+		NumInitializations: inits,
+		Schedule:           schedule,
+		Objective:          objective,
+	}
+	for _, alpha := range alphas {
+		for init := 0; init < inits; init++ {
+			cp.Runs = append(cp.Runs, MLRunCheckpoint{Alpha: alpha, Seed: rng.Int63()})
+		}
+	}
+	saver := newCheckpointSaver(cp, MLCheckpointOptions{Path: path})
+
+	res, err := cp.annealRun(g, 0, saver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saver.finish(0, res)
+
+	// второй запуск - как в annealRun, но с отменой после stopAt сохранений
+	run := cp.Runs[1]
+	runRng, src := newCountingRand(run.Seed, 0)
+	ml := NewMLModel(g, run.Alpha, 0.0)
+	ml.Rng = runRng
+	ml.Objective = objective
+	cancel := make(chan struct{})
+	ml.Cancel = cancel
+	st := newAnnealState(ml, initializeRandomPartition(g, runRng.Intn(5)+3, runRng), schedule)
+	saves := 0
+	if _, err := ml.anneal(st, schedule, func(st *AnnealState) {
+		saver.save(1, st, src.draws)
+		if saves++; saves == stopAt {
+			close(cancel)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if saver.err != nil {
+		t.Fatal(saver.err)
+	}
+	if saves != stopAt {
+		t.Fatalf("второй запуск закончился за %d проходов, раньше прерывания на %d", saves, stopAt)
+	}
+}
+
+// sameFloats сравнивает истории побитно (NaN равен NaN)
+func sameFloats(a, b []float64) bool {
+	return slices.EqualFunc(a, b, func(x, y float64) bool {
+		return math.Float64bits(x) == math.Float64bits(y)
+	})
+}
+
+// TestResumeMatchesUninterrupted - перебор, продолженный с контрольной точки,
+// даёт тот же результат, что и непрерывный с тем же зерном
+func TestResumeMatchesUninterrupted(t *testing.T) {
+	reheated := DefaultAnnealingSchedule(ScheduleGeometric, 2)
+	reheated.Steps, reheated.SweepsPerStep, reheated.Reheats = 4, 8, 1
+
+	tests := []struct {
+		name      string
+		schedule  AnnealingSchedule
+		objective MLObjective
+		stopAt    int
+	}{
+		{"potential/first sweep", DefaultAnnealingSchedule(ScheduleGeometric, 2), ObjectivePotential, 1},
+		{"potential/mid step", DefaultAnnealingSchedule(ScheduleGeometric, 2), ObjectivePotential, 37},
+		{"em/mid step", DefaultAnnealingSchedule(ScheduleAdaptive, 2), ObjectiveEM, 23},
+		{"legacy list", LegacySchedule([]float64{0.5, 1, 2}, 20, 2), ObjectivePotential, 30},
+		{"reheat", reheated, ObjectivePotential, 40},
+	}
+	g := LoadKarateClub()
+	alphas := []float64{0.3, 0.5}
+	const inits, seed = 2, 7
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := MaximumLikelihoodImproved(g, alphas, tt.schedule, tt.objective, inits, NewRand(seed), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(t.TempDir(), "search.ckpt")
+			interruptedCheckpoint(t, g, alphas, tt.schedule, tt.objective, inits, seed, tt.stopAt, path)
+			got, err := ResumeMaximumLikelihoodImproved(g, 2, MLCheckpointOptions{Path: path})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.BestObjective != want.BestObjective || got.OptimalAlpha != want.OptimalAlpha ||
+				got.ConvergedAt != want.ConvergedAt || got.TotalIterations != want.TotalIterations {
+				t.Errorf("итог (цель %g, α %g, проход %d из %d), без перерыва (цель %g, α %g, проход %d из %d)",
+					got.BestObjective, got.OptimalAlpha, got.ConvergedAt, got.TotalIterations,
+					want.BestObjective, want.OptimalAlpha, want.ConvergedAt, want.TotalIterations)
+			}
+			if VariationOfInformation(got.BestPartition, want.BestPartition) != 0 {
+				t.Errorf("лучшее разбиение отличается от разбиения без перерыва")
+			}
+			if !sameFloats(got.ObjectiveHistory, want.ObjectiveHistory) ||
+				!sameFloats(got.LikelihoodHistory, want.LikelihoodHistory) ||
+				!sameFloats(got.AlphaHistory, want.AlphaHistory) {
+				t.Errorf("истории отличаются от историй без перерыва")
+			}
+		})
+	}
+}
@@ -0,0 +1,1255 @@
+// cli.go - подкоманды командной строки
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const usageText = `Использование: hedonic-games <команда> [флаги]
+
+Команды:
+  run       запустить один алгоритм на одном графе
+  sweep     перебор параметров по сетке
+  inspect   вывести информацию о графе
+  validate  проверить стабильность сохранённого разбиения
+  generate  построить синтетический граф и сохранить его с эталонным разбиением
+  scan      перебор разрешения γ модулярности (или CPM) и поиск плато
+  hierarchy вложенные сообщества: дендрограмма и путь сообщества каждого узла
+  overlap   перекрывающиеся коалиции: агент в нескольких сообществах (до r)
+  update    тёплый старт: изменения графа и равновесие от затронутых узлов
+  track     сообщества во времени: снимки графа, сопоставление и события
+  mlsearch  перебор α × начальных разбиений ML-отжигом с контрольными точками
+  serve     локальный HTTP-сервис: загрузка графов, очередь запусков, результаты
+
+Граф (-graph): karate, caveman[:NxS], синтетический граф
+<модель>[:ключ=значение,...] (sbm, planted, lfr, relaxed-caveman, er;
+например lfr:n=500,mu=0.4,seed=7) или путь к файлу. Форматы файлов
+определяются по расширению или содержимому: node-link JSON (как
+ds/relations_graph.json), список рёбер (*.edgelist, *.csv), GML, GraphML,
+Pajek (*.net).
+
+Алгоритмы (-algo у run, -algos у sweep): hedonic, ml и базовые louvain,
+leiden - оптимизация Q_γ или CPM (см. -resolution, -null-model), их строки
+попадают в тот же CSV и JSON, что и у гедонической игры.
+
+Эталон (-truth у run, sweep, validate): builtin или файл разметки; в вывод
+и CSV добавляются NMI, ARI, VI, F1, чистота и точность/полнота по парам.
+
+Разрешение (-resolution, -null-model у run, sweep, validate): потенциал (7.2)
+с γ Райхардта–Борнхольдта (γ > 1 - мельче сообщества) или моделью Поттса
+с константой (cpm). scan перебирает γ по логарифмической сетке и печатает
+плато - диапазоны γ с неизменным разбиением (уровни иерархии сообществ).
+
+Предпочтения (-preference у run, validate): friends-appreciation и
+enemies-aversion строятся по графу; additively-separable (v_i(S) = Σ v_ij),
+anonymous (полезность по размеру коалиции) и fractional с заданными
+ценностями читают таблицы из -values: "i j v" - ценность j для i, "k v" -
+полезность коалиции из k агентов. В сервисе (serve) -values нет.
+
+Перекрытие (overlap): агент может состоять в r коалициях, платя -cost за
+каждое членство; начальное разбиение строит -algo. В JSON у узла community -
+основное сообщество, communities и strengths - все сообщества и сила членства.
+Эталон сравнивается по перекрывающейся NMI (McDaid) и индексу омега.
+
+Снимки (track): разбиение каждого графа из списка (с -warm - от разбиения
+предыдущего снимка), связи сообществ соседних снимков по сходству Жаккара
+имён узлов и события birth, death, growth, contraction, continue, merge,
+split в JSON и CSV рядом с разбиениями снимков.
+
+Контрольные точки (mlsearch -checkpoint): состояние перебора (разбиения,
+лучшее найденное, позиция ГСЧ и отжига, истории цели) пишется в файл не
+чаще -checkpoint-every; mlsearch -resume -checkpoint файл продолжает с того
+же прохода Гиббса и даёт тот же итог, что и перебор без перерыва.
+
+Сервис (serve): только на localhost. POST /graphs - node-link JSON (как
+ds/relations_graph.json), POST /jobs - {"graph": "g1", "params": {"algo":
+"ml", "beta": 2}} с флагами run без дефиса; GET /jobs/{id} - статус,
+DELETE /jobs/{id} - отмена, GET /jobs/{id}/partition и /jobs/{id}/result -
+разбиение как у ExportPartitionToJSON и строка результатов (?format=csv).
+Хранится не больше -max-graphs графов и -max-jobs запусков (старые
+завершённые забываются); DELETE /graphs/{id} удаляет граф без незавершённых
+запусков.
+
+Справка по флагам команды: hedonic-games <команда> -h
+`
+
+// truthUsage - справка флага -truth
+const truthUsage = "эталонное разбиение: builtin (karate, caveman, синтетические графы) или файл (*.json как у ExportPartitionToJSON, иначе \"узел метка\")"
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, usageText)
+}
+
+// ============================================================
+// ЗАГРУЗКА ГРАФА ПО ОПИСАНИЮ
+// ============================================================
+
+// loadGraphSpec загружает граф по значению флага -graph
+// Возвращает граф, имена узлов и короткое имя графа для файлов результатов
+func loadGraphSpec(spec string) (*Graph, map[int]string, string, error) {
+	switch {
+	case spec == "karate":
+		g := LoadKarateClub()
+		return g, generateNodeNames(g.NumNodes()), "karate", nil
+
+	case spec == "caveman" || strings.HasPrefix(spec, "caveman:"):
+		numCliques, cliqueSize, err := parseCavemanSpec(spec)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		g := LoadCavemanGraph(numCliques, cliqueSize)
+		return g, generateNodeNames(g.NumNodes()), "caveman", nil
+
+	case isGeneratorSpec(spec):
+		g, _, err := GenerateFromSpec(spec)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		return g, generateNodeNames(g.NumNodes()), generatorName(spec), nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(spec), filepath.Ext(spec))
+
+	g, _, idToName, err := LoadGraphFile(spec)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return g, idToName, name, nil
+}
+
+// parseCavemanSpec разбирает caveman[:NxS] (по умолчанию 6 клик по 5 узлов)
+func parseCavemanSpec(spec string) (int, int, error) {
+	numCliques, cliqueSize := 6, 5
+	if rest, ok := strings.CutPrefix(spec, "caveman:"); ok {
+		if _, err := fmt.Sscanf(rest, "%dx%d", &numCliques, &cliqueSize); err != nil {
+			return 0, 0, fmt.Errorf("caveman: ожидалось caveman:NxS, получено %q", spec)
+		}
+	}
+	return numCliques, cliqueSize, nil
+}
+
+// loadTruthSpec загружает эталон по значению флага -truth:
+// builtin - известное разбиение встроенного графа (фракции karate, клики caveman,
+// заложенное разбиение синтетического графа),
+// иначе путь к файлу (см. LoadGroundTruth). Пустое значение - эталона нет
+func loadTruthSpec(spec, graphSpec string, idToName map[int]string) (*Partition, error) {
+	switch {
+	case spec == "":
+		return nil, nil
+	case spec == "builtin" && graphSpec == "karate":
+		return KarateClubFactions(), nil
+	case spec == "builtin" && (graphSpec == "caveman" || strings.HasPrefix(graphSpec, "caveman:")):
+		numCliques, cliqueSize, err := parseCavemanSpec(graphSpec)
+		if err != nil {
+			return nil, err
+		}
+		return CavemanCliques(numCliques, cliqueSize), nil
+	case spec == "builtin" && isGeneratorSpec(graphSpec):
+		_, truth, err := GenerateFromSpec(graphSpec)
+		return truth, err
+	case spec == "builtin":
+		return nil, fmt.Errorf("у графа %s нет встроенного эталона", graphSpec)
+	}
+
+	nameToID := make(map[string]int, len(idToName))
+	for id, name := range idToName {
+		nameToID[name] = id
+	}
+	return LoadGroundTruth(spec, nameToID)
+}
+
+// ============================================================
+// ФЛАГИ
+// ============================================================
+
+// addRunFlags регистрирует флаги одного запуска
+func addRunFlags(fs *flag.FlagSet, cfg *RunConfig) {
+	fs.StringVar(&cfg.Algorithm, "algo", cfg.Algorithm, "алгоритм: hedonic | ml | louvain | leiden")
+	fs.Float64Var(&cfg.Alpha, "alpha", cfg.Alpha, "штраф за незнакомцев α")
+	fs.Float64Var(&cfg.Beta, "beta", cfg.Beta, "обратная температура (ML)")
+	fs.IntVar(&cfg.TargetK, "k", cfg.TargetK, "ровно K сообществ (-1 = не ограничено)")
+	fs.IntVar(&cfg.Constraints.MinSize, "min-size", cfg.Constraints.MinSize, "минимальный размер сообщества (0 = не ограничен)")
+	fs.IntVar(&cfg.Constraints.MaxSize, "max-size", cfg.Constraints.MaxSize, "максимальный размер сообщества (0 = не ограничен)")
+	fs.IntVar(&cfg.MaxIterations, "iter", cfg.MaxIterations, "максимум итераций")
+	fs.Int64Var(&cfg.Seed, "seed", cfg.Seed, "зерно ГСЧ (0 = случайное)")
+	fs.BoolVar(&cfg.UseModularity, "modularity", cfg.UseModularity, "потенциал по формуле (7.2) вместо (7.1)")
+	fs.Float64Var(&cfg.Resolution, "resolution", cfg.Resolution, "разрешение γ потенциала (7.2) (0 = 1, обычная модулярность)")
+	fs.StringVar(&cfg.NullModel, "null-model", cfg.NullModel, "нулевая модель потенциала (7.2): ng (Ньюман–Гирван) | cpm (модель Поттса с константой)")
+	fs.StringVar(&cfg.Dynamics, "dynamics", cfg.Dynamics, "динамика гедонической игры: potential | utility")
+	fs.StringVar(&cfg.Scheduler, "scheduler", cfg.Scheduler, "порядок ходов для -dynamics utility: round-robin | random | max-gain")
+	fs.StringVar(&cfg.Objective, "objective", cfg.Objective, "цель ML: potential | likelihood (профильное правдоподобие) | em (α из Pin/Pout) | dcsbm (likelihood и em - только для невзвешенного графа)")
+	fs.BoolVar(&cfg.Greedy, "greedy", cfg.Greedy, "жадная доводка ML после сэмплирования")
+	fs.StringVar(&cfg.SelectK, "select-k", cfg.SelectK, "выбрать число сообществ DC-SBM: bic | mdl")
+	fs.IntVar(&cfg.MaxK, "max-k", cfg.MaxK, "наибольшее K при -select-k (0 = 10)")
+	fs.StringVar(&cfg.Schedule, "schedule", cfg.Schedule, "отжиг ML: geometric | linear | logarithmic | adaptive (пусто = фиксированная β)")
+	fs.Float64Var(&cfg.BetaStart, "beta-start", cfg.BetaStart, "начальная β отжига (0 = β/100)")
+	fs.IntVar(&cfg.Reheats, "reheats", cfg.Reheats, "число повторных нагревов отжига")
+	fs.IntVar(&cfg.Plateau, "plateau", cfg.Plateau, "остановка отжига после стольких проходов без улучшения (0 = нет)")
+	fs.StringVar(&cfg.Preference, "preference", cfg.Preference, "модель предпочтений: "+PreferenceNames)
+}
+
+// loadConstraintsFile дополняет ограничения из JSON-файла (см. LoadConstraintsFromJSON):
+// связи добавляются, размеры и K берутся из файла, если не заданы флагами
+func loadConstraintsFile(path string, idToName map[int]string, c *Constraints, targetK *int) error {
+	if path == "" {
+		return nil
+	}
+	nameToID := make(map[string]int, len(idToName))
+	for id, name := range idToName {
+		nameToID[name] = id
+	}
+	loaded, err := LoadConstraintsFromJSON(path, nameToID)
+	if err != nil {
+		return err
+	}
+	c.MustLink = append(c.MustLink, loaded.MustLink...)
+	c.CannotLink = append(c.CannotLink, loaded.CannotLink...)
+	if c.MinSize == 0 {
+		c.MinSize = loaded.MinSize
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = loaded.MaxSize
+	}
+	if targetK != nil && *targetK <= 0 && loaded.ExactK > 0 {
+		*targetK = loaded.ExactK
+	}
+	return nil
+}
+
+// valuesUsage - описание флага -values
+const valuesUsage = "файл ценностей для -preference additively-separable | anonymous | fractional: строки \"i j v\" (ценность j для i) и \"k v\" (полезность коалиции из k)"
+
+// loadPreferenceValuesFile загружает таблицы ценностей предпочтений (см. LoadPreferenceValues)
+func loadPreferenceValuesFile(path string, idToName map[int]string, values *PreferenceValues) error {
+	if path == "" {
+		return nil
+	}
+	nameToID := make(map[string]int, len(idToName))
+	for id, name := range idToName {
+		nameToID[name] = id
+	}
+	loaded, err := LoadPreferenceValues(path, nameToID)
+	if err != nil {
+		return err
+	}
+	*values = loaded
+	return nil
+}
+
+// printViolations печатает нарушения ограничений
+func printViolations(violations []ConstraintViolation, idToName map[int]string) {
+	for _, v := range violations {
+		names := make([]string, len(v.Nodes))
+		for i, node := range v.Nodes {
+			names[i] = idToName[node]
+		}
+		fmt.Printf("  нарушение %s (узлы: %s)\n", v, strings.Join(names, ", "))
+	}
+}
+
+func parseFloatList(s string) ([]float64, error) {
+	var values []float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("неверное число %q", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseIntList(s string) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("неверное целое %q", part)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// ============================================================
+// ПОДКОМАНДЫ
+// ============================================================
+
+// runCommand - один алгоритм на одном графе
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	cfg := DefaultRunConfig()
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	csvPath := fs.String("csv", "", "сохранить строку результата в CSV (пусто = не сохранять)")
+	reproducible := fs.Bool("reproducible", false, "не писать время выполнения и метку времени в CSV")
+	verbose := fs.Bool("v", false, "напечатать сообщества")
+	tracePath := fs.String("trace", "", "сохранить ходы динамики улучшающих ответов в CSV")
+	annealPath := fs.String("anneal-stats", "", "сохранить статистику отжига ML по температурам в CSV")
+	likelihoodPath := fs.String("likelihood", "", "сохранить правдоподобие ML (и α для EM) по итерациям в CSV")
+	constraintsPath := fs.String("constraints", "", "JSON-файл ограничений (must_link, cannot_link, min_size, max_size, exact_k)")
+	valuesPath := fs.String("values", "", valuesUsage)
+	truthSpec := fs.String("truth", "", truthUsage)
+	addRunFlags(fs, &cfg)
+	fs.Parse(args)
+
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	if err := loadConstraintsFile(*constraintsPath, idToName, &cfg.Constraints, &cfg.TargetK); err != nil {
+		return err
+	}
+	if err := loadPreferenceValuesFile(*valuesPath, idToName, &cfg.PreferenceValues); err != nil {
+		return err
+	}
+	truth, err := loadTruthSpec(*truthSpec, *graphSpec, idToName)
+	if err != nil {
+		return err
+	}
+
+	partition, result, err := RunExperiment(g, cfg)
+	if err != nil {
+		return err
+	}
+	result.ScoreAgainst(partition, truth)
+
+	fmt.Printf("%s: сообществ %d, потенциал %.4f, модулярность %.4f, итераций %d (%.3f с, зерно %d)\n",
+		result.TestName, result.Communities, result.Potential, result.Modularity,
+		result.Iterations, result.ExecutionTime, result.Seed)
+	if result.Accuracy != nil {
+		fmt.Printf("Согласие с эталоном (%d узлов): %s\n", result.Accuracy.Nodes, result.Accuracy)
+	}
+	if len(result.ModelScores) > 0 {
+		fmt.Printf("  %3s %14s %12s %12s\n", "K", "log L", "BIC", "MDL")
+		for _, score := range result.ModelScores {
+			mark := ""
+			if score.K == result.Communities {
+				mark = " ←"
+			}
+			fmt.Printf("  %3d %14.4f %12.4f %12.4f%s\n", score.K, score.LogLikelihood, score.BIC, score.MDL, mark)
+		}
+	}
+	if len(result.Violations) > 0 {
+		fmt.Printf("Ограничения нарушены (%d):\n", len(result.Violations))
+		printViolations(result.Violations, idToName)
+	}
+	if *verbose {
+		PrintCommunities(partition)
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	filename := filepath.Join(*outDir, PartitionFileName(graphName, cfg, result.Communities))
+	if err := ExportPartitionToJSON(g, partition, idToName, filename); err != nil {
+		return err
+	}
+	fmt.Printf("Разбиение сохранено: %s\n", filename)
+
+	if *tracePath != "" {
+		if err := SaveMoveTraceToCSV(result.Trace, *tracePath); err != nil {
+			return err
+		}
+		fmt.Printf("Ходы динамики (%d): %s\n", len(result.Trace), *tracePath)
+	}
+
+	if *annealPath != "" {
+		if err := SaveTemperatureStatsToCSV(result.Temperatures, *annealPath); err != nil {
+			return err
+		}
+		fmt.Printf("Статистика отжига (%d температур): %s\n", len(result.Temperatures), *annealPath)
+	}
+
+	if *likelihoodPath != "" {
+		if err := SaveLikelihoodHistoryToCSV(result.LikelihoodHistory, result.AlphaHistory, *likelihoodPath); err != nil {
+			return err
+		}
+		fmt.Printf("Правдоподобие по итерациям (%d): %s\n", len(result.LikelihoodHistory), *likelihoodPath)
+	}
+
+	if *csvPath != "" {
+		results := []ExperimentResult{result}
+		if *reproducible {
+			StripTimings(results)
+		}
+		if err := SaveResultsToCSV(results, *csvPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweepCommand - перебор параметров по сетке
+func sweepCommand(args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	preset := fs.String("preset", "", "готовая сетка: legacy (эксперименты старого main.go)")
+	algos := fs.String("algos", "hedonic", "алгоритмы через запятую: hedonic, ml, louvain, leiden")
+	alphas := fs.String("alphas", "0.1,0.3,0.5,0.7,0.9", "значения α через запятую")
+	betas := fs.String("betas", "1.0", "значения β через запятую (только ML)")
+	ks := fs.String("ks", "-1", "значения K через запятую (-1 = не ограничено)")
+	iterations := fs.Int("iter", 1000, "максимум итераций")
+	seed := fs.Int64("seed", 0, "зерно ГСЧ (0 = случайное; ячейка i получает seed+i)")
+	seeds := fs.String("seeds", "", "зёрна через запятую: каждая ячейка повторяется с каждым зерном (вместо -seed)")
+	workers := fs.Int("workers", 0, "число параллельных запусков (0 = по числу процессоров)")
+	reproducible := fs.Bool("reproducible", false, "не писать время выполнения и метку времени в CSV")
+	useModularity := fs.Bool("modularity", false, "потенциал по формуле (7.2) вместо (7.1)")
+	resolution := fs.Float64("resolution", 0, "разрешение γ потенциала (7.2) (0 = 1, обычная модулярность)")
+	nullModel := fs.String("null-model", "", "нулевая модель потенциала (7.2): ng | cpm")
+	var constraints Constraints
+	fs.IntVar(&constraints.MinSize, "min-size", 0, "минимальный размер сообщества (0 = не ограничен)")
+	fs.IntVar(&constraints.MaxSize, "max-size", 0, "максимальный размер сообщества (0 = не ограничен)")
+	constraintsPath := fs.String("constraints", "", "JSON-файл ограничений (K задаётся -ks)")
+	truthSpec := fs.String("truth", "", truthUsage)
+	fs.Parse(args)
+
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	if err := loadConstraintsFile(*constraintsPath, idToName, &constraints, nil); err != nil {
+		return err
+	}
+	truth, err := loadTruthSpec(*truthSpec, *graphSpec, idToName)
+	if err != nil {
+		return err
+	}
+
+	var cells []RunConfig
+	if *preset == "legacy" {
+		cells = LegacyKarateSweep()
+	} else if *preset != "" {
+		return fmt.Errorf("неизвестная сетка %q", *preset)
+	} else {
+		alphaValues, err := parseFloatList(*alphas)
+		if err != nil {
+			return err
+		}
+		betaValues, err := parseFloatList(*betas)
+		if err != nil {
+			return err
+		}
+		kValues, err := parseIntList(*ks)
+		if err != nil {
+			return err
+		}
+
+		seedValues, err := parseIntList(*seeds)
+		if err != nil {
+			return err
+		}
+
+		grid := SweepGrid{
+			Alphas: alphaValues,
+			Betas:  betaValues,
+			Ks:     kValues,
+			Base: RunConfig{
+				MaxIterations: *iterations,
+				UseModularity: *useModularity,
+				Resolution:    *resolution,
+				NullModel:     *nullModel,
+			},
+		}
+		for _, algo := range strings.Split(*algos, ",") {
+			grid.Algorithms = append(grid.Algorithms, strings.TrimSpace(algo))
+		}
+		for _, s := range seedValues {
+			grid.Seeds = append(grid.Seeds, int64(s))
+		}
+		cells = grid.Cells()
+	}
+	for i := range cells {
+		cells[i].Constraints = constraints
+	}
+	ResolveSweepSeeds(cells, *seed)
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+
+	results, err := RunSweep(g, cells, *workers, func(cell *SweepCell) {
+		cfg, result := cell.Config, &cell.Result
+		result.ScoreAgainst(cell.Partition, truth)
+
+		prefix := graphName
+		if *seeds != "" {
+			prefix = fmt.Sprintf("%s_seed%d", graphName, cfg.Seed)
+		}
+		filename := filepath.Join(*outDir, PartitionFileName(prefix, cfg, result.Communities))
+		if err := ExportPartitionToJSON(g, cell.Partition, idToName, filename); err != nil {
+			fmt.Printf("export error: %v\n", err)
+		}
+		accuracy := ""
+		if result.Accuracy != nil {
+			accuracy = fmt.Sprintf(", NMI=%.4f, ARI=%.4f", result.Accuracy.NMI, result.Accuracy.ARI)
+		}
+		fmt.Printf("%-22s α=%-5g β=%-4g K=%-3d → сообществ %d, Q=%.4f%s\n",
+			result.TestName, cfg.Alpha, cfg.Beta, cfg.TargetK, result.Communities, result.Modularity, accuracy)
+		printViolations(result.Violations, idToName)
+	})
+	if err != nil {
+		return err
+	}
+
+	if *reproducible {
+		StripTimings(results)
+	}
+	csvFile := filepath.Join(*outDir, graphName+"_experiments.csv")
+	if err := SaveResultsToCSV(results, csvFile); err != nil {
+		return err
+	}
+	fmt.Printf("Результаты: %s\n", csvFile)
+	return nil
+}
+
+// inspectCommand - информация о графе
+func inspectCommand(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	graphSpec := fs.String("graph", "karate", "граф")
+	fs.Parse(args)
+
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+
+	PrintGraphInfo(g, graphName, idToName)
+	if g.IsWeighted() {
+		fmt.Printf("\n  Суммарный вес:     %.4f\n", g.TotalWeight())
+	}
+	if g.Directed {
+		fmt.Printf("  Ориентированный:   %d дуг\n", g.NumArcs())
+	}
+	return nil
+}
+
+// validateCommand - проверка устойчивости сохранённого разбиения
+func validateCommand(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	graphSpec := fs.String("graph", "karate", "граф")
+	partitionFile := fs.String("partition", "", "JSON-файл разбиения (формат ExportPartitionToJSON)")
+	alpha := fs.Float64("alpha", 0.3, "штраф за незнакомцев α")
+	useModularity := fs.Bool("modularity", false, "потенциал по формуле (7.2) вместо (7.1)")
+	resolution := fs.Float64("resolution", 0, "разрешение γ потенциала (7.2) (0 = 1, обычная модулярность)")
+	nullModelName := fs.String("null-model", "", "нулевая модель потенциала (7.2): ng | cpm")
+	concept := fs.String("concept", "ns", "концепция устойчивости: ns | is | cis | core")
+	exactLimit := fs.Int("core-exact", DefaultCoreExactLimit, "ядро: полный перебор, если кандидатов не больше")
+	preferenceName := fs.String("preference", "", "модель предпочтений: "+PreferenceNames)
+	valuesPath := fs.String("values", "", valuesUsage)
+	constraintsPath := fs.String("constraints", "", "JSON-файл ограничений: проверить и учитывать только допустимые ходы")
+	truthSpec := fs.String("truth", "", truthUsage)
+	fs.Parse(args)
+
+	if *partitionFile == "" {
+		return fmt.Errorf("не задан -partition")
+	}
+
+	g, idToName, _, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+
+	communities, err := LoadPartitionFromJSON(*partitionFile)
+	if err != nil {
+		return err
+	}
+
+	var values PreferenceValues
+	if err := loadPreferenceValuesFile(*valuesPath, idToName, &values); err != nil {
+		return err
+	}
+	preference, err := ParsePreference(*preferenceName, values, g)
+	if err != nil {
+		return err
+	}
+
+	var constraints Constraints
+	if err := loadConstraintsFile(*constraintsPath, idToName, &constraints, &constraints.ExactK); err != nil {
+		return err
+	}
+
+	nullModel, err := ParseNullModel(*nullModelName)
+	if err != nil {
+		return err
+	}
+
+	hg := NewHedonicGame(*g, *alpha)
+	hg.Preference = preference
+	hg.Resolution = *resolution
+	hg.NullModel = nullModel
+	if !constraints.IsZero() {
+		hg.Constraints = &constraints
+	}
+	assignment := make(map[int]int, g.NumNodes())
+	for _, node := range g.GetNodeList() {
+		comm, ok := communities[idToName[node]]
+		if !ok {
+			return fmt.Errorf("узел %s отсутствует в %s", idToName[node], *partitionFile)
+		}
+		if comm < 0 {
+			return fmt.Errorf("узел %s: отрицательный номер сообщества %d", idToName[node], comm)
+		}
+		assignment[node] = comm
+	}
+	hg.Partition = PartitionFromMap(assignment) // номера из файла сжимаются
+
+	fmt.Printf("Сообществ: %d, потенциал %.4f, модулярность %.4f\n",
+		hg.GetNumberOfCommunities(), hg.ComputePotentialCurrent(*useModularity),
+		ComputeModularity(g, hg.Partition))
+	truth, err := loadTruthSpec(*truthSpec, *graphSpec, idToName)
+	if err != nil {
+		return err
+	}
+	if truth != nil {
+		scores := ComparePartitions(hg.Partition, truth)
+		fmt.Printf("Согласие с эталоном (%d узлов): %s\n", scores.Nodes, scores)
+	}
+	if preference != nil {
+		fmt.Printf("Предпочтения: %s, потенциал существует: %v\n", preference.Name(), preference.HasPotential())
+	}
+	if violations := hg.CheckConstraints(); len(violations) > 0 {
+		fmt.Printf("Разбиение нарушает ограничения (%d):\n", len(violations))
+		printViolations(violations, idToName)
+		os.Exit(1)
+	}
+
+	var stable bool
+	var witness *Deviation
+	switch *concept {
+	case "ns":
+		if stable, err = hg.IsNashStable(*useModularity); err != nil {
+			return err
+		}
+	case "is":
+		stable, witness = hg.IsIndividuallyStable()
+	case "cis":
+		stable, witness = hg.IsContractuallyIndividuallyStable()
+	case "core":
+		stable, witness = hg.IsCoreStable(*exactLimit)
+	default:
+		return fmt.Errorf("неизвестная концепция %q", *concept)
+	}
+
+	label := strings.ToUpper(*concept)
+	if !stable {
+		fmt.Printf("Разбиение НЕ устойчиво (%s)\n", label)
+		if witness != nil {
+			fmt.Printf("  %s\n", witness)
+			if witness.Concept != "core" {
+				fmt.Printf("  узел: %s\n", idToName[witness.Node])
+			}
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("Разбиение устойчиво (%s)\n", label)
+	return nil
+}
+
+// generateCommand - синтетический граф в список рёбер и эталон в файл "узел метка"
+func generateCommand(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	model := fs.String("model", "lfr", "модель: <модель>[:ключ=значение,...], как у -graph")
+	out := fs.String("out", "", "файл списка рёбер (пусто - results/<имя>.edgelist)")
+	truthOut := fs.String("truth-out", "", "файл эталона (пусто - рядом со списком рёбер, *.truth)")
+	fs.Parse(args)
+
+	if !isGeneratorSpec(*model) {
+		return fmt.Errorf("неизвестная модель графа %q", *model)
+	}
+	g, truth, err := GenerateFromSpec(*model)
+	if err != nil {
+		return err
+	}
+	idToName := generateNodeNames(g.NumNodes())
+
+	if *out == "" {
+		*out = filepath.Join("results", generatorName(*model)+".edgelist")
+	}
+	if *truthOut == "" {
+		*truthOut = strings.TrimSuffix(*out, filepath.Ext(*out)) + ".truth"
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0755); err != nil {
+		return err
+	}
+
+	var edges, labels strings.Builder
+	isolated := 0
+	for _, u := range g.GetNodeList() {
+		neighbors := g.GetNeighbors(u)
+		if len(neighbors) == 0 {
+			isolated++ // в списке рёбер узел без рёбер не сохранить
+			continue
+		}
+		fmt.Fprintf(&labels, "%s %d\n", idToName[u], truth.Community(u))
+		for _, v := range neighbors {
+			if u <= v {
+				fmt.Fprintf(&edges, "%s %s\n", idToName[u], idToName[v])
+			}
+		}
+	}
+	if err := os.WriteFile(*out, []byte(edges.String()), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*truthOut, []byte(labels.String()), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Граф %s: %d узлов, %d рёбер, %d сообществ, смешивание μ=%.4f\n",
+		*model, g.NumNodes(), g.NumEdges(), truth.NumCommunities(), MixingParameter(g, truth))
+	if isolated > 0 {
+		fmt.Printf("Узлов без рёбер (не сохранены): %d\n", isolated)
+	}
+	fmt.Printf("Список рёбер: %s\nЭталон: %s\n", *out, *truthOut)
+	return nil
+}
+
+// scanCommand - сканирование по разрешению: разбиение при каждом γ и плато
+func scanCommand(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	nullModelName := fs.String("null-model", "", "нулевая модель: ng (Ньюман–Гирван) | cpm (модель Поттса с константой)")
+	gammaMin := fs.Float64("gamma-min", 0, "наименьшее γ (0 = 0.1 для ng, 0.01 для cpm)")
+	gammaMax := fs.Float64("gamma-max", 0, "наибольшее γ (0 = 10 для ng, 1 для cpm)")
+	steps := fs.Int("steps", 40, "число значений γ (логарифмическая сетка)")
+	gammas := fs.String("gammas", "", "значения γ через запятую (вместо -gamma-min/-gamma-max/-steps)")
+	minPoints := fs.Int("min-points", 3, "наименьшее число точек плато")
+	tolerance := fs.Float64("tolerance", 0, "допуск VI между соседними разбиениями одного плато (0 = совпадают)")
+	iterations := fs.Int("iter", 1000, "максимум итераций")
+	seed := fs.Int64("seed", 0, "зерно ГСЧ (0 = случайное; точка i получает seed+i)")
+	workers := fs.Int("workers", 0, "число параллельных запусков (0 = по числу процессоров)")
+	truthSpec := fs.String("truth", "", truthUsage)
+	fs.Parse(args)
+
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	truth, err := loadTruthSpec(*truthSpec, *graphSpec, idToName)
+	if err != nil {
+		return err
+	}
+	nullModel, err := ParseNullModel(*nullModelName)
+	if err != nil {
+		return err
+	}
+
+	var gammaValues []float64
+	if *gammas != "" {
+		if gammaValues, err = parseFloatList(*gammas); err != nil {
+			return err
+		}
+	} else {
+		from, to := DefaultResolutionRange(nullModel)
+		if *gammaMin > 0 {
+			from = *gammaMin
+		}
+		if *gammaMax > 0 {
+			to = *gammaMax
+		}
+		if gammaValues, err = ResolutionGrid(from, to, *steps); err != nil {
+			return err
+		}
+	}
+
+	base := DefaultRunConfig()
+	base.MaxIterations = *iterations
+	base.Seed = *seed
+	base.NullModel = string(nullModel)
+	points, err := ResolutionScan(g, base, gammaValues, *tolerance, *workers)
+	if err != nil {
+		return err
+	}
+	plateaus := FindPlateaus(points, *minPoints)
+
+	fmt.Printf("Скан %s (%s): %d значений γ\n", graphName, nullModel, len(points))
+	fmt.Printf("  %10s %5s %10s %10s %8s\n", "γ", "K", "качество", "Q", "VI")
+	for i := range points {
+		p := &points[i]
+		p.Result.ScoreAgainst(p.Partition, truth)
+		mark := ""
+		if p.SameAsPrev {
+			mark = " ="
+		}
+		accuracy := ""
+		if p.Result.Accuracy != nil {
+			accuracy = fmt.Sprintf("  NMI=%.4f", p.Result.Accuracy.NMI)
+		}
+		fmt.Printf("  %10.4f %5d %10.4f %10.4f %8.4f%s%s\n",
+			p.Gamma, p.Communities, p.Quality, p.Result.Modularity, p.VIPrev, mark, accuracy)
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	csvFile := filepath.Join(*outDir, fmt.Sprintf("%s_scan_%s.csv", graphName, nullModel))
+	if err := SaveResolutionScanToCSV(points, plateaus, csvFile); err != nil {
+		return err
+	}
+
+	if len(plateaus) == 0 {
+		fmt.Printf("Плато не найдено (не меньше %d точек подряд)\n", *minPoints)
+	} else {
+		fmt.Printf("Плато (%d):\n", len(plateaus))
+	}
+	for i, p := range plateaus {
+		result := points[p.Start].Result
+		filename := filepath.Join(*outDir, fmt.Sprintf("%s_scan_%s_plateau%d_k%d.json", graphName, nullModel, i+1, p.Communities))
+		if err := ExportPartitionToJSON(g, p.Partition, idToName, filename); err != nil {
+			return err
+		}
+		fmt.Printf("  %d. γ ∈ [%.4f, %.4f] (%d точек, %.2f декады): сообществ %d, Q=%.4f → %s\n",
+			i+1, p.GammaFrom, p.GammaTo, p.Points(), p.Width(), p.Communities, result.Modularity, filename)
+	}
+	fmt.Printf("Скан: %s\n", csvFile)
+	return nil
+}
+
+// hierarchyCommand - иерархические сообщества: дерево и JSON с путями узлов
+func hierarchyCommand(args []string) error {
+	fs := flag.NewFlagSet("hierarchy", flag.ExitOnError)
+	cfg := DefaultRunConfig()
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	mode := fs.String("mode", string(HierarchyAggregate), "aggregate (свёртка сообществ, снизу вверх) | recursive (деление сообществ с растущим α, сверху вниз)")
+	levels := fs.Int("levels", 0, "максимум уровней (0 = пока сообщества меняются)")
+	growth := fs.Float64("growth", 2, "во сколько раз α (γ при -modularity) растёт к более мелкому уровню")
+	minSize := fs.Int("min-split", 3, "recursive: сообщества меньше не делятся")
+	addRunFlags(fs, &cfg)
+	fs.Parse(args)
+
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	hierarchyMode, err := ParseHierarchyMode(*mode)
+	if err != nil {
+		return err
+	}
+
+	h, err := BuildHierarchy(g, cfg, HierarchyOptions{Mode: hierarchyMode, MaxLevels: *levels, Growth: *growth, MinSize: *minSize})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Иерархия %s (%s): уровней %d\n", graphName, hierarchyMode, h.Depth())
+	for level := 1; level <= h.Depth(); level++ {
+		cut := h.Cut(level)
+		fmt.Printf("  уровень %d: сообществ %d, модулярность %.4f\n", level, cut.NumCommunities(), ComputeModularity(g, cut))
+	}
+	fmt.Print(h)
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	filename := filepath.Join(*outDir, fmt.Sprintf("%s_hierarchy_%s_%s.json", graphName, cfg.Algorithm, hierarchyMode))
+	if err := ExportHierarchyToJSON(g, h, idToName, filename); err != nil {
+		return err
+	}
+	fmt.Printf("Иерархия сохранена: %s\n", filename)
+	return nil
+}
+
+// overlapCommand - перекрывающиеся коалиции: от разбиения алгоритма (-algo и др.)
+// агенты вступают ещё в коалиции (не больше r) с платой за каждое членство
+func overlapCommand(args []string) error {
+	fs := flag.NewFlagSet("overlap", flag.ExitOnError)
+	cfg := DefaultRunConfig()
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	r := fs.Int("r", 2, "наибольшее число коалиций агента")
+	cost := fs.Float64("cost", 0.5, "плата за каждое членство в коалиции")
+	verbose := fs.Bool("v", false, "напечатать сообщества")
+	truthSpec := fs.String("truth", "", truthUsage+"; *.json может задавать перекрывающиеся сообщества (communities)")
+	addRunFlags(fs, &cfg)
+	fs.Parse(args)
+
+	if *r < 1 {
+		return fmt.Errorf("-r должно быть не меньше 1")
+	}
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	truth, err := loadCoverTruthSpec(*truthSpec, *graphSpec, idToName)
+	if err != nil {
+		return err
+	}
+
+	partition, result, err := RunExperiment(g, cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Начальное разбиение %s: сообществ %d, модулярность %.4f (зерно %d)\n",
+		result.TestName, result.Communities, result.Modularity, result.Seed)
+
+	og := NewOverlappingGame(g, partition, cfg.Alpha, *cost, *r, NewRand(result.Seed))
+	cover := og.FindStableCover(cfg.MaxIterations)
+	fmt.Printf("Перекрытие (r=%d, плата %.4g): сообществ %d, узлов в нескольких %d, членств %d, потенциал %.4f, раундов %d, устойчиво: %v\n",
+		*r, *cost, cover.NumCommunities(), len(cover.OverlappingNodes()), cover.NumMemberships(),
+		og.ComputePotential(), og.Iterations, og.IsStable())
+	if truth != nil {
+		fmt.Printf("Согласие с эталоном: NMI (перекрывающаяся) %.4f, омега %.4f\n",
+			OverlappingNMI(cover, truth), OmegaIndex(cover, truth))
+	}
+	if *verbose {
+		for _, comm := range cover.Communities() {
+			fmt.Printf("C%d: %v\n", comm, cover.Members(comm))
+		}
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	filename := filepath.Join(*outDir, fmt.Sprintf("%s_overlap_r%d_cost_%s.json", graphName, *r, formatParam(*cost)))
+	if err := ExportCoverToJSON(g, cover, idToName, filename); err != nil {
+		return err
+	}
+	fmt.Printf("Покрытие сохранено: %s\n", filename)
+	return nil
+}
+
+// loadCoverTruthSpec - эталон для перекрывающихся сообществ: *.json читается
+// LoadCoverFromJSON, остальное - как loadTruthSpec (одно сообщество на узел)
+func loadCoverTruthSpec(spec, graphSpec string, idToName map[int]string) (*Cover, error) {
+	if !strings.EqualFold(filepath.Ext(spec), ".json") {
+		truth, err := loadTruthSpec(spec, graphSpec, idToName)
+		if truth == nil || err != nil {
+			return nil, err
+		}
+		return CoverFromPartition(truth), nil
+	}
+
+	communities, err := LoadCoverFromJSON(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	nameToID := make(map[string]int, len(idToName))
+	for id, name := range idToName {
+		nameToID[name] = id
+	}
+	truth := NewCover()
+	for name, comms := range communities {
+		id, ok := nameToID[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: узел %q отсутствует в графе", spec, name)
+		}
+		for _, comm := range comms {
+			if comm < 0 {
+				return nil, fmt.Errorf("%s: узел %q: отрицательный номер сообщества %d", spec, name, comm)
+			}
+			truth.Add(id, comm)
+		}
+	}
+	return truth, nil
+}
+
+// updateCommand - тёплый старт: равновесие игры на исходном графе, изменения
+// графа из файла и восстановление равновесия от затронутых узлов
+func updateCommand(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	cfg := DefaultRunConfig()
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	updatesPath := fs.String("updates", "", "файл изменений: \"+ u v [вес]\", \"- u v\", \"+node u\", \"-node u\" по строке")
+	compare := fs.Bool("compare", false, "сравнить с запуском с нуля на изменённом графе")
+	addRunFlags(fs, &cfg)
+	fs.Parse(args)
+
+	if *updatesPath == "" {
+		return fmt.Errorf("не задан -updates")
+	}
+	if cfg.Algorithm != "hedonic" || (cfg.Dynamics != "" && cfg.Dynamics != "potential") {
+		return fmt.Errorf("тёплый старт - только для гедонической игры с динамикой potential")
+	}
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	nameToID := make(map[string]int, len(idToName))
+	for id, name := range idToName {
+		nameToID[name] = id
+	}
+	updates, err := LoadGraphUpdates(*updatesPath, nameToID, idToName)
+	if err != nil {
+		return err
+	}
+
+	cfg.Seed = ResolveSeed(cfg.Seed)
+	hg, err := newHedonicGameFromConfig(g, cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := hg.FindNashStablePartition_WithPotential(cfg.MaxIterations, cfg.UseModularity); err != nil {
+		return err
+	}
+	fmt.Printf("Исходный граф: сообществ %d, потенциал %.4f, итераций %d (зерно %d)\n",
+		hg.Partition.NumCommunities(), hg.ComputePotentialCurrent(cfg.UseModularity), hg.Iterations, cfg.Seed)
+
+	start := time.Now()
+	changed, err := hg.ApplyUpdates(updates, cfg.MaxIterations, cfg.UseModularity)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start).Seconds()
+	fmt.Printf("Изменений %d: узлов %d, рёбер %d; сообществ %d, потенциал %.4f, модулярность %.4f, проходов %d (%.3f с)\n",
+		len(updates), g.NumNodes(), g.NumEdges(), hg.Partition.NumCommunities(),
+		hg.ComputePotentialCurrent(cfg.UseModularity), ComputeModularity(g, hg.Partition), hg.Iterations, elapsed)
+	fmt.Printf("Сменили сообщество (%d):", len(changed))
+	for _, node := range changed {
+		fmt.Printf(" %s", idToName[node])
+	}
+	fmt.Println()
+
+	if *compare {
+		cold, result, err := RunExperiment(g, cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("С нуля: сообществ %d, потенциал %.4f, модулярность %.4f (%.3f с); NMI с тёплым стартом %.4f\n",
+			result.Communities, result.Potential, result.Modularity, result.ExecutionTime, NMI(hg.Partition, cold))
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	filename := filepath.Join(*outDir, PartitionFileName(graphName+"_updated", cfg, hg.Partition.NumCommunities()))
+	if err := ExportPartitionToJSON(g, hg.Partition, idToName, filename); err != nil {
+		return err
+	}
+	fmt.Printf("Разбиение сохранено: %s\n", filename)
+	return nil
+}
+
+// trackCommand - сообщества во времени: разбиения снимков по порядку,
+// сопоставление сообществ и события их жизненного цикла
+func trackCommand(args []string) error {
+	fs := flag.NewFlagSet("track", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Использование: hedonic-games track [флаги] снимок1 снимок2 ...")
+		fs.PrintDefaults()
+	}
+	cfg := DefaultRunConfig()
+	outDir := fs.String("out", "results", "каталог для результатов")
+	warm := fs.Bool("warm", false, "тёплый старт: динамика снимка начинается с разбиения предыдущего (hedonic)")
+	threshold := fs.Float64("threshold", 0.3, "наименьшее сходство Жаккара для связи сообществ соседних снимков")
+	tolerance := fs.Float64("tolerance", 0, "относительное изменение размера, до которого сообщество не растёт и не сжимается")
+	addRunFlags(fs, &cfg)
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("нужно не меньше двух снимков")
+	}
+	snapshots := make([]Snapshot, fs.NArg())
+	for i, spec := range fs.Args() {
+		g, idToName, graphName, err := loadGraphSpec(spec)
+		if err != nil {
+			return err
+		}
+		snapshots[i] = Snapshot{Name: graphName, Graph: g, IDToName: idToName}
+	}
+
+	tr, err := TrackCommunities(snapshots, cfg, TemporalOptions{WarmStart: *warm, Threshold: *threshold, Tolerance: *tolerance})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	files := make([]string, len(tr.Snapshots))
+	var results []ExperimentResult
+	for i, s := range tr.Snapshots {
+		counts := make(map[EventKind]int)
+		for _, e := range tr.Events {
+			if e.Step == i {
+				counts[e.Kind]++
+			}
+		}
+		fmt.Printf("t%d %s: узлов %d, сообществ %d, модулярность %.4f", i, s.Name, s.Result.NumNodes, s.Result.Communities, s.Result.Modularity)
+		if *warm && i > 0 {
+			fmt.Printf(", сменили сообщество %d", len(s.Changed))
+		}
+		fmt.Println()
+		for _, kind := range []EventKind{EventBirth, EventDeath, EventGrowth, EventContraction, EventContinue, EventMerge, EventSplit} {
+			if counts[kind] > 0 {
+				fmt.Printf("    %-12s %d\n", kind, counts[kind])
+			}
+		}
+
+		name := PartitionFileName(s.Name, cfg, s.Result.Communities)
+		if *warm {
+			name = "warm_" + name
+		}
+		files[i] = filepath.Join(*outDir, fmt.Sprintf("t%02d_%s", i, name))
+		if err := ExportPartitionToJSON(s.Graph, s.Partition, s.IDToName, files[i]); err != nil {
+			return err
+		}
+		results = append(results, s.Result)
+	}
+	fmt.Printf("Динамических сообществ: %d\n", tr.NumTracks)
+
+	prefix := fmt.Sprintf("%s_temporal_%s", snapshots[0].Name, cfg.Algorithm)
+	if *warm {
+		prefix += "_warm"
+	}
+	eventsJSON := filepath.Join(*outDir, prefix+"_events.json")
+	if err := ExportTemporalToJSON(tr, files, eventsJSON); err != nil {
+		return err
+	}
+	eventsCSV := filepath.Join(*outDir, prefix+"_events.csv")
+	if err := SaveTemporalEventsToCSV(tr, eventsCSV); err != nil {
+		return err
+	}
+	resultsCSV := filepath.Join(*outDir, prefix+"_results.csv")
+	if err := SaveResultsToCSV(results, resultsCSV); err != nil {
+		return err
+	}
+	fmt.Printf("События: %s, %s\nРезультаты: %s\n", eventsJSON, eventsCSV, resultsCSV)
+	return nil
+}
+
+// mlsearchCommand - перебор α × начальных разбиений ML-отжигом
+// (MaximumLikelihoodImproved) с контрольными точками и продолжением
+func mlsearchCommand(args []string) error {
+	fs := flag.NewFlagSet("mlsearch", flag.ExitOnError)
+	cfg := DefaultRunConfig()
+	cfg.Schedule = string(ScheduleGeometric)
+	graphSpec := fs.String("graph", "karate", "граф")
+	outDir := fs.String("out", "results", "каталог для результатов")
+	alphas := fs.String("alphas", "0.1,0.3,0.5,0.7,0.9", "значения α через запятую")
+	inits := fs.Int("inits", 3, "случайных начальных разбиений на каждое α")
+	workers := fs.Int("workers", 0, "число параллельных запусков (0 = по числу процессоров)")
+	checkpoint := fs.String("checkpoint", "", "файл контрольной точки (пусто = не сохранять)")
+	every := fs.Duration("checkpoint-every", time.Minute, "сохранять контрольную точку не чаще (0 = всякий раз, когда файл не занят предыдущей записью)")
+	resume := fs.Bool("resume", false, "продолжить перебор с -checkpoint (параметры перебора берутся из него)")
+	addRunFlags(fs, &cfg)
+	fs.Parse(args)
+
+	g, idToName, graphName, err := loadGraphSpec(*graphSpec)
+	if err != nil {
+		return err
+	}
+	opts := MLCheckpointOptions{Path: *checkpoint, Interval: *every}
+
+	start := time.Now()
+	var res *GibbsSamplingResult
+	if *resume {
+		if *checkpoint == "" {
+			return fmt.Errorf("-resume требует -checkpoint")
+		}
+		cp, err := LoadMLCheckpoint(*checkpoint)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Продолжение %s: закончено запусков %d из %d\n", *checkpoint, cp.Done(), len(cp.Runs))
+		if res, err = ResumeMaximumLikelihoodImproved(g, *workers, opts); err != nil {
+			return err
+		}
+	} else {
+		alphaValues, err := parseFloatList(*alphas)
+		if err != nil {
+			return err
+		}
+		objective, err := ParseMLObjective(cfg.Objective)
+		if err != nil {
+			return err
+		}
+		schedule, err := cfg.annealingSchedule()
+		if err != nil {
+			return err
+		}
+		cfg.Seed = ResolveSeed(cfg.Seed)
+		fmt.Printf("Перебор: α %v × %d разбиений, отжиг %s (зерно %d)\n", alphaValues, *inits, schedule.Kind, cfg.Seed)
+		res, err = MaximumLikelihoodImprovedCheckpointed(g, alphaValues, schedule, objective, *inits, NewRand(cfg.Seed), *workers, opts)
+		if err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+
+	fmt.Printf("Лучший запуск: α %.4f, цель %.4f, сообществ %d, Pin %.4f, Pout %.4f, модулярность %.4f\n",
+		res.OptimalAlpha, res.BestObjective, res.NumCommunities, res.OptimalPin, res.OptimalPout,
+		ComputeModularity(g, res.BestPartition))
+	fmt.Printf("Проходов Гиббса во всех запусках %d, лучший на %d (%.3f с)\n", len(res.ObjectiveHistory), res.ConvergedAt, elapsed)
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	filename := filepath.Join(*outDir, fmt.Sprintf("%s_mlsearch_alpha_%s.json", graphName, formatParam(res.OptimalAlpha)))
+	if err := ExportPartitionToJSON(g, res.BestPartition, idToName, filename); err != nil {
+		return err
+	}
+	fmt.Printf("Разбиение сохранено: %s\n", filename)
+	return nil
+}
+
+// serveCommand - локальный HTTP-сервис (см. Server.Handler): графы загружаются
+// node-link JSON, запуски идут через ограниченную очередь. До Ctrl+C
+func serveCommand(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "адрес (только localhost)")
+	queueSize := fs.Int("queue", 16, "мест в очереди запусков (сверх них - 503)")
+	workers := fs.Int("workers", 1, "одновременных запусков")
+	maxGraphs := fs.Int("max-graphs", 64, "хранимых графов (сверх них загрузка - 507)")
+	maxJobs := fs.Int("max-jobs", 1024, "хранимых запусков (сверх них забываются старые завершённые)")
+	fs.Parse(args)
+
+	if err := checkLoopback(*addr); err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+
+	srv := NewServer(ServerOptions{
+		QueueSize: *queueSize,
+		Workers:   *workers,
+		MaxGraphs: *maxGraphs,
+		MaxJobs:   *maxJobs,
+	})
+	httpServer := &http.Server{Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- httpServer.Serve(listener) }()
+	fmt.Printf("Сервис: http://%s (очередь %d, исполнителей %d)\n", listener.Addr(), *queueSize, *workers)
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		fmt.Println("Остановка: отмена запусков")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = httpServer.Shutdown(shutdownCtx)
+	}
+	srv.Close()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestParseLists - списки через запятую: пробелы и пустые элементы пропускаются
+func TestParseLists(t *testing.T) {
+	floats, err := parseFloatList(" 0.1, 1,,2.5e1 ")
+	if err != nil || !slices.Equal(floats, []float64{0.1, 1, 25}) {
+		t.Errorf("parseFloatList = %v, %v", floats, err)
+	}
+	ints, err := parseIntList("3, 1,2,")
+	if err != nil || !slices.Equal(ints, []int{3, 1, 2}) {
+		t.Errorf("parseIntList = %v, %v", ints, err)
+	}
+	if _, err := parseFloatList("0.1,x"); err == nil {
+		t.Error("parseFloatList(0.1,x): нет ошибки")
+	}
+	if _, err := parseIntList("1,2.5"); err == nil {
+		t.Error("parseIntList(1,2.5): нет ошибки")
+	}
+}
+
+// TestParseCavemanSpec - caveman по умолчанию 6x5, caveman:NxS, ошибка на мусоре
+func TestParseCavemanSpec(t *testing.T) {
+	tests := []struct {
+		spec          string
+		cliques, size int
+		wantErr       bool
+	}{
+		{spec: "caveman", cliques: 6, size: 5},
+		{spec: "caveman:3x4", cliques: 3, size: 4},
+		{spec: "caveman:3", wantErr: true},
+		{spec: "caveman:ax4", wantErr: true},
+	}
+	for _, tt := range tests {
+		cliques, size, err := parseCavemanSpec(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ошибка %v, ожидалась %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (cliques != tt.cliques || size != tt.size) {
+			t.Errorf("%s: %dx%d, want %dx%d", tt.spec, cliques, size, tt.cliques, tt.size)
+		}
+	}
+}
+
+// TestLoadGraphAndTruthSpec - встроенные графы, генераторы и файлы вместе
+// с их эталонами по флагу -truth
+func TestLoadGraphAndTruthSpec(t *testing.T) {
+	dir := t.TempDir()
+	edges := filepath.Join(dir, "toy.txt")
+	truthFile := filepath.Join(dir, "toy_truth.txt")
+	if err := os.WriteFile(edges, []byte("a b\nb c\nc a\nc d\nd e\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(truthFile, []byte("node label\na x\nb x\nc x\nd y\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec, truth string
+		nodes       int
+		name        string
+		truthComms  int // 0 - эталона нет
+		wantErr     bool
+	}{
+		{spec: "karate", truth: "builtin", nodes: 34, name: "karate", truthComms: 2},
+		{spec: "caveman:3x4", truth: "builtin", nodes: 12, name: "caveman", truthComms: 3},
+		{spec: "planted:groups=2,size=5,pin=1,pout=0,seed=1", truth: "builtin", nodes: 10, name: "planted_groups2_size5_pin1_pout0_seed1", truthComms: 2},
+		{spec: edges, truth: truthFile, nodes: 5, name: "toy", truthComms: 2},
+		{spec: edges, truth: "", nodes: 5, name: "toy"},
+		{spec: edges, truth: "builtin", wantErr: true},
+		{spec: filepath.Join(dir, "missing.txt"), wantErr: true},
+	}
+	for _, tt := range tests {
+		g, idToName, name, err := loadGraphSpec(tt.spec)
+		var truth *Partition
+		if err == nil {
+			truth, err = loadTruthSpec(tt.truth, tt.spec, idToName)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s/%q: ошибка %v, ожидалась %v", tt.spec, tt.truth, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if g.NumNodes() != tt.nodes || len(idToName) != tt.nodes || name != tt.name {
+			t.Errorf("%s: %d узлов, %d имён, имя %q; want %d, %q",
+				tt.spec, g.NumNodes(), len(idToName), name, tt.nodes, tt.name)
+		}
+		switch {
+		case tt.truthComms == 0 && truth != nil:
+			t.Errorf("%s: эталон без -truth", tt.spec)
+		case tt.truthComms > 0 && (truth == nil || truth.NumCommunities() != tt.truthComms):
+			t.Errorf("%s/%q: эталон %v, want %d сообществ", tt.spec, tt.truth, truth, tt.truthComms)
+		}
+	}
+}
+
+// TestRunCommandOutputs - run пишет разбиение в -out, а с -reproducible
+// повтор с тем же зерном даёт побайтно тот же CSV
+func TestRunCommandOutputs(t *testing.T) {
+	dir := t.TempDir()
+	run := func(csvName string) []byte {
+		t.Helper()
+		csvPath := filepath.Join(dir, csvName)
+		args := []string{"-graph", "caveman:4x5", "-algo", "louvain", "-seed", "3",
+			"-truth", "builtin", "-out", dir, "-csv", csvPath, "-reproducible"}
+		if err := runCommand(args); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(csvPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	first, second := run("first.csv"), run("second.csv")
+	if !bytes.Equal(first, second) {
+		t.Errorf("CSV двух запусков с -reproducible различаются:\n%s\n%s", first, second)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "caveman_louvain*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("файлы разбиения: %v, %v", files, err)
+	}
+	communities, err := LoadPartitionFromJSON(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := make(map[int]bool)
+	for _, comm := range communities {
+		labels[comm] = true
+	}
+	if len(communities) != 20 || len(labels) != 4 {
+		t.Errorf("%s: %d узлов в %d сообществах, want 20 в 4", files[0], len(communities), len(labels))
+	}
+}
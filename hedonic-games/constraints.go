@@ -0,0 +1,605 @@
+// constraints.go - жёсткие ограничения на разбиение: число и размеры сообществ,
+// обязательные и запрещённые совместные членства
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"slices"
+)
+
+// Constraints - ограничения, которые динамика не нарушает ни на одном ходу
+type Constraints struct {
+	ExactK     int      // ровно K сообществ (0 = не ограничено)
+	MinSize    int      // минимальный размер сообщества (0 = не ограничено)
+	MaxSize    int      // максимальный размер сообщества (0 = не ограничено)
+	MustLink   [][]int  // группы узлов, которые всегда в одном сообществе
+	CannotLink [][2]int // пары узлов, которые не могут быть в одном сообществе
+}
+
+// IsZero сообщает, что ограничений нет
+func (c *Constraints) IsZero() bool {
+	return c == nil || (c.ExactK <= 0 && c.MinSize <= 1 && c.MaxSize <= 0 &&
+		len(c.MustLink) == 0 && len(c.CannotLink) == 0)
+}
+
+// ConstraintViolation - нарушение ограничения в разбиении
+type ConstraintViolation struct {
+	Kind      string // "exact-k", "min-size", "max-size", "must-link" или "cannot-link"
+	Community int    // сообщество (для размеров), иначе -1
+	Nodes     []int  // затронутые узлы
+	Detail    string
+}
+
+func (v ConstraintViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Kind, v.Detail)
+}
+
+// Check возвращает все нарушения ограничений в разбиении (nil - разбиение допустимо)
+func (c *Constraints) Check(partition *Partition) []ConstraintViolation {
+	if c.IsZero() {
+		return nil
+	}
+	var violations []ConstraintViolation
+
+	if k := partition.NumCommunities(); c.ExactK > 0 && k != c.ExactK {
+		violations = append(violations, ConstraintViolation{
+			Kind:      "exact-k",
+			Community: -1,
+			Detail:    fmt.Sprintf("сообществ %d, требуется %d", k, c.ExactK),
+		})
+	}
+
+	for _, comm := range partition.Communities() {
+		size := partition.Size(comm)
+		if size < c.MinSize {
+			violations = append(violations, ConstraintViolation{
+				Kind:      "min-size",
+				Community: comm,
+				Nodes:     partition.SortedMembers(comm),
+				Detail:    fmt.Sprintf("в C%d %d узлов, минимум %d", comm, size, c.MinSize),
+			})
+		}
+		if c.MaxSize > 0 && size > c.MaxSize {
+			violations = append(violations, ConstraintViolation{
+				Kind:      "max-size",
+				Community: comm,
+				Nodes:     partition.SortedMembers(comm),
+				Detail:    fmt.Sprintf("в C%d %d узлов, максимум %d", comm, size, c.MaxSize),
+			})
+		}
+	}
+
+	for _, group := range c.MustLink {
+		seen := make(map[int]bool)
+		for _, node := range group {
+			seen[partition.Community(node)] = true
+		}
+		if len(seen) > 1 {
+			violations = append(violations, ConstraintViolation{
+				Kind:      "must-link",
+				Community: -1,
+				Nodes:     group,
+				Detail:    fmt.Sprintf("узлы %v разнесены по сообществам %v", group, slices.Sorted(maps.Keys(seen))),
+			})
+		}
+	}
+
+	for _, pair := range c.CannotLink {
+		if partition.Community(pair[0]) == partition.Community(pair[1]) {
+			violations = append(violations, ConstraintViolation{
+				Kind:      "cannot-link",
+				Community: -1,
+				Nodes:     []int{pair[0], pair[1]},
+				Detail:    fmt.Sprintf("узлы %d и %d оба в C%d", pair[0], pair[1], partition.Community(pair[0])),
+			})
+		}
+	}
+
+	return violations
+}
+
+// CheckConstraints - нарушения ограничений игры в текущем разбиении
+func (hg *HedonicGame) CheckConstraints() []ConstraintViolation {
+	return hg.Constraints.Check(hg.Partition)
+}
+
+// ============================================================
+// ИНДЕКС ОГРАНИЧЕНИЙ
+// ============================================================
+
+// constraintIndex - ограничения в виде, удобном для проверки ходов:
+// узлы, связанные обязательными связями, объединены в блоки и ходят вместе
+type constraintIndex struct {
+	c       *Constraints
+	blockOf map[int]int          // узел -> представитель блока (минимальный узел)
+	blocks  map[int][]int        // представитель -> узлы блока по возрастанию
+	order   []int                // представители блоков по возрастанию
+	cannot  map[int]map[int]bool // запрещённые соседи по сообществу
+}
+
+// compile строит индекс для узлов графа и проверяет ограничения на совместность
+func (c *Constraints) compile(g *Graph) (*constraintIndex, error) {
+	n := g.NumNodes()
+	if c.MaxSize > 0 && c.MinSize > c.MaxSize {
+		return nil, fmt.Errorf("минимальный размер %d больше максимального %d", c.MinSize, c.MaxSize)
+	}
+	if c.ExactK > 0 {
+		if c.ExactK > n {
+			return nil, fmt.Errorf("требуется %d сообществ, а узлов %d", c.ExactK, n)
+		}
+		if c.ExactK*max(c.MinSize, 1) > n {
+			return nil, fmt.Errorf("%d сообществ по %d узлов не помещаются в %d узлов", c.ExactK, c.MinSize, n)
+		}
+		if c.MaxSize > 0 && c.ExactK*c.MaxSize < n {
+			return nil, fmt.Errorf("%d сообществ по %d узлов не вмещают %d узлов", c.ExactK, c.MaxSize, n)
+		}
+	}
+
+	// Объединение обязательных связей (система непересекающихся множеств)
+	parent := make(map[int]int, n)
+	for node := range g.Nodes {
+		parent[node] = node
+	}
+	var find func(int) int
+	find = func(x int) int {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	for _, group := range c.MustLink {
+		for _, node := range group {
+			if !g.Nodes[node] {
+				return nil, fmt.Errorf("must-link: узел %d отсутствует в графе", node)
+			}
+		}
+		for _, node := range group[min(1, len(group)):] {
+			a, b := find(group[0]), find(node)
+			if a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	ci := &constraintIndex{
+		c:       c,
+		blockOf: make(map[int]int, n),
+		blocks:  make(map[int][]int),
+		cannot:  make(map[int]map[int]bool),
+	}
+	for _, node := range g.GetNodeList() {
+		rep := find(node)
+		ci.blockOf[node] = rep
+		ci.blocks[rep] = append(ci.blocks[rep], node)
+	}
+	ci.order = SortedCommunityIDs(ci.blocks)
+
+	for _, pair := range c.CannotLink {
+		u, v := pair[0], pair[1]
+		if !g.Nodes[u] || !g.Nodes[v] {
+			return nil, fmt.Errorf("cannot-link: пара (%d, %d) ссылается на отсутствующий узел", u, v)
+		}
+		if ci.blockOf[u] == ci.blockOf[v] {
+			return nil, fmt.Errorf("узлы %d и %d одновременно обязаны и не могут быть вместе", u, v)
+		}
+		for _, p := range [][2]int{{u, v}, {v, u}} {
+			if ci.cannot[p[0]] == nil {
+				ci.cannot[p[0]] = make(map[int]bool)
+			}
+			ci.cannot[p[0]][p[1]] = true
+		}
+	}
+
+	if c.MaxSize > 0 {
+		for _, rep := range ci.order {
+			if len(ci.blocks[rep]) > c.MaxSize {
+				return nil, fmt.Errorf("блок обязательных связей %v больше максимального размера %d", ci.blocks[rep], c.MaxSize)
+			}
+		}
+	}
+
+	return ci, nil
+}
+
+// canMove проверяет, что переход блока в сообщество to сохраняет все ограничения
+func (ci *constraintIndex) canMove(partition *Partition, block []int, to int) bool {
+	from := partition.Community(block[0])
+	if to == from {
+		return false
+	}
+	b := len(block)
+	remaining := partition.Size(from) - b
+	sizeTo := partition.Size(to)
+
+	if ci.c.ExactK > 0 {
+		k := partition.NumCommunities()
+		if sizeTo == 0 {
+			k++
+		}
+		if remaining == 0 {
+			k--
+		}
+		if k != ci.c.ExactK {
+			return false
+		}
+	}
+	if remaining > 0 && remaining < ci.c.MinSize {
+		return false
+	}
+	if sizeTo+b < ci.c.MinSize {
+		return false
+	}
+	if ci.c.MaxSize > 0 && sizeTo+b > ci.c.MaxSize {
+		return false
+	}
+	return ci.compatible(partition, block, to, -1)
+}
+
+// compatible - ни один узел блока не запрещён с членами сообщества comm
+// (кроме узлов блока except, который из comm уходит)
+func (ci *constraintIndex) compatible(partition *Partition, block []int, comm, except int) bool {
+	for _, node := range block {
+		for other := range ci.cannot[node] {
+			if partition.Community(other) == comm && ci.blockOf[other] != except {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ============================================================
+// ДОПУСТИМОЕ НАЧАЛЬНОЕ РАЗБИЕНИЕ
+// ============================================================
+
+// FeasiblePartition строит случайное разбиение, удовлетворяющее ограничениям.
+// Блоки раскладываются от больших к меньшим в наименьшее совместимое сообщество,
+// затем недобравшие минимальный размер сообщества пополняются из крупных.
+// Задача в общем случае NP-трудна (запрещённые пары - раскраска графа),
+// поэтому при неудаче возвращается ошибка, даже если решение существует.
+func FeasiblePartition(g *Graph, c *Constraints, rng *rand.Rand) (*Partition, error) {
+	if c == nil {
+		c = &Constraints{}
+	}
+	ci, err := c.compile(g)
+	if err != nil {
+		return nil, err
+	}
+	return ci.feasiblePartition(g, rng)
+}
+
+func (ci *constraintIndex) feasiblePartition(g *Graph, rng *rand.Rand) (*Partition, error) {
+	c := ci.c
+	n := g.NumNodes()
+
+	// Без ограничений на число и минимальный размер каждый блок - отдельное сообщество
+	if c.ExactK <= 0 && c.MinSize <= 1 {
+		partition := NewPartition(n)
+		for _, rep := range ci.order {
+			for _, node := range ci.blocks[rep] {
+				partition.Set(node, rep)
+			}
+		}
+		return partition, nil
+	}
+
+	order := slices.Clone(ci.order)
+	rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	slices.SortStableFunc(order, func(a, b int) int { return len(ci.blocks[b]) - len(ci.blocks[a]) })
+
+	var ks []int
+	if c.ExactK > 0 {
+		ks = []int{c.ExactK}
+	} else {
+		lower, upper := 1, n/c.MinSize
+		if c.MaxSize > 0 {
+			lower = (n + c.MaxSize - 1) / c.MaxSize
+		}
+		preferred := max(lower, min(upper, len(order)))
+		for k := preferred; k >= lower && len(ks) < 8; k-- {
+			ks = append(ks, k)
+		}
+		for k := preferred + 1; k <= upper && len(ks) < 16; k++ {
+			ks = append(ks, k)
+		}
+	}
+
+	for _, k := range ks {
+		if partition, ok := ci.assignBlocks(order, k); ok {
+			return partition, nil
+		}
+	}
+	return nil, fmt.Errorf("не удалось построить разбиение, удовлетворяющее ограничениям")
+}
+
+// assignBlocks раскладывает блоки по k сообществам 0..k-1
+func (ci *constraintIndex) assignBlocks(order []int, k int) (*Partition, bool) {
+	c := ci.c
+	partition := NewPartition(len(ci.blockOf))
+	place := func(block []int, comm int) {
+		for _, node := range block {
+			partition.Set(node, comm)
+		}
+	}
+
+	for _, rep := range order {
+		block := ci.blocks[rep]
+		best := -1
+		for comm := 0; comm < k; comm++ {
+			if c.MaxSize > 0 && partition.Size(comm)+len(block) > c.MaxSize {
+				continue
+			}
+			if !ci.compatible(partition, block, comm, -1) {
+				continue
+			}
+			if best < 0 || partition.Size(comm) < partition.Size(best) {
+				best = comm
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+		place(block, best)
+	}
+
+	// Пополнение недобравших сообществ блоками из тех, кому есть чем поделиться
+	minSize := max(c.MinSize, 1)
+	for comm := 0; comm < k; comm++ {
+		for partition.Size(comm) < minSize {
+			moved := false
+			for _, rep := range order {
+				block := ci.blocks[rep]
+				from := partition.Community(rep)
+				if from == comm || partition.Size(from)-len(block) < minSize {
+					continue
+				}
+				if c.MaxSize > 0 && partition.Size(comm)+len(block) > c.MaxSize {
+					continue
+				}
+				if !ci.compatible(partition, block, comm, -1) {
+					continue
+				}
+				place(block, comm)
+				moved = true
+				break
+			}
+			if !moved {
+				return nil, false
+			}
+		}
+	}
+	return partition, true
+}
+
+// ============================================================
+// ХОДЫ ПРИ ОГРАНИЧЕНИЯХ
+// ============================================================
+
+// NewHedonicGameWithConstraints создаёт игру с допустимым случайным начальным разбиением
+func NewHedonicGameWithConstraints(g Graph, alpha float64, c *Constraints, rng *rand.Rand) (*HedonicGame, error) {
+	if rng == nil {
+		rng = NewRand(ResolveSeed(0))
+	}
+	partition, err := FeasiblePartition(&g, c, rng)
+	if err != nil {
+		return nil, err
+	}
+	targetK := -1
+	if c.ExactK > 0 {
+		targetK = c.ExactK
+	}
+	return &HedonicGame{
+		G:           g,
+		Partition:   partition,
+		Alpha:       alpha,
+		TargetK:     targetK,
+		Rng:         rng,
+		Constraints: c,
+	}, nil
+}
+
+// constraintIndex - индекс ограничений игры (nil - ограничений нет)
+func (hg *HedonicGame) constraintIndex() (*constraintIndex, error) {
+	if hg.Constraints.IsZero() {
+		return nil, nil
+	}
+	return hg.Constraints.compile(&hg.G)
+}
+
+// bestConstrainedMove - лучший допустимый переход блока по функции выигрыша gain.
+// Кандидаты - сообщества соседей блока (при Preference - все) и новое сообщество.
+func (hg *HedonicGame) bestConstrainedMove(ci *constraintIndex, block []int, gain func([]int, int) float64) (int, float64) {
+	from := hg.Partition.Community(block[0])
+	targets := make(map[int]bool)
+	if hg.Preference != nil {
+		for _, comm := range hg.Partition.Communities() {
+			targets[comm] = true
+		}
+	} else {
+		for _, node := range block {
+			for comm := range hg.communityLinks(node) {
+				targets[comm] = true
+			}
+		}
+	}
+	delete(targets, from)
+	targets[hg.freshCommunityID(block[0])] = true
+
+	bestComm, bestGain := from, 0.0
+	for _, comm := range SortedCommunityIDs(targets) {
+		if !ci.canMove(hg.Partition, block, comm) {
+			continue
+		}
+		if g := gain(block, comm); g > bestGain {
+			bestComm, bestGain = comm, g
+		}
+	}
+	return bestComm, bestGain
+}
+
+// moveBlock переносит все узлы блока в сообщество comm
+func (hg *HedonicGame) moveBlock(block []int, comm int) {
+	for _, node := range block {
+		hg.MoveNode(node, comm)
+	}
+}
+
+// blockPotentialGain - прирост потенциала при переносе блока в target
+// (последовательные переносы узлов с откатом)
+func (hg *HedonicGame) blockPotentialGain(block []int, target int, useModularity bool) float64 {
+	if len(block) == 1 {
+		return hg.MoveGain(block[0], target, useModularity)
+	}
+	from := hg.Partition.Community(block[0])
+	total := 0.0
+	for _, node := range block {
+		total += hg.MoveGain(node, target, useModularity)
+		hg.MoveNode(node, target)
+	}
+	hg.moveBlock(block, from)
+	return total
+}
+
+// blockUtilityGain - минимальный по членам блока прирост полезности при переносе в target:
+// блок переходит, только если выигрывает каждый
+func (hg *HedonicGame) blockUtilityGain(block []int, target int) float64 {
+	from := hg.Partition.Community(block[0])
+	current := hg.communityMembers(from)
+	joined := append(hg.communityMembers(target), block...)
+	minGain := 0.0
+	for i, node := range block {
+		gain := hg.coalitionUtility(node, joined) - hg.coalitionUtility(node, current)
+		if i == 0 || gain < minGain {
+			minGain = gain
+		}
+	}
+	return minGain
+}
+
+// improvingSwap ищет обмен блока с блоком того же размера из соседнего сообщества,
+// увеличивающий потенциал. Размеры сообществ при обмене не меняются.
+func (hg *HedonicGame) improvingSwap(ci *constraintIndex, block []int, useModularity bool) bool {
+	from := hg.Partition.Community(block[0])
+	rep := ci.blockOf[block[0]]
+	targets := make(map[int]bool)
+	for _, node := range block {
+		for comm := range hg.communityLinks(node) {
+			targets[comm] = true
+		}
+	}
+	delete(targets, from)
+
+	for _, comm := range SortedCommunityIDs(targets) {
+		seen := make(map[int]bool)
+		for _, member := range hg.communityMembers(comm) {
+			other := ci.blockOf[member]
+			if seen[other] || len(ci.blocks[other]) != len(block) {
+				continue
+			}
+			seen[other] = true
+			otherBlock := ci.blocks[other]
+			if !ci.compatible(hg.Partition, block, comm, other) || !ci.compatible(hg.Partition, otherBlock, from, rep) {
+				continue
+			}
+
+			gain := hg.blockPotentialGain(block, comm, useModularity)
+			hg.moveBlock(block, comm)
+			gain += hg.blockPotentialGain(otherBlock, from, useModularity)
+			if gain > stabilityEps {
+				hg.moveBlock(otherBlock, from)
+				return true
+			}
+			hg.moveBlock(block, from)
+		}
+	}
+	return false
+}
+
+// findConstrainedPartition - динамика по потенциалу при жёстких ограничениях:
+// ходят блоки обязательных связей, и только допустимыми ходами.
+// Если блоку некуда выгодно перейти, пробуется обмен с блоком того же размера.
+func (hg *HedonicGame) findConstrainedPartition(ci *constraintIndex, maxIterations int, useModularity bool) *Partition {
+	gain := func(block []int, target int) float64 {
+		return hg.blockPotentialGain(block, target, useModularity)
+	}
+
+	for iter := 0; iter < maxIterations && !isCancelled(hg.Cancel); iter++ {
+		changed := false
+		for _, rep := range ci.order {
+			block := ci.blocks[rep]
+			if comm, g := hg.bestConstrainedMove(ci, block, gain); g > stabilityEps {
+				hg.moveBlock(block, comm)
+				changed = true
+			} else if hg.improvingSwap(ci, block, useModularity) {
+				changed = true
+			}
+		}
+
+		hg.Iterations = iter + 1
+		if !changed {
+			break
+		}
+	}
+	return hg.Partition
+}
+
+// ============================================================
+// ЗАГРУЗКА ИЗ ФАЙЛА
+// ============================================================
+
+// constraintsJSON - файл ограничений; узлы задаются именами, как в разбиениях
+type constraintsJSON struct {
+	ExactK     int        `json:"exact_k"`
+	MinSize    int        `json:"min_size"`
+	MaxSize    int        `json:"max_size"`
+	MustLink   [][]string `json:"must_link"`
+	CannotLink [][]string `json:"cannot_link"`
+}
+
+// LoadConstraintsFromJSON читает ограничения; nameToID переводит имена узлов в номера
+func LoadConstraintsFromJSON(filename string, nameToID map[string]int) (*Constraints, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+	var raw constraintsJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	resolve := func(names []string) ([]int, error) {
+		ids := make([]int, 0, len(names))
+		for _, name := range names {
+			id, ok := nameToID[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: неизвестный узел %q", filename, name)
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	c := &Constraints{ExactK: raw.ExactK, MinSize: raw.MinSize, MaxSize: raw.MaxSize}
+	for _, group := range raw.MustLink {
+		ids, err := resolve(group)
+		if err != nil {
+			return nil, err
+		}
+		c.MustLink = append(c.MustLink, ids)
+	}
+	for _, pair := range raw.CannotLink {
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("%s: cannot_link ожидает пары узлов, получено %v", filename, pair)
+		}
+		ids, err := resolve(pair)
+		if err != nil {
+			return nil, err
+		}
+		c.CannotLink = append(c.CannotLink, [2]int{ids[0], ids[1]})
+	}
+	return c, nil
+}
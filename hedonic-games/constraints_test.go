@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestConstraintsCheck - Check находит каждое нарушение своего вида
+func TestConstraintsCheck(t *testing.T) {
+	// сообщества: {0,1,2}, {3}, {4,5}
+	p := partitionOf(0, 0, 0, 1, 2, 2)
+	tests := []struct {
+		name string
+		c    *Constraints
+		want []string
+	}{
+		{"none", nil, nil},
+		{"satisfied", &Constraints{ExactK: 3, MaxSize: 3, MustLink: [][]int{{0, 2}}, CannotLink: [][2]int{{0, 3}}}, nil},
+		{"exact-k", &Constraints{ExactK: 2}, []string{"exact-k"}},
+		{"sizes", &Constraints{MinSize: 2, MaxSize: 2}, []string{"max-size", "min-size"}},
+		{"must-link", &Constraints{MustLink: [][]int{{1, 4, 5}}}, []string{"must-link"}},
+		{"cannot-link", &Constraints{CannotLink: [][2]int{{0, 1}, {3, 4}, {4, 5}}}, []string{"cannot-link", "cannot-link"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range tt.c.Check(p) {
+				got = append(got, v.Kind)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("нарушения %v, ожидались %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestInfeasibleConstraintsReported - несовместные ограничения возвращаются ошибкой
+// из конструктора, обеих динамик и IsNashStable, а не пропускаются молча
+func TestInfeasibleConstraintsReported(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *Constraints
+	}{
+		{"must and cannot link", &Constraints{MustLink: [][]int{{0, 1, 2}}, CannotLink: [][2]int{{2, 0}}}},
+		{"min above max", &Constraints{MinSize: 5, MaxSize: 3}},
+		{"k times max below n", &Constraints{ExactK: 3, MaxSize: 10}},
+		{"k times min above n", &Constraints{ExactK: 4, MinSize: 10}},
+		{"block above max", &Constraints{MaxSize: 2, MustLink: [][]int{{0, 1, 2}}}},
+		{"missing node", &Constraints{CannotLink: [][2]int{{0, 99}}}},
+	}
+	g := LoadKarateClub()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHedonicGameWithConstraints(*g, 0.4, tt.c, NewRand(1)); err == nil {
+				t.Errorf("NewHedonicGameWithConstraints: нет ошибки")
+			}
+
+			hg := NewHedonicGame(*g, 0.4)
+			hg.SetSeed(1)
+			hg.Constraints = tt.c
+			before := hg.Partition.Clone()
+			if _, err := hg.FindNashStablePartition_WithPotential(100, false); err == nil {
+				t.Errorf("FindNashStablePartition_WithPotential: нет ошибки")
+			}
+			if _, err := hg.FindStablePartition_BetterResponse(100, SchedulerRoundRobin); err == nil {
+				t.Errorf("FindStablePartition_BetterResponse: нет ошибки")
+			}
+			if VariationOfInformation(hg.Partition, before) != 0 {
+				t.Errorf("динамика изменила разбиение при несовместных ограничениях")
+			}
+			if stable, err := hg.IsNashStable(false); err == nil {
+				t.Errorf("IsNashStable = %v без ошибки", stable)
+			}
+		})
+	}
+}
+
+// TestConstrainedDynamics - обе динамики не нарушают ограничений, динамика по
+// потенциалу приходит к разбиению, устойчивому относительно допустимых ходов
+func TestConstrainedDynamics(t *testing.T) {
+	g := LoadKarateClub()
+	tests := []struct {
+		name string
+		c    *Constraints
+	}{
+		{"exact k", &Constraints{ExactK: 3}},
+		{"sizes", &Constraints{MinSize: 6, MaxSize: 12}},
+		{"links", &Constraints{MustLink: [][]int{{0, 33}, {1, 2, 3}}, CannotLink: [][2]int{{0, 1}, {32, 2}}}},
+		{"all", &Constraints{ExactK: 2, MinSize: 10, MustLink: [][]int{{4, 5, 6}}, CannotLink: [][2]int{{0, 33}}}},
+	}
+	for _, tt := range tests {
+		for _, useModularity := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/7.2=%v", tt.name, useModularity), func(t *testing.T) {
+				hg, err := NewHedonicGameWithConstraints(*g, 0.4, tt.c, NewRand(3))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v := hg.CheckConstraints(); v != nil {
+					t.Fatalf("начальное разбиение нарушает ограничения: %v", v)
+				}
+				equilibrate(t, hg, useModularity)
+				if v := hg.CheckConstraints(); v != nil {
+					t.Errorf("динамика по потенциалу нарушила ограничения: %v", v)
+				}
+				if !nashStable(t, hg, useModularity) {
+					t.Errorf("разбиение не устойчиво относительно допустимых ходов")
+				}
+
+				if _, err := hg.FindStablePartition_BetterResponse(100, SchedulerRandomOrder); err != nil {
+					t.Fatal(err)
+				}
+				if v := hg.CheckConstraints(); v != nil {
+					t.Errorf("динамика улучшающих ответов нарушила ограничения: %v", v)
+				}
+			})
+		}
+	}
+}
+
+// TestTargetK - NewHedonicGameWithTargetK даёт ровно K сообществ (K не больше числа узлов)
+func TestTargetK(t *testing.T) {
+	g := LoadKarateClub()
+	for _, k := range []int{1, 2, 5, 34, 50} {
+		hg, err := NewHedonicGameWithTargetK(*g, 0.4, k, NewRand(1))
+		if err != nil {
+			t.Fatalf("K=%d: %v", k, err)
+		}
+		if got, want := hg.GetNumberOfCommunities(), min(k, g.NumNodes()); got != want {
+			t.Errorf("K=%d: сообществ %d, ожидалось %d", k, got, want)
+		}
+	}
+}
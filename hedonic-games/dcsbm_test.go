@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// bruteForceDCSBM - ½·Σ_ij [A_ij·log λ_ij - λ_ij] по всем упорядоченным парам,
+// λ_ij = θ_i·θ_j·e_rs с оценками θ_i = k_i/κ_r (граф без петель)
+func bruteForceDCSBM(g *Graph, p *Partition) float64 {
+	nodes := g.GetNodeList()
+	kappa := make(map[int]float64)
+	e := make(map[[2]int]float64)
+	for _, u := range nodes {
+		kappa[p.Community(u)] += g.Degree(u)
+		for _, v := range g.GetNeighbors(u) {
+			e[[2]int{p.Community(u), p.Community(v)}] += g.Weight(u, v)
+		}
+	}
+	ll := 0.0
+	for _, u := range nodes {
+		for _, v := range nodes {
+			r, s := p.Community(u), p.Community(v)
+			lambda := g.Degree(u) / kappa[r] * g.Degree(v) / kappa[s] * e[[2]int{r, s}]
+			if a := g.Weight(u, v); a > 0 {
+				ll += 0.5 * a * math.Log(lambda)
+			}
+			ll -= 0.5 * lambda
+		}
+	}
+	return ll
+}
+
+// TestDCSBMLogLikelihood - формула через суммы по блокам равна прямому подсчёту
+// пуассоновского правдоподобия, а дробление сообщества его не уменьшает
+// (вложенная модель с большим числом параметров)
+func TestDCSBMLogLikelihood(t *testing.T) {
+	g := LoadKarateClub()
+	rng := NewRand(8)
+	ml := NewMLModel(g, 0.3, 1)
+	for trial := 0; trial < 20; trial++ {
+		p := initializeRandomPartition(g, 2+trial%4, rng)
+		got, want := ml.DCSBMLogLikelihood(p), bruteForceDCSBM(g, p)
+		if math.Abs(got-want) > 1e-9*math.Abs(want) {
+			t.Errorf("испытание %d: L = %g, прямой подсчёт %g", trial, got, want)
+		}
+
+		refined := p.Clone()
+		fresh := refined.MaxCommunity() + 1
+		for _, node := range p.SortedMembers(p.Communities()[0]) {
+			if rng.Intn(2) == 0 {
+				refined.Set(node, fresh)
+			}
+		}
+		if ml.DCSBMLogLikelihood(refined) < ml.DCSBMLogLikelihood(p)-1e-9 {
+			t.Errorf("испытание %d: дробление уменьшило L", trial)
+		}
+	}
+	if k := DCSBMParameters(10, 3); k != 13 {
+		t.Errorf("DCSBMParameters(10, 3) = %d, ожидалось 6 + 7", k)
+	}
+}
+
+// TestSelectNumCommunities - на трёх явных группах оба критерия выбирают K = 3
+func TestSelectNumCommunities(t *testing.T) {
+	g, truth, err := GenerateFromSpec("planted:groups=3,size=15,pin=0.6,pout=0.02,seed=4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule := DefaultAnnealingSchedule(ScheduleGeometric, 5)
+	schedule.SweepsPerStep = 5
+	for _, criterion := range []string{"bic", "mdl"} {
+		t.Run(criterion, func(t *testing.T) {
+			ml := NewMLModel(g, 0.3, 1)
+			ml.SetSeed(2)
+			objective := ml.Objective
+			sel, err := ml.SelectNumCommunities(1, 5, criterion, schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sel.Scores) != 5 {
+				t.Fatalf("оценено %d значений K, ожидалось 5", len(sel.Scores))
+			}
+			best := sel.BestScore()
+			var scores []string
+			for _, s := range sel.Scores {
+				scores = append(scores, fmt.Sprintf("K=%d BIC=%.1f MDL=%.1f", s.K, s.BIC, s.MDL))
+			}
+			if best.K != 3 || NMI(best.Partition, truth) < 1-1e-12 {
+				t.Errorf("выбрано K = %d (NMI %g): %v", best.K, NMI(best.Partition, truth), scores)
+			}
+			for _, s := range sel.Scores {
+				if s.Partition.NumCommunities() != s.K {
+					t.Errorf("у модели K = %d разбиение на %d сообществ", s.K, s.Partition.NumCommunities())
+				}
+			}
+			if ml.Objective != objective || ml.Constraints != nil {
+				t.Error("SelectNumCommunities не вернул цель и ограничения модели")
+			}
+		})
+	}
+	if _, err := NewMLModel(g, 0.3, 1).SelectNumCommunities(1, 3, "aic", schedule); err == nil {
+		t.Error("неизвестный критерий должен быть отклонён")
+	}
+}
@@ -0,0 +1,346 @@
+// dynamic.go - изменения графа живой игры и тёплый старт динамики
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GraphUpdateKind - вид изменения графа
+type GraphUpdateKind string
+
+const (
+	UpdateAddEdge    GraphUpdateKind = "+"     // добавить ребро (дугу) или изменить его вес
+	UpdateRemoveEdge GraphUpdateKind = "-"     // удалить ребро (дугу)
+	UpdateAddNode    GraphUpdateKind = "+node" // добавить узел (в одиночное сообщество)
+	UpdateRemoveNode GraphUpdateKind = "-node" // удалить узел со всеми рёбрами
+)
+
+// GraphUpdate - одно изменение графа. Для рёбер U, V - концы (в ориентированном
+// графе - дуга U→V), Weight - вес добавляемого ребра (0 - 1); для узлов задан только U
+type GraphUpdate struct {
+	Kind   GraphUpdateKind
+	U, V   int
+	Weight float64
+}
+
+// ApplyUpdates применяет изменения к графу игры и восстанавливает равновесие
+// динамикой лучших ответов по потенциалу (как FindNashStablePartition_WithPotential),
+// начиная не с одиночек, а с текущего разбиения. Агрегаты сообществ обновляются
+// по изменённым рёбрам, без пересборки. В очередь сначала встают только
+// затронутые узлы: концы изменённых рёбер и их соседи, а у удалённого узла - его
+// соседи, его сообщество и узлы, связанные с этим сообществом. После перехода узла
+// из A в B в очередь встают его соседи, члены A и B и узлы, связанные с A
+// (присоединиться к A стало выгоднее).
+//
+// Локальной очереди достаточно всюду, кроме (7.2) с нулевой моделью Ньюмана–Гирвана
+// при изменившемся m: m входит в прирост каждого узла, поэтому после очереди один
+// проход по всем узлам возвращает в неё тех, кому теперь выгоден ход. То же для
+// (7.1) с Preference: полезность модели может зависеть от графа целиком (например,
+// от числа узлов). maxIterations ограничивает число опустошений очереди
+// (hg.Iterations - их число, не больше двух).
+//
+// Агрегаты должны соответствовать разбиению (после динамики игры это так; после
+// прямого изменения hg.Partition нужен RebuildAggregates). Новые узлы начинают
+// одиночками; удаление отсутствующего ребра или узла ничего не меняет. Граф игры
+// меняется на месте (карты общие с Graph, из которого создана игра).
+// Возвращает узлы, сменившие сообщество (по возрастанию; новый узел - если не остался один)
+func (hg *HedonicGame) ApplyUpdates(updates []GraphUpdate, maxIterations int, useModularity bool) ([]int, error) {
+	if hg.Constraints != nil {
+		return nil, fmt.Errorf("тёплый старт не поддерживает ограничения")
+	}
+	for _, up := range updates {
+		switch up.Kind {
+		case UpdateAddEdge, UpdateRemoveEdge, UpdateAddNode, UpdateRemoveNode:
+		default:
+			return nil, fmt.Errorf("неизвестное изменение графа %q", up.Kind)
+		}
+	}
+	if hg.commDegree == nil {
+		hg.RebuildAggregates()
+	}
+	totalWeight := hg.totalWeight
+
+	affected := make(map[int]bool)
+	touch := func(nodes ...int) {
+		for _, u := range nodes {
+			affected[u] = true
+			for _, v := range hg.G.GetNeighbors(u) {
+				affected[v] = true
+			}
+		}
+	}
+	for _, up := range updates {
+		switch up.Kind {
+		case UpdateAddEdge:
+			w := up.Weight
+			if w == 0 {
+				w = 1
+			}
+			hg.addNode(up.U)
+			hg.addNode(up.V)
+			hg.changeEdge(up.U, up.V, w)
+			touch(up.U, up.V)
+		case UpdateRemoveEdge:
+			if !hg.G.HasEdge(up.U, up.V) {
+				continue
+			}
+			touch(up.U, up.V) // соседи - до удаления, пока ребро есть
+			hg.changeEdge(up.U, up.V, 0)
+		case UpdateAddNode:
+			hg.addNode(up.U)
+			affected[up.U] = true
+		case UpdateRemoveNode:
+			if !hg.G.Nodes[up.U] {
+				continue
+			}
+			comm := hg.Partition.Community(up.U)
+			touch(up.U)
+			hg.removeNode(up.U)
+			touch(hg.Partition.Members(comm)...)
+			delete(affected, up.U)
+		}
+	}
+	// при удалении рёбер и узлов в affected могли остаться узлы, которых уже нет
+	for u := range affected {
+		if !hg.G.Nodes[u] {
+			delete(affected, u)
+		}
+	}
+
+	global := !useModularity && hg.Preference != nil
+	if useModularity && hg.NullModel != NullModelCPM && hg.totalWeight != totalWeight {
+		global = true
+	}
+
+	initial := make(map[int]int) // сообщество узла до первого перехода
+	queue := SortedCommunityIDs(affected)
+	inQueue := affected
+	enqueue := func(v int) {
+		if hg.G.Nodes[v] && !inQueue[v] {
+			inQueue[v] = true
+			queue = append(queue, v)
+		}
+	}
+
+	hg.Iterations = 0
+	for maxIterations <= 0 || hg.Iterations < maxIterations {
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			inQueue[node] = false
+
+			from := hg.Partition.Community(node)
+			target := hg.bestPotentialResponse(node, useModularity)
+			if target == from {
+				continue
+			}
+			if _, ok := initial[node]; !ok {
+				initial[node] = from
+			}
+			hg.MoveNode(node, target)
+			for _, v := range hg.G.GetNeighbors(node) {
+				enqueue(v)
+			}
+			for _, v := range hg.Partition.SortedMembers(target) {
+				enqueue(v)
+			}
+			for _, u := range hg.Partition.SortedMembers(from) {
+				enqueue(u)
+				for _, v := range hg.G.GetNeighbors(u) {
+					enqueue(v)
+				}
+			}
+			// узел с номером A или B пробует это сообщество как «новое» (bestPotentialResponse)
+			enqueue(from)
+			enqueue(target)
+		}
+		hg.Iterations++
+
+		if !global {
+			break
+		}
+		global = false
+		for _, node := range hg.G.GetNodeList() {
+			if hg.bestPotentialResponse(node, useModularity) != hg.Partition.Community(node) {
+				enqueue(node)
+			}
+		}
+		if len(queue) == 0 {
+			break
+		}
+	}
+
+	var changed []int
+	for _, node := range SortedCommunityIDs(initial) {
+		if hg.Partition.Community(node) != initial[node] && hg.G.Nodes[node] {
+			changed = append(changed, node)
+		}
+	}
+	return changed, nil
+}
+
+// addNode добавляет узел в граф и одиночное сообщество, если его ещё нет
+func (hg *HedonicGame) addNode(node int) {
+	if hg.G.Nodes[node] {
+		return
+	}
+	hg.G.AddNode(node)
+	comm := hg.freshCommunityID(node)
+	hg.Partition.Set(node, comm)
+	hg.degree[node] = 0
+	hg.commDegree[comm] = 0
+	hg.commEdges[comm] = 0
+	if hg.G.Directed {
+		hg.outDegree[node], hg.inDegree[node] = 0, 0
+		hg.commOut[comm], hg.commIn[comm] = 0, 0
+	}
+}
+
+// changeEdge задаёт вес ребра {u, v} (в ориентированном графе - дуги u→v),
+// w = 0 - удаляет его, и переносит разницу весов в агрегаты концов и их сообществ
+func (hg *HedonicGame) changeEdge(u, v int, w float64) {
+	oldWeight, oldArc := hg.G.Weight(u, v), hg.G.ArcWeight(u, v)
+	switch {
+	case w == 0 && hg.G.Directed:
+		hg.G.RemoveArc(u, v)
+	case w == 0:
+		hg.G.RemoveEdge(u, v)
+	case hg.G.Directed:
+		hg.G.AddArc(u, v, w)
+	default:
+		hg.G.AddWeightedEdge(u, v, w)
+	}
+
+	cu, cv := hg.Partition.Community(u), hg.Partition.Community(v)
+	if d := hg.G.Weight(u, v) - oldWeight; d != 0 {
+		hg.totalWeight += d
+		if u == v {
+			hg.degree[u] += 2 * d // петля входит в степень дважды
+			hg.commDegree[cu] += 2 * d
+		} else {
+			hg.degree[u] += d
+			hg.degree[v] += d
+			hg.commDegree[cu] += d
+			hg.commDegree[cv] += d
+			if cu == cv {
+				hg.commEdges[cu] += d
+			}
+		}
+	}
+	if hg.G.Directed {
+		d := hg.G.ArcWeight(u, v) - oldArc
+		hg.outDegree[u] += d
+		hg.inDegree[v] += d
+		hg.commOut[cu] += d
+		hg.commIn[cv] += d
+		hg.arcTotal += d
+	}
+}
+
+// removeNode удаляет узел: сначала его рёбра (с агрегатами), затем сам узел
+func (hg *HedonicGame) removeNode(node int) {
+	for _, v := range hg.G.GetNeighbors(node) {
+		hg.changeEdge(node, v, 0)
+		if hg.G.Directed && v != node {
+			hg.changeEdge(v, node, 0)
+		}
+	}
+	comm := hg.Partition.Community(node)
+	hg.Partition.Remove(node)
+	hg.G.RemoveNode(node)
+	delete(hg.degree, node)
+	delete(hg.outDegree, node)
+	delete(hg.inDegree, node)
+	if hg.Partition.Size(comm) == 0 {
+		delete(hg.commEdges, comm)
+		delete(hg.commDegree, comm)
+		delete(hg.commOut, comm)
+		delete(hg.commIn, comm)
+	}
+}
+
+// LoadGraphUpdates читает изменения графа, по одному в строке:
+//
+//	"+ u v [вес]" - добавить ребро (в ориентированном графе - дугу u→v) или задать вес
+//	"- u v"       - удалить ребро
+//	"+node u"     - добавить узел
+//	"-node u"     - удалить узел со всеми рёбрами
+//
+// Узлы - имена, как в файле графа; новым именам выдаются следующие свободные
+// номера (nameToID и idToName дополняются). Пустые строки и строки с # пропускаются
+func LoadGraphUpdates(filePath string, nameToID map[string]int, idToName map[int]string) ([]GraphUpdate, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	nextID := 0
+	for id := range idToName {
+		nextID = max(nextID, id+1)
+	}
+	resolve := func(name string, create bool) (int, bool) {
+		id, ok := nameToID[name]
+		if !ok && create {
+			id, ok = nextID, true
+			nextID++
+			nameToID[name] = id
+			idToName[id] = name
+		}
+		return id, ok
+	}
+
+	var updates []GraphUpdate
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		up := GraphUpdate{Kind: GraphUpdateKind(fields[0])}
+		args := fields[1:]
+		create := up.Kind == UpdateAddEdge || up.Kind == UpdateAddNode
+		var nodes []int
+		switch up.Kind {
+		case UpdateAddNode, UpdateRemoveNode:
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%s:%d: ожидается \"%s узел\"", filePath, lineNum, up.Kind)
+			}
+		case UpdateAddEdge:
+			if len(args) != 2 && len(args) != 3 {
+				return nil, fmt.Errorf("%s:%d: ожидается \"+ узел узел [вес]\"", filePath, lineNum)
+			}
+		case UpdateRemoveEdge:
+			if len(args) != 2 {
+				return nil, fmt.Errorf("%s:%d: ожидается \"- узел узел\"", filePath, lineNum)
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: неизвестное изменение %q (+, -, +node, -node)", filePath, lineNum, fields[0])
+		}
+		for _, name := range args[:min(len(args), 2)] {
+			id, ok := resolve(name, create)
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: узел %q отсутствует в графе", filePath, lineNum, name)
+			}
+			nodes = append(nodes, id)
+		}
+		up.U = nodes[0]
+		if len(nodes) > 1 {
+			up.V = nodes[1]
+		}
+		if len(args) == 3 {
+			if up.Weight, err = strconv.ParseFloat(args[2], 64); err != nil || up.Weight <= 0 {
+				return nil, fmt.Errorf("%s:%d: некорректный вес %q", filePath, lineNum, args[2])
+			}
+		}
+		updates = append(updates, up)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	return updates, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// TestApplyUpdatesInvariants - после тёплого старта агрегаты совпадают
+// с пересборкой, разбиение Нэш-стабильно и покрывает те же узлы, что и
+// холодный запуск на изменённом графе
+func TestApplyUpdatesInvariants(t *testing.T) {
+	updates := []GraphUpdate{
+		{Kind: UpdateAddEdge, U: 0, V: 33},
+		{Kind: UpdateAddEdge, U: 5, V: 24, Weight: 3},
+		{Kind: UpdateAddEdge, U: 2, V: 3, Weight: 2}, // изменение веса
+		{Kind: UpdateRemoveEdge, U: 0, V: 1},
+		{Kind: UpdateRemoveEdge, U: 32, V: 33},
+		{Kind: UpdateRemoveEdge, U: 4, V: 9}, // ребра нет
+		{Kind: UpdateAddNode, U: 40},
+		{Kind: UpdateAddEdge, U: 40, V: 16},
+		{Kind: UpdateAddEdge, U: 41, V: 40}, // новый узел с ребром
+		{Kind: UpdateRemoveNode, U: 11},
+		{Kind: UpdateAddEdge, U: 7, V: 7}, // петля
+	}
+	tests := []struct {
+		name          string
+		graph         func() *Graph
+		useModularity bool
+		nullModel     NullModel
+		preference    Preference
+	}{
+		{name: "undirected/7.1", graph: LoadKarateClub},
+		{name: "undirected/7.2", graph: LoadKarateClub, useModularity: true},
+		{name: "weighted/7.2", graph: weightedKarate, useModularity: true},
+		{name: "weighted/cpm", graph: weightedKarate, useModularity: true, nullModel: NullModelCPM},
+		{name: "preference", graph: LoadKarateClub, preference: &FractionalPreference{}},
+		{name: "directed/7.1", graph: func() *Graph { return randomDirectedGraph(42, 160, NewRand(3)) }},
+		{name: "directed/7.2", graph: func() *Graph { return randomDirectedGraph(42, 160, NewRand(3)) }, useModularity: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configure := func(hg *HedonicGame) {
+				hg.SetSeed(1)
+				hg.NullModel = tt.nullModel
+				hg.Preference = tt.preference
+				if tt.nullModel == NullModelCPM {
+					hg.Resolution = 0.3
+				}
+			}
+			hg := NewHedonicGame(*tt.graph(), 0.4)
+			configure(hg)
+			equilibrate(t, hg, tt.useModularity)
+
+			if _, err := hg.ApplyUpdates(updates, 100, tt.useModularity); err != nil {
+				t.Fatal(err)
+			}
+			aggregatesMatch(t, hg) // до IsNashStable: она пересобирает агрегаты
+			if !nashStable(t, hg, tt.useModularity) {
+				t.Errorf("разбиение после ApplyUpdates не Нэш-стабильно")
+			}
+
+			cold := NewHedonicGame(*hg.G.Clone(), 0.4)
+			configure(cold)
+			equilibrate(t, cold, tt.useModularity)
+			if !nashStable(t, cold, tt.useModularity) {
+				t.Errorf("холодный запуск не Нэш-стабилен")
+			}
+			if got, want := hg.Partition.Nodes(), cold.Partition.Nodes(); !slices.Equal(got, want) {
+				t.Errorf("узлы разбиения %v, у холодного запуска %v", got, want)
+			}
+		})
+	}
+}
+
+// TestApplyUpdatesRandom - случайные серии изменений подряд, без пересборки
+// агрегатов между ними: после каждой серии агрегаты совпадают с пересборкой,
+// разбиение Нэш-стабильно
+func TestApplyUpdatesRandom(t *testing.T) {
+	for _, useModularity := range []bool{false, true} {
+		rng := rand.New(rand.NewSource(5))
+		hg := NewHedonicGame(*weightedKarate(), 0.4)
+		hg.SetSeed(2)
+		equilibrate(t, hg, useModularity)
+
+		for batch := 0; batch < 20; batch++ {
+			var updates []GraphUpdate
+			for i := 0; i < 4; i++ {
+				u, v := rng.Intn(38), rng.Intn(38)
+				switch rng.Intn(5) {
+				case 0, 1:
+					updates = append(updates, GraphUpdate{Kind: UpdateAddEdge, U: u, V: v, Weight: float64(1 + rng.Intn(3))})
+				case 2, 3:
+					updates = append(updates, GraphUpdate{Kind: UpdateRemoveEdge, U: u, V: v})
+				default:
+					updates = append(updates, GraphUpdate{Kind: UpdateRemoveNode, U: u})
+				}
+			}
+			if _, err := hg.ApplyUpdates(updates, 100, useModularity); err != nil {
+				t.Fatal(err)
+			}
+			aggregatesMatch(t, hg)
+			// копия: IsNashStable пересобирает агрегаты, а следующая серия
+			// должна идти от инкрементальных
+			check := &HedonicGame{G: hg.G, Partition: hg.Partition.Clone(), Alpha: hg.Alpha}
+			if !nashStable(t, check, useModularity) {
+				t.Fatalf("7.2=%v, серия %d: разбиение не Нэш-стабильно", useModularity, batch)
+			}
+		}
+	}
+}
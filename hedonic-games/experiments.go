@@ -0,0 +1,482 @@
+// experiments.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// RunConfig описывает один запуск алгоритма на графе
+type RunConfig struct {
+	Algorithm     string  // "hedonic", "ml" или базовые "louvain", "leiden"
+	Alpha         float64 // штраф за незнакомцев (α)
+	Beta          float64 // обратная температура для ML
+	TargetK       int     // желаемое число сообществ (-1 = не ограничено)
+	MaxIterations int     // максимум итераций (для ML - число проходов Гиббса)
+	Seed          int64   // зерно ГСЧ (0 = случайное, фактическое попадает в результат)
+	UseModularity bool    // потенциал (7.2) вместо (7.1)
+	Resolution    float64 // разрешение γ потенциала (7.2) (0 - 1)
+	NullModel     string  // нулевая модель потенциала (7.2): ng | cpm (см. ParseNullModel)
+	Dynamics      string  // "potential" (рост потенциала) или "utility" (улучшающие ответы агентов)
+	Scheduler     string  // порядок ходов для Dynamics="utility"
+	Preference    string  // модель предпочтений (см. ParsePreference), "" - друзья/незнакомцы
+	Objective     string  // цель ML: potential | likelihood | em | dcsbm (см. ParseMLObjective)
+	Greedy        bool    // жадная доводка ML после сэмплирования
+	SelectK       string  // выбор числа сообществ DC-SBM по критерию bic | mdl ("" - не выбирать)
+	MaxK          int     // верхняя граница K при SelectK (0 - 10)
+
+	// Отжиг ML (Schedule = "" - фиксированная β = Beta все MaxIterations проходов).
+	// Иначе β идёт от BetaStart до Beta за 10 температур по MaxIterations/10 проходов
+	Schedule  string  // geometric | linear | logarithmic | adaptive
+	BetaStart float64 // начальная β (0 - Beta/100)
+	Reheats   int     // повторных нагревов
+	Plateau   int     // остановка после стольких проходов без улучшения или без смены сообществ (0 - нет)
+
+	// Жёсткие ограничения; TargetK > 0 задаёт Constraints.ExactK
+	Constraints Constraints
+
+	// Таблицы ценностей моделей предпочтений additively-separable, anonymous
+	// и fractional (см. LoadPreferenceValues)
+	PreferenceValues PreferenceValues
+
+	// Закрытие канала прерывает запуск между проходами динамики (nil - без отмены)
+	Cancel <-chan struct{} `json:"-"`
+}
+
+// ErrCancelled - запуск прерван через RunConfig.Cancel
+var ErrCancelled = errors.New("запуск отменён")
+
+// isCancelled - закрыт ли канал отмены (nil - никогда)
+func isCancelled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultRunConfig возвращает параметры по умолчанию
+func DefaultRunConfig() RunConfig {
+	return RunConfig{
+		Algorithm:     "hedonic",
+		Alpha:         0.3,
+		Beta:          1.0,
+		TargetK:       -1,
+		MaxIterations: 1000,
+		Dynamics:      "potential",
+		Scheduler:     string(SchedulerRoundRobin),
+	}
+}
+
+// hasResolution - задан ли потенциал (7.2), отличный от обычной модулярности
+func (cfg RunConfig) hasResolution() bool {
+	return cfg.UseModularity && ((cfg.Resolution != 0 && cfg.Resolution != 1) || NullModel(cfg.NullModel) == NullModelCPM)
+}
+
+// constraints - ограничения запуска (nil - нет)
+func (cfg RunConfig) constraints() *Constraints {
+	c := cfg.Constraints
+	if cfg.TargetK > 0 {
+		c.ExactK = cfg.TargetK
+	}
+	if c.IsZero() {
+		return nil
+	}
+	return &c
+}
+
+// RunExperiment запускает алгоритм по конфигурации и собирает строку результатов.
+// Если запуск отменён через cfg.Cancel, возвращает ErrCancelled
+func RunExperiment(g *Graph, cfg RunConfig) (*Partition, ExperimentResult, error) {
+	cfg.Seed = ResolveSeed(cfg.Seed)
+
+	var run func(*Graph, RunConfig) (*Partition, ExperimentResult, error)
+	switch cfg.Algorithm {
+	case "hedonic":
+		run = runHedonic
+	case "ml":
+		run = runML
+	case "louvain", "leiden":
+		run = runMultilevel
+	default:
+		return nil, ExperimentResult{}, fmt.Errorf("неизвестный алгоритм %q", cfg.Algorithm)
+	}
+	partition, result, err := run(g, cfg)
+	if err == nil && isCancelled(cfg.Cancel) {
+		return nil, ExperimentResult{}, ErrCancelled
+	}
+	return partition, result, err
+}
+
+// runHedonic - гедоническая игра: динамика лучших ответов по потенциалу
+// или улучшающих ответов по полезности агентов
+func runHedonic(g *Graph, cfg RunConfig) (*Partition, ExperimentResult, error) {
+	start := time.Now()
+
+	hg, err := newHedonicGameFromConfig(g, cfg)
+	if err != nil {
+		return nil, ExperimentResult{}, err
+	}
+	preference := hg.Preference
+
+	var partition *Partition
+	algorithmSuffix := ""
+	if cfg.hasResolution() {
+		algorithmSuffix = fmt.Sprintf("_%s_gamma%s", hg.NullModel, formatParam(hg.resolution()))
+	}
+	switch cfg.Dynamics {
+	case "", "potential":
+		partition, err = hg.FindNashStablePartition_WithPotential(cfg.MaxIterations, cfg.UseModularity)
+	case "utility":
+		var scheduler Scheduler
+		if scheduler, err = ParseScheduler(cfg.Scheduler); err != nil {
+			return nil, ExperimentResult{}, err
+		}
+		partition, err = hg.FindStablePartition_BetterResponse(cfg.MaxIterations, scheduler)
+		algorithmSuffix += "_BR_" + string(scheduler)
+	default:
+		return nil, ExperimentResult{}, fmt.Errorf("неизвестная динамика %q", cfg.Dynamics)
+	}
+	if err != nil {
+		return nil, ExperimentResult{}, err
+	}
+	potential := hg.ComputePotentialCurrent(cfg.UseModularity)
+	modularity := ComputeModularity(g, partition)
+
+	elapsed := time.Since(start).Seconds()
+
+	testName, algorithm, parameter := "Hedonic_NoConstraint", "Hedonic", cfg.Alpha
+	if hg.Constraints != nil {
+		testName = "Hedonic_Constrained"
+	}
+	if cfg.TargetK > 0 {
+		testName = fmt.Sprintf("Hedonic_K%d", cfg.TargetK)
+		algorithm = "Hedonic_FixedK"
+		parameter = float64(cfg.TargetK)
+	}
+
+	result := NewExperimentResult(
+		testName,
+		algorithm,
+		parameter,
+		g,
+		partition,
+		potential,
+		modularity,
+		hg.Iterations,
+		hg.Iterations,
+		elapsed,
+	)
+	result.Algorithm += algorithmSuffix
+	if preference != nil {
+		result.Algorithm += "_" + preference.Name()
+	}
+	result.Seed = cfg.Seed
+	result.Trace = hg.Trace
+	result.Violations = hg.CheckConstraints()
+	return partition, result, nil
+}
+
+// newHedonicGameFromConfig - игра с начальным разбиением (с учётом ограничений),
+// предпочтениями и нулевой моделью из конфигурации запуска
+func newHedonicGameFromConfig(g *Graph, cfg RunConfig) (*HedonicGame, error) {
+	rng := NewRand(cfg.Seed)
+
+	var hg *HedonicGame
+	var err error
+	if constraints := cfg.constraints(); constraints != nil {
+		hg, err = NewHedonicGameWithConstraints(*g, cfg.Alpha, constraints, rng)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		hg = NewHedonicGame(*g, cfg.Alpha)
+		hg.Rng = rng
+	}
+	preference, err := ParsePreference(cfg.Preference, cfg.PreferenceValues, g)
+	if err != nil {
+		return nil, err
+	}
+	hg.Preference = preference
+	if hg.NullModel, err = ParseNullModel(cfg.NullModel); err != nil {
+		return nil, err
+	}
+	hg.Resolution = cfg.Resolution
+	hg.Cancel = cfg.Cancel
+	return hg, nil
+}
+
+// runML - сэмплирование Гиббса для ML-модели при фиксированной температуре
+func runML(g *Graph, cfg RunConfig) (*Partition, ExperimentResult, error) {
+	start := time.Now()
+
+	rng := NewRand(cfg.Seed)
+
+	ml := NewMLModel(g, cfg.Alpha, cfg.Beta)
+	ml.Rng = rng
+	ml.Cancel = cfg.Cancel
+	ml.Constraints = cfg.constraints()
+	mlObjective, err := ParseMLObjective(cfg.Objective)
+	if err != nil {
+		return nil, ExperimentResult{}, err
+	}
+	if err := mlObjective.CheckGraph(g); err != nil {
+		return nil, ExperimentResult{}, err
+	}
+	ml.Objective = mlObjective
+
+	var partition *Partition
+	if ml.Constraints != nil {
+		if partition, err = FeasiblePartition(g, ml.Constraints, rng); err != nil {
+			return nil, ExperimentResult{}, err
+		}
+	} else {
+		partition = initializeRandomPartition(g, 4, rng)
+	}
+
+	iterations, convergedAt := cfg.MaxIterations, cfg.MaxIterations
+	var annealed *GibbsSamplingResult
+	var selection *ModelSelection
+	var likelihoodHistory, alphaHistory []float64
+	if cfg.SelectK != "" {
+		if cfg.Objective == "" {
+			ml.Objective = ObjectiveDCSBM
+		} else if ml.Objective != ObjectiveDCSBM {
+			return nil, ExperimentResult{}, fmt.Errorf("выбор K реализован только для -objective dcsbm")
+		}
+		schedule := DefaultAnnealingSchedule(ScheduleGeometric, cfg.Beta)
+		schedule.SweepsPerStep = max(1, cfg.MaxIterations/schedule.Steps)
+		if cfg.Schedule != "" {
+			if schedule, err = cfg.annealingSchedule(); err != nil {
+				return nil, ExperimentResult{}, err
+			}
+		}
+		maxK := cfg.MaxK
+		if maxK <= 0 {
+			maxK = 10
+		}
+		if selection, err = ml.SelectNumCommunities(1, maxK, cfg.SelectK, schedule); err != nil {
+			return nil, ExperimentResult{}, err
+		}
+		partition = selection.BestScore().Partition
+	} else if cfg.Schedule != "" {
+		schedule, err := cfg.annealingSchedule()
+		if err != nil {
+			return nil, ExperimentResult{}, err
+		}
+		if annealed, err = ml.Anneal(partition, schedule); err != nil {
+			return nil, ExperimentResult{}, err
+		}
+		partition = annealed.BestPartition
+		iterations, convergedAt = annealed.TotalIterations, annealed.ConvergedAt
+		likelihoodHistory, alphaHistory = annealed.LikelihoodHistory, annealed.AlphaHistory
+	} else {
+		for iter := 0; iter < cfg.MaxIterations && !isCancelled(cfg.Cancel); iter++ {
+			if _, _, err := ml.gibbsSweep(partition); err != nil {
+				return nil, ExperimentResult{}, err
+			}
+			likelihoodHistory = append(likelihoodHistory, ml.ProfileLikelihood(partition))
+			if ml.Objective == ObjectiveEM {
+				alphaHistory = append(alphaHistory, ml.Alpha)
+			}
+		}
+	}
+	if cfg.Greedy {
+		c := ml.Constraints
+		if c != nil && (len(c.MustLink) > 0 || len(c.CannotLink) > 0 || c.MinSize > 0 || c.MaxSize > 0) {
+			return nil, ExperimentResult{}, fmt.Errorf("жадная доводка ML поддерживает только ограничение на K")
+		}
+		iterations += ml.GreedyOptimize(partition, c != nil || selection != nil, cfg.MaxIterations)
+	}
+	if ml.Objective == ObjectiveEM {
+		ml.FitAlpha(partition)
+	}
+
+	objective := ml.objective(partition)
+	modularity := ComputeModularity(g, partition)
+	elapsed := time.Since(start).Seconds()
+
+	testName, algorithm, parameter := "ML_NoConstraint", fmt.Sprintf("ML_beta_%.1f", cfg.Beta), cfg.Alpha
+	if ml.Constraints != nil {
+		testName = "ML_Constrained"
+	}
+	if cfg.TargetK > 0 {
+		testName = fmt.Sprintf("ML_K%d", cfg.TargetK)
+		algorithm = "ML_FixedK"
+		parameter = float64(cfg.TargetK)
+	}
+
+	result := NewExperimentResult(
+		testName,
+		algorithm,
+		parameter,
+		g,
+		partition,
+		objective,
+		modularity,
+		iterations,
+		convergedAt,
+		elapsed,
+	)
+	if annealed != nil {
+		result.Algorithm += "_anneal_" + cfg.Schedule
+		result.Temperatures = annealed.Temperatures
+	}
+	if ml.Objective != ObjectivePotential {
+		result.Algorithm += "_" + string(ml.Objective)
+	}
+	if selection != nil {
+		result.Algorithm += "_select_" + selection.Criterion
+		result.ModelScores = selection.Scores
+	}
+	if cfg.Greedy {
+		result.Algorithm += "_greedy"
+	}
+	if ml.Objective == ObjectiveEM && cfg.TargetK <= 0 {
+		result.Parameter = ml.Alpha // α, выведенное из итоговых Pin/Pout
+	}
+	result.LikelihoodHistory = likelihoodHistory
+	result.AlphaHistory = alphaHistory
+	result.Seed = cfg.Seed
+	result.Violations = ml.Constraints.Check(partition)
+	return partition, result, nil
+}
+
+// runMultilevel - базовые методы Louvain и Leiden по Q_γ или CPM
+// (Resolution, NullModel; MaxIterations ограничивает проходы на уровне).
+// Ограничения не поддерживаются; Parameter - γ, Potential - Q_γ или H_CPM.
+// Ориентированный граф методы оптимизируют по симметризации, и Potential - её
+// качество; Modularity - Лейхта–Ньюмана, как у остальных алгоритмов
+func runMultilevel(g *Graph, cfg RunConfig) (*Partition, ExperimentResult, error) {
+	start := time.Now()
+
+	if cfg.constraints() != nil {
+		return nil, ExperimentResult{}, fmt.Errorf("%s не поддерживает ограничения и фиксированное K", cfg.Algorithm)
+	}
+	nullModel, err := ParseNullModel(cfg.NullModel)
+	if err != nil {
+		return nil, ExperimentResult{}, err
+	}
+	opts := MultilevelOptions{Resolution: cfg.Resolution, NullModel: nullModel, MaxSweeps: cfg.MaxIterations, Cancel: cfg.Cancel}
+	if opts.Resolution == 0 {
+		opts.Resolution = 1
+	}
+
+	rng := NewRand(cfg.Seed)
+	var detected *MultilevelResult
+	testName := "Louvain"
+	if cfg.Algorithm == "leiden" {
+		detected = Leiden(g, opts, rng)
+		testName = "Leiden"
+	} else {
+		detected = Louvain(g, opts, rng)
+	}
+	partition := detected.Partition
+	modularity := ComputeModularity(g, partition)
+	elapsed := time.Since(start).Seconds()
+
+	result := NewExperimentResult(
+		testName,
+		fmt.Sprintf("%s_%s", testName, nullModel),
+		opts.Resolution,
+		g,
+		partition,
+		detected.Quality,
+		modularity,
+		len(detected.Levels),
+		len(detected.Levels),
+		elapsed,
+	)
+	result.Seed = cfg.Seed
+	return partition, result, nil
+}
+
+// annealingSchedule - расписание отжига запуска (см. поля Schedule... в RunConfig)
+func (cfg RunConfig) annealingSchedule() (AnnealingSchedule, error) {
+	kind, err := ParseScheduleKind(cfg.Schedule)
+	if err != nil {
+		return AnnealingSchedule{}, err
+	}
+	if kind == ScheduleList {
+		return AnnealingSchedule{}, fmt.Errorf("расписание list задаётся списком β и недоступно из конфигурации")
+	}
+	schedule := DefaultAnnealingSchedule(kind, cfg.Beta)
+	schedule.SweepsPerStep = max(1, cfg.MaxIterations/schedule.Steps)
+	if cfg.BetaStart > 0 {
+		schedule.BetaStart = cfg.BetaStart
+	}
+	schedule.Reheats = cfg.Reheats
+	schedule.Convergence = ConvergenceCriterion{PlateauSweeps: cfg.Plateau, StableSweeps: cfg.Plateau}
+	return schedule, schedule.Validate()
+}
+
+// LegacyKarateSweep воспроизводит четыре серии экспериментов, которые раньше были зашиты в main.go
+func LegacyKarateSweep() []RunConfig {
+	var cells []RunConfig
+
+	// ЭКСПЕРИМЕНТ 1: Гедонические игры с разными альфа
+	for _, alpha := range []float64{0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.07, 0.08, 0.09, 0.1, 0.3, 0.5, 0.7, 0.9} {
+		cells = append(cells, RunConfig{Algorithm: "hedonic", Alpha: alpha, TargetK: -1, MaxIterations: 1000})
+	}
+
+	// ЭКСПЕРИМЕНТ 2: Гедонические игры с фиксированным K
+	targetKValues := []int{2, 3, 4, 5, 6}
+	for _, targetK := range targetKValues {
+		cells = append(cells, RunConfig{Algorithm: "hedonic", Alpha: 0.3, TargetK: targetK, MaxIterations: 1000})
+	}
+
+	// ЭКСПЕРИМЕНТ 3: Maximum Likelihood с разными параметрами
+	for _, alpha := range []float64{0.2, 0.5, 0.8} {
+		for _, beta := range []float64{0.1, 0.5, 1.0} {
+			cells = append(cells, RunConfig{Algorithm: "ml", Alpha: alpha, Beta: beta, TargetK: -1, MaxIterations: 100})
+		}
+	}
+
+	// ЭКСПЕРИМЕНТ 4: ML с фиксированным K
+	for _, targetK := range targetKValues {
+		cells = append(cells, RunConfig{Algorithm: "ml", Alpha: 0.5, Beta: 1.0, TargetK: targetK, MaxIterations: 100})
+	}
+
+	return cells
+}
+
+// PartitionFileName строит имя JSON-файла разбиения в стиле results/
+func PartitionFileName(prefix string, cfg RunConfig, actualK int) string {
+	switch {
+	case cfg.Algorithm == "hedonic" && cfg.TargetK > 0:
+		return fmt.Sprintf("%s_hedonic_k%d_actual%d.json", prefix, cfg.TargetK, actualK)
+	case cfg.Algorithm == "hedonic" && cfg.hasResolution():
+		nullModel := cfg.NullModel
+		if nullModel == "" {
+			nullModel = string(NullModelNewmanGirvan)
+		}
+		return fmt.Sprintf("%s_hedonic_%s_gamma_%s.json", prefix, nullModel, formatParam(cfg.Resolution))
+	case cfg.Algorithm == "hedonic":
+		return fmt.Sprintf("%s_hedonic_alpha_%s.json", prefix, formatParam(cfg.Alpha))
+	case cfg.Algorithm == "louvain" || cfg.Algorithm == "leiden":
+		nullModel := cfg.NullModel
+		if nullModel == "" {
+			nullModel = string(NullModelNewmanGirvan)
+		}
+		gamma := cfg.Resolution
+		if gamma == 0 {
+			gamma = 1
+		}
+		return fmt.Sprintf("%s_%s_%s_gamma_%s.json", prefix, cfg.Algorithm, nullModel, formatParam(gamma))
+	case cfg.TargetK > 0:
+		return fmt.Sprintf("%s_ml_k%d_actual%d.json", prefix, cfg.TargetK, actualK)
+	default:
+		return fmt.Sprintf("%s_ml_alpha_%s_beta_%s.json", prefix, formatParam(cfg.Alpha), formatParam(cfg.Beta))
+	}
+}
+
+// formatParam печатает параметр с одним знаком, если этого достаточно (0.5 -> "0.5", 0.01 -> "0.01")
+func formatParam(x float64) string {
+	if math.Abs(x*10-math.Round(x*10)) < 1e-9 {
+		return fmt.Sprintf("%.1f", x)
+	}
+	return fmt.Sprintf("%g", x)
+}
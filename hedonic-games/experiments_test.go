@@ -0,0 +1,88 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+// seededConfigs - конфигурации всех алгоритмов, использующих ГСЧ
+func seededConfigs() map[string]RunConfig {
+	configs := make(map[string]RunConfig)
+	base := DefaultRunConfig()
+	base.MaxIterations = 50
+
+	hedonic := base
+	configs["hedonic/potential"] = hedonic
+
+	utility := base
+	utility.Dynamics, utility.Scheduler = "utility", string(SchedulerRandomOrder)
+	configs["hedonic/utility-random"] = utility
+
+	ml := base
+	ml.Algorithm, ml.Beta, ml.TargetK = "ml", 2, 2
+	configs["ml"] = ml
+
+	for _, name := range []string{"louvain", "leiden"} {
+		cfg := base
+		cfg.Algorithm = name
+		configs[name] = cfg
+	}
+	return configs
+}
+
+// TestRunExperimentSeed - одно зерно даёт одно и то же разбиение, зерно 0
+// заменяется фактическим, записанным в результат, и повтор с ним воспроизводит запуск
+func TestRunExperimentSeed(t *testing.T) {
+	g := LoadKarateClub()
+	for name, cfg := range seededConfigs() {
+		t.Run(name, func(t *testing.T) {
+			run := func(seed int64) (map[int]int, ExperimentResult) {
+				t.Helper()
+				cfg.Seed = seed
+				partition, result, err := RunExperiment(g, cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return partition.ToMap(), result
+			}
+
+			first, result := run(7)
+			if result.Seed != 7 {
+				t.Errorf("Seed = %d, want 7", result.Seed)
+			}
+			second, again := run(7)
+			if !maps.Equal(first, second) {
+				t.Error("два запуска с зерном 7 дали разные разбиения")
+			}
+			if result.Modularity != again.Modularity || result.Iterations != again.Iterations {
+				t.Errorf("два запуска с зерном 7: Q %g/%g, итераций %d/%d",
+					result.Modularity, again.Modularity, result.Iterations, again.Iterations)
+			}
+
+			random, recorded := run(0)
+			if recorded.Seed == 0 {
+				t.Fatal("зерно 0 не заменено фактическим")
+			}
+			replay, _ := run(recorded.Seed)
+			if !maps.Equal(random, replay) {
+				t.Errorf("повтор с записанным зерном %d дал другое разбиение", recorded.Seed)
+			}
+		})
+	}
+}
+
+// TestResolveSeed - ненулевое зерно не меняется, нулевое заменяется ненулевым
+func TestResolveSeed(t *testing.T) {
+	if got := ResolveSeed(42); got != 42 {
+		t.Errorf("ResolveSeed(42) = %d", got)
+	}
+	if got := ResolveSeed(0); got == 0 {
+		t.Error("ResolveSeed(0) = 0")
+	}
+	a, b := NewRand(3), NewRand(3)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Int63(), b.Int63(); x != y {
+			t.Fatalf("NewRand(3): %d-е число %d и %d", i, x, y)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,7 @@ type ExperimentResult struct {
 	TestName      string
 	Algorithm     string
 	Parameter     float64
+	Seed          int64 // зерно ГСЧ, с которым получен результат
 	NumNodes      int
 	NumEdges      int
 	Communities   int
@@ -22,6 +25,14 @@ type ExperimentResult struct {
 	ConvergedAt   int
 	ExecutionTime float64
 	Timestamp     string
+	Trace         []MoveRecord          // ходы динамики улучшающих ответов (в CSV не пишется)
+	Violations    []ConstraintViolation // нарушения ограничений (в CSV - их число)
+	Accuracy      *ComparisonScores     // согласие с эталоном (nil - эталон не задан)
+	Temperatures  []TemperatureStats    // статистика отжига ML (в CSV не пишется)
+
+	LikelihoodHistory []float64    // профильное правдоподобие ML по итерациям (в CSV не пишется)
+	AlphaHistory      []float64    // α по итерациям EM (в CSV не пишется)
+	ModelScores       []ModelScore // перебор K при выборе модели (в CSV не пишется)
 }
 
 type PartitionJSON struct {
@@ -33,18 +44,60 @@ type PartitionJSON struct {
 }
 
 type NodeJSON struct {
-	ID        string `json:"id"`
-	Community int    `json:"community"`
+	ID            string `json:"id"`
+	Community     int    `json:"community"`
+	CommunityPath []int  `json:"community_path,omitempty"` // путь в иерархии от крупного к мелкому (см. ExportHierarchyToJSON)
+
+	// Только для перекрывающихся сообществ (см. ExportCoverToJSON):
+	// community - основное сообщество узла
+	Communities []int     `json:"communities,omitempty"` // все сообщества узла по возрастанию
+	Strengths   []float64 `json:"strengths,omitempty"`   // сила членства в каждом из них (сумма 1)
 }
 
 type LinkJSON struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Weight float64 `json:"weight,omitempty"` // только для взвешенных графов
+}
+
+func ExportPartitionToJSON(g *Graph, partition *Partition, idToName map[int]string, filename string) error {
+	return writePartitionJSON(partitionJSON(g, partition, idToName), filename)
+}
+
+// ExportHierarchyToJSON сохраняет дендрограмму: community - номер листа,
+// community_path - номера сообществ от верхнего уровня к листу
+func ExportHierarchyToJSON(g *Graph, h *Hierarchy, idToName map[int]string, filename string) error {
+	leaves := h.Leaves()
+	pj := partitionJSON(g, leaves, idToName)
+	paths := h.Paths()
+	for i, node := range leaves.Nodes() { // узлы в том же порядке, что и в pj.Nodes
+		pj.Nodes[i].CommunityPath = paths[node]
+	}
+	pj.Graph = map[string]interface{}{"hierarchy": string(h.Mode), "depth": h.Depth()}
+	return writePartitionJSON(pj, filename)
+}
+
+// ExportCoverToJSON сохраняет перекрывающиеся сообщества: community - основное
+// сообщество узла, communities и strengths - все его сообщества и силы членства
+func ExportCoverToJSON(g *Graph, cover *Cover, idToName map[int]string, filename string) error {
+	primary := PrimaryPartition(g, cover)
+	pj := partitionJSON(g, primary, idToName)
+	for i, node := range primary.Nodes() { // узлы в том же порядке, что и в pj.Nodes
+		pj.Nodes[i].Communities = cover.Memberships(node)
+		pj.Nodes[i].Strengths = MembershipStrengths(g, cover, node)
+	}
+	pj.Graph = map[string]interface{}{
+		"overlapping": true,
+		"overlaps":    len(cover.OverlappingNodes()),
+	}
+	return writePartitionJSON(pj, filename)
 }
 
-func ExportPartitionToJSON(g *Graph, partition map[int]int, idToName map[int]string, filename string) error {
-	nodes := make([]NodeJSON, 0, len(partition))
-	for nodeID, commID := range partition {
+// partitionJSON - разбиение и рёбра графа в формате node-link
+func partitionJSON(g *Graph, partition *Partition, idToName map[int]string) PartitionJSON {
+	nodes := make([]NodeJSON, 0, partition.Len())
+	for _, nodeID := range partition.Nodes() {
+		commID := partition.Community(nodeID)
 		name := idToName[nodeID]
 		nodes = append(nodes, NodeJSON{
 			ID:        name,
@@ -52,11 +105,22 @@ func ExportPartitionToJSON(g *Graph, partition map[int]int, idToName map[int]str
 		})
 	}
 
+	if g.Directed {
+		return PartitionJSON{
+			Directed:   true,
+			Multigraph: false,
+			Graph:      map[string]interface{}{},
+			Nodes:      nodes,
+			Links:      arcLinksJSON(g, idToName),
+		}
+	}
+
 	links := make([]LinkJSON, 0, g.NumEdges())
 	visited := make(map[[2]int]bool)
+	weighted := g.IsWeighted()
 
-	for u := 0; u < g.NumNodes(); u++ {
-		for v := range g.Edges[u] {
+	for _, u := range g.GetNodeList() { // номера узлов могут идти с пропусками
+		for _, v := range g.GetNeighbors(u) {
 			if u < v {
 				u1, u2 := u, v
 				if u > v {
@@ -64,24 +128,57 @@ func ExportPartitionToJSON(g *Graph, partition map[int]int, idToName map[int]str
 				}
 				key := [2]int{u1, u2}
 				if !visited[key] {
-					links = append(links, LinkJSON{
+					link := LinkJSON{
 						Source: idToName[u],
 						Target: idToName[v],
-					})
+					}
+					if weighted {
+						link.Weight = g.Weight(u, v)
+					}
+					links = append(links, link)
 					visited[key] = true
 				}
 			}
 		}
 	}
 
-	pj := PartitionJSON{
+	return PartitionJSON{
 		Directed:   false,
 		Multigraph: false,
 		Graph:      map[string]interface{}{},
 		Nodes:      nodes,
 		Links:      links,
 	}
+}
+
+// arcLinksJSON - дуги ориентированного графа (вес - только если не все дуги единичные)
+func arcLinksJSON(g *Graph, idToName map[int]string) []LinkJSON {
+	weighted := false
+	for _, u := range g.GetNodeList() {
+		for _, v := range g.OutNeighbors(u) {
+			if g.ArcWeight(u, v) != 1.0 {
+				weighted = true
+			}
+		}
+	}
+
+	links := make([]LinkJSON, 0, g.NumArcs())
+	for _, u := range g.GetNodeList() {
+		for _, v := range g.OutNeighbors(u) {
+			link := LinkJSON{
+				Source: idToName[u],
+				Target: idToName[v],
+			}
+			if weighted {
+				link.Weight = g.ArcWeight(u, v)
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
 
+func writePartitionJSON(pj PartitionJSON, filename string) error {
 	data, err := json.MarshalIndent(pj, "", "  ")
 	if err != nil {
 		return fmt.Errorf("JSON error: %w", err)
@@ -101,7 +198,14 @@ func SaveResultsToCSV(results []ExperimentResult, filename string) error {
 	}
 	defer file.Close()
 
-	writer := csv.NewWriter(file)
+	return writeResultsCSV(file, results)
+}
+
+// writeResultsCSV пишет заголовок и строки результатов в формате SaveResultsToCSV.
+// Колонки исходного формата идут на прежних местах (до Timestamp включительно),
+// новые добавляются после них, чтобы не ломать чтение по номеру колонки
+func writeResultsCSV(w io.Writer, results []ExperimentResult) error {
+	writer := csv.NewWriter(w)
 	defer writer.Flush()
 
 	header := []string{
@@ -117,6 +221,15 @@ func SaveResultsToCSV(results []ExperimentResult, filename string) error {
 		"ConvergedAt",
 		"ExecutionTime",
 		"Timestamp",
+		"Seed",
+		"NMI",
+		"ARI",
+		"VI",
+		"F1",
+		"Purity",
+		"PairPrecision",
+		"PairRecall",
+		"Violations",
 	}
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("header error: %w", err)
@@ -136,6 +249,268 @@ func SaveResultsToCSV(results []ExperimentResult, filename string) error {
 			fmt.Sprintf("%d", r.ConvergedAt),
 			fmt.Sprintf("%.4f", r.ExecutionTime),
 			r.Timestamp,
+			fmt.Sprintf("%d", r.Seed),
+		}
+		row = append(row, r.accuracyColumns()...)
+		row = append(row, fmt.Sprintf("%d", len(r.Violations)))
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// accuracyColumns - колонки согласия с эталоном (пустые, если эталона нет)
+func (r ExperimentResult) accuracyColumns() []string {
+	if r.Accuracy == nil {
+		return make([]string, 7)
+	}
+	a := r.Accuracy
+	values := []float64{a.NMI, a.ARI, a.VI, a.F1, a.Purity, a.PairPrecision, a.PairRecall}
+	columns := make([]string, len(values))
+	for i, v := range values {
+		columns[i] = fmt.Sprintf("%.6f", v)
+	}
+	return columns
+}
+
+// ScoreAgainst сравнивает найденное разбиение с эталоном и запоминает меры
+func (r *ExperimentResult) ScoreAgainst(partition, truth *Partition) {
+	if truth == nil {
+		r.Accuracy = nil
+		return
+	}
+	scores := ComparePartitions(partition, truth)
+	r.Accuracy = &scores
+}
+
+// SaveMoveTraceToCSV сохраняет ходы динамики улучшающих ответов
+func SaveMoveTraceToCSV(trace []MoveRecord, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create error: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"Step", "Round", "Node", "From", "To", "Gain"}); err != nil {
+		return fmt.Errorf("header error: %w", err)
+	}
+
+	for _, m := range trace {
+		row := []string{
+			fmt.Sprintf("%d", m.Step),
+			fmt.Sprintf("%d", m.Round),
+			fmt.Sprintf("%d", m.Node),
+			fmt.Sprintf("%d", m.From),
+			fmt.Sprintf("%d", m.To),
+			fmt.Sprintf("%.6f", m.Gain),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveTemperatureStatsToCSV сохраняет статистику отжига по температурам
+func SaveTemperatureStatsToCSV(stats []TemperatureStats, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create error: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"Pass", "Step", "Beta", "Sweeps", "Proposals", "Accepted", "AcceptanceRate", "MeanObjective", "BestObjective"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("header error: %w", err)
+	}
+
+	for _, t := range stats {
+		row := []string{
+			fmt.Sprintf("%d", t.Pass),
+			fmt.Sprintf("%d", t.Step),
+			fmt.Sprintf("%.6f", t.Beta),
+			fmt.Sprintf("%d", t.Sweeps),
+			fmt.Sprintf("%d", t.Proposals),
+			fmt.Sprintf("%d", t.Accepted),
+			fmt.Sprintf("%.6f", t.AcceptanceRate),
+			fmt.Sprintf("%.6f", t.MeanObjective),
+			fmt.Sprintf("%.6f", t.BestObjective),
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveLikelihoodHistoryToCSV сохраняет правдоподобие ML по итерациям
+// (колонка Alpha заполнена только для EM)
+func SaveLikelihoodHistoryToCSV(likelihood, alpha []float64, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create error: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"Iteration", "LogLikelihood", "Alpha"}); err != nil {
+		return fmt.Errorf("header error: %w", err)
+	}
+
+	for i, ll := range likelihood {
+		row := []string{fmt.Sprintf("%d", i), fmt.Sprintf("%.6f", ll), ""}
+		if i < len(alpha) {
+			row[2] = fmt.Sprintf("%.6f", alpha[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SaveResolutionScanToCSV сохраняет скан по разрешению: по строке на γ
+// (Plateau - номер плато с 1, 0 - точка вне плато)
+func SaveResolutionScanToCSV(points []ScanPoint, plateaus []ResolutionPlateau, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create error: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"Gamma", "Communities", "Quality", "Modularity", "VIPrev", "NMIPrev", "Plateau", "NMI", "ARI"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("header error: %w", err)
+	}
+
+	plateauOf := make([]int, len(points))
+	for i, p := range plateaus {
+		for j := p.Start; j <= p.End; j++ {
+			plateauOf[j] = i + 1
+		}
+	}
+	for i, p := range points {
+		row := []string{
+			fmt.Sprintf("%.6f", p.Gamma),
+			fmt.Sprintf("%d", p.Communities),
+			fmt.Sprintf("%.6f", p.Quality),
+			fmt.Sprintf("%.6f", p.Result.Modularity),
+			fmt.Sprintf("%.6f", p.VIPrev),
+			fmt.Sprintf("%.6f", p.NMIPrev),
+			fmt.Sprintf("%d", plateauOf[i]),
+			"",
+			"",
+		}
+		if a := p.Result.Accuracy; a != nil {
+			row[7] = fmt.Sprintf("%.6f", a.NMI)
+			row[8] = fmt.Sprintf("%.6f", a.ARI)
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// TemporalSnapshotJSON - снимок в файле событий
+type TemporalSnapshotJSON struct {
+	Step        int         `json:"step"`
+	Name        string      `json:"name"`
+	File        string      `json:"file,omitempty"` // разбиение снимка (ExportPartitionToJSON)
+	Nodes       int         `json:"nodes"`
+	Edges       int         `json:"edges"`
+	Communities int         `json:"communities"`
+	Modularity  float64     `json:"modularity"`
+	Changed     []string    `json:"changed,omitempty"` // тёплый старт: узлы, сменившие сообщество
+	Tracks      map[int]int `json:"tracks"`            // сообщество -> динамическое сообщество
+}
+
+// ExportTemporalToJSON сохраняет снимки и события жизненного цикла сообществ;
+// files - файлы разбиений снимков (в порядке снимков, могут быть пустыми)
+func ExportTemporalToJSON(tr *TemporalResult, files []string, filename string) error {
+	snapshots := make([]TemporalSnapshotJSON, len(tr.Snapshots))
+	for i, s := range tr.Snapshots {
+		snapshots[i] = TemporalSnapshotJSON{
+			Step:        i,
+			Name:        s.Name,
+			Nodes:       s.Result.NumNodes,
+			Edges:       s.Result.NumEdges,
+			Communities: s.Partition.NumCommunities(),
+			Modularity:  s.Result.Modularity,
+			Tracks:      s.Tracks,
+		}
+		if i < len(files) {
+			snapshots[i].File = files[i]
+		}
+		for _, node := range s.Changed {
+			snapshots[i].Changed = append(snapshots[i].Changed, s.IDToName[node])
+		}
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Snapshots []TemporalSnapshotJSON `json:"snapshots"`
+		Events    []TemporalEvent        `json:"events"`
+		Tracks    int                    `json:"tracks"`
+	}{snapshots, tr.Events, tr.NumTracks}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("JSON error: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("write error: %w", err)
+	}
+	return nil
+}
+
+// SaveTemporalEventsToCSV сохраняет события жизненного цикла (списки через ";")
+func SaveTemporalEventsToCSV(tr *TemporalResult, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("create error: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	header := []string{"Step", "Snapshot", "Event", "From", "To", "Sizes", "Jaccard", "Track"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("header error: %w", err)
+	}
+
+	join := func(xs []int) string {
+		parts := make([]string, len(xs))
+		for i, x := range xs {
+			parts[i] = fmt.Sprintf("%d", x)
+		}
+		return strings.Join(parts, ";")
+	}
+	for _, e := range tr.Events {
+		row := []string{
+			fmt.Sprintf("%d", e.Step),
+			tr.Snapshots[e.Step].Name,
+			string(e.Kind),
+			join(e.From),
+			join(e.To),
+			join(e.Sizes),
+			fmt.Sprintf("%.6f", e.Jaccard),
+			fmt.Sprintf("%d", e.Track),
 		}
 		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("write error: %w", err)
@@ -150,25 +525,20 @@ func NewExperimentResult(
 	algorithm string,
 	parameter float64,
 	g *Graph,
-	partition map[int]int,
+	partition *Partition,
 	potential float64,
 	modularity float64,
 	iterations int,
 	convergedAt int,
 	executionTime float64,
 ) ExperimentResult {
-	comms := make(map[int][]int)
-	for node, comm := range partition {
-		comms[comm] = append(comms[comm], node)
-	}
-
 	return ExperimentResult{
 		TestName:      testName,
 		Algorithm:     algorithm,
 		Parameter:     parameter,
 		NumNodes:      g.NumNodes(),
 		NumEdges:      g.NumEdges(),
-		Communities:   len(comms),
+		Communities:   partition.NumCommunities(),
 		Potential:     potential,
 		Modularity:    modularity,
 		Iterations:    iterations,
@@ -177,3 +547,57 @@ func NewExperimentResult(
 		Timestamp:     time.Now().Format(time.RFC3339),
 	}
 }
+
+// LoadPartitionFromJSON читает разбиение, сохранённое ExportPartitionToJSON
+// Возвращает соответствие имя узла → номер сообщества
+func LoadPartitionFromJSON(filename string) (map[string]int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	var pj PartitionJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return nil, fmt.Errorf("JSON error: %w", err)
+	}
+
+	communities := make(map[string]int, len(pj.Nodes))
+	for _, node := range pj.Nodes {
+		communities[node.ID] = node.Community
+	}
+	return communities, nil
+}
+
+// LoadCoverFromJSON читает перекрывающиеся сообщества, сохранённые ExportCoverToJSON
+// (узлы без communities - в одном сообществе community, т.е. подходит и ExportPartitionToJSON).
+// Возвращает соответствие имя узла → номера сообществ
+func LoadCoverFromJSON(filename string) (map[string][]int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	var pj PartitionJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return nil, fmt.Errorf("JSON error: %w", err)
+	}
+
+	communities := make(map[string][]int, len(pj.Nodes))
+	for _, node := range pj.Nodes {
+		if len(node.Communities) > 0 {
+			communities[node.ID] = node.Communities
+		} else {
+			communities[node.ID] = []int{node.Community}
+		}
+	}
+	return communities, nil
+}
+
+// StripTimings обнуляет время выполнения и метку времени, чтобы CSV
+// повторного запуска с тем же зерном совпадал побайтно
+func StripTimings(results []ExperimentResult) {
+	for i := range results {
+		results[i].ExecutionTime = 0
+		results[i].Timestamp = ""
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestResultsCSVKeepsBaselineColumns - колонки исходного формата (заголовок
+// results/karate_experiments.csv) стоят на прежних местах, новые - после них
+func TestResultsCSVKeepsBaselineColumns(t *testing.T) {
+	file, err := os.Open("results/karate_experiments.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	baseline, err := csv.NewReader(file).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scored := ExperimentResult{TestName: "scored", Seed: 7, Violations: make([]ConstraintViolation, 2)}
+	scored.ScoreAgainst(partitionOf(0, 0, 1, 1), partitionOf(0, 0, 1, 1))
+	var buf bytes.Buffer
+	if err := writeResultsCSV(&buf, []ExperimentResult{{TestName: "plain"}, scored}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := records[0]
+	if !slices.Equal(header[:len(baseline)], baseline) {
+		t.Fatalf("начало заголовка %v, в исходном формате %v", header[:len(baseline)], baseline)
+	}
+	want := []string{"Seed", "NMI", "ARI", "VI", "F1", "Purity", "PairPrecision", "PairRecall", "Violations"}
+	if got := header[len(baseline):]; !slices.Equal(got, want) {
+		t.Errorf("новые колонки %v, ожидались %v", got, want)
+	}
+
+	column := func(row []string, name string) string {
+		return row[slices.Index(header, name)]
+	}
+	scoredRow := records[2]
+	if column(scoredRow, "TestName") != "scored" || column(scoredRow, "Seed") != "7" ||
+		column(scoredRow, "NMI") != "1.000000" || column(scoredRow, "Violations") != "2" {
+		t.Errorf("строка с эталоном и нарушениями: %v", scoredRow)
+	}
+	if column(records[1], "NMI") != "" {
+		t.Errorf("без эталона колонка NMI не пуста: %q", column(records[1], "NMI"))
+	}
+}
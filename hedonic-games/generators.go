@@ -0,0 +1,647 @@
+// generators.go - синтетические графы с известным (заложенным) разбиением
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Все генераторы возвращают граф и заложенное разбиение; узлы нумеруются 0..n-1
+// и присутствуют в графе даже без рёбер. Случайность - только из переданного rng,
+// поэтому одинаковое зерно даёт одинаковый граф.
+
+// ============================================================
+// СТОХАСТИЧЕСКАЯ БЛОЧНАЯ МОДЕЛЬ
+// ============================================================
+
+// GenerateSBM - стохастическая блочная модель: блоки размеров sizes,
+// ребро между узлами блоков a и b появляется с вероятностью probs[a][b]
+func GenerateSBM(sizes []int, probs [][]float64, rng *rand.Rand) (*Graph, *Partition, error) {
+	if len(probs) != len(sizes) {
+		return nil, nil, fmt.Errorf("SBM: матрица вероятностей %dx?, блоков %d", len(probs), len(sizes))
+	}
+	for a, row := range probs {
+		if len(row) != len(sizes) {
+			return nil, nil, fmt.Errorf("SBM: строка %d матрицы вероятностей длины %d, блоков %d", a, len(row), len(sizes))
+		}
+	}
+	// симметрия проверяется после длин всех строк: probs[b] может быть ещё не проверена
+	for a, row := range probs {
+		for b, p := range row {
+			if !(p >= 0 && p <= 1) {
+				return nil, nil, fmt.Errorf("SBM: вероятность p[%d][%d] = %g вне [0, 1]", a, b, p)
+			}
+			if probs[b][a] != p {
+				return nil, nil, fmt.Errorf("SBM: матрица вероятностей несимметрична (p[%d][%d] != p[%d][%d])", a, b, b, a)
+			}
+		}
+	}
+
+	start := make([]int, len(sizes)+1)
+	for a, size := range sizes {
+		if size <= 0 {
+			return nil, nil, fmt.Errorf("SBM: размер блока %d должен быть положительным", a)
+		}
+		start[a+1] = start[a] + size
+	}
+	n := start[len(sizes)]
+
+	g := NewGraph()
+	truth := NewPartition(n)
+	for a := range sizes {
+		for u := start[a]; u < start[a+1]; u++ {
+			g.AddNode(u)
+			truth.Set(u, a)
+		}
+	}
+
+	for a := range sizes {
+		for b := a; b < len(sizes); b++ {
+			for u := start[a]; u < start[a+1]; u++ {
+				from := start[b]
+				if a == b {
+					from = u + 1
+				}
+				bernoulliRange(from, start[b+1], probs[a][b], rng, func(v int) {
+					g.AddEdge(u, v)
+				})
+			}
+		}
+	}
+	return g, truth, nil
+}
+
+// GeneratePlantedPartition - numGroups групп по groupSize узлов,
+// внутри группы ребро с вероятностью pIn, между группами - pOut
+func GeneratePlantedPartition(numGroups, groupSize int, pIn, pOut float64, rng *rand.Rand) (*Graph, *Partition, error) {
+	if numGroups <= 0 || groupSize <= 0 {
+		return nil, nil, fmt.Errorf("planted: нужны положительные число и размер групп")
+	}
+	sizes := make([]int, numGroups)
+	probs := make([][]float64, numGroups)
+	for a := range sizes {
+		sizes[a] = groupSize
+		probs[a] = make([]float64, numGroups)
+		for b := range probs[a] {
+			probs[a][b] = pOut
+		}
+		probs[a][a] = pIn
+	}
+	return GenerateSBM(sizes, probs, rng)
+}
+
+// GenerateErdosRenyi - случайный граф G(n, p); эталон - одно сообщество
+// (структуры нет, граф полезен как нулевая модель)
+func GenerateErdosRenyi(n int, p float64, rng *rand.Rand) (*Graph, *Partition, error) {
+	if n <= 0 {
+		return nil, nil, fmt.Errorf("er: число узлов должно быть положительным")
+	}
+	return GenerateSBM([]int{n}, [][]float64{{p}}, rng)
+}
+
+// bernoulliRange вызывает visit для каждого v из [from, to) с вероятностью p.
+// Пропуски между успехами - геометрические, так что разреженный граф строится
+// за время, пропорциональное числу рёбер, а не пар
+func bernoulliRange(from, to int, p float64, rng *rand.Rand, visit func(v int)) {
+	if p <= 0 || from >= to {
+		return
+	}
+	if p >= 1 {
+		for v := from; v < to; v++ {
+			visit(v)
+		}
+		return
+	}
+	logq := math.Log1p(-p)
+	v := from - 1
+	for {
+		skip := math.Floor(math.Log(1-rng.Float64()) / logq)
+		if skip >= float64(to-v) {
+			return
+		}
+		v += 1 + int(skip)
+		if v >= to {
+			return
+		}
+		visit(v)
+	}
+}
+
+// ============================================================
+// ОСЛАБЛЕННЫЙ ГРАФ ПЕЩЕРНЫХ ЛЮДЕЙ
+// ============================================================
+
+// GenerateRelaxedCaveman - numCliques клик по cliqueSize узлов, каждое ребро
+// с вероятностью p перебрасывается на случайный узел (как relaxed_caveman_graph в networkx)
+func GenerateRelaxedCaveman(numCliques, cliqueSize int, p float64, rng *rand.Rand) (*Graph, *Partition, error) {
+	if numCliques <= 0 || cliqueSize <= 0 {
+		return nil, nil, fmt.Errorf("relaxed-caveman: нужны положительные число и размер клик")
+	}
+	if p < 0 || p > 1 {
+		return nil, nil, fmt.Errorf("relaxed-caveman: вероятность %g вне [0, 1]", p)
+	}
+	n := numCliques * cliqueSize
+
+	g := NewGraph()
+	truth := CavemanCliques(numCliques, cliqueSize)
+	var edges [][2]int
+	for u := 0; u < n; u++ {
+		g.AddNode(u)
+		for v := u + 1; v < (u/cliqueSize+1)*cliqueSize; v++ {
+			g.AddEdge(u, v)
+			edges = append(edges, [2]int{u, v})
+		}
+	}
+
+	for _, e := range edges {
+		if rng.Float64() >= p {
+			continue
+		}
+		u, x := e[0], rng.Intn(n)
+		if x == u || g.HasEdge(u, x) {
+			continue
+		}
+		g.RemoveEdge(u, e[1])
+		g.AddEdge(u, x)
+	}
+	return g, truth, nil
+}
+
+// ============================================================
+// LFR
+// ============================================================
+
+// LFRParams - параметры теста Lancichinetti-Fortunato-Radicchi
+type LFRParams struct {
+	N            int     // число узлов
+	AvgDegree    float64 // средняя степень
+	MaxDegree    int     // наибольшая степень
+	Tau1         float64 // показатель степенного закона степеней (обычно 2..3)
+	Tau2         float64 // показатель степенного закона размеров сообществ (обычно 1..2)
+	Mu           float64 // доля рёбер узла, ведущих из его сообщества
+	MinCommunity int     // наименьший размер сообщества (0 - как наименьшая степень)
+	MaxCommunity int     // наибольший размер сообщества (0 - как MaxDegree)
+}
+
+// DefaultLFRParams - параметры классического теста LFR на 1000 узлов
+func DefaultLFRParams() LFRParams {
+	return LFRParams{
+		N:            1000,
+		AvgDegree:    15,
+		MaxDegree:    50,
+		Tau1:         2,
+		Tau2:         1,
+		Mu:           0.3,
+		MinCommunity: 20,
+		MaxCommunity: 100,
+	}
+}
+
+// GenerateLFR строит граф LFR: степени и размеры сообществ по степенным законам,
+// у каждого узла доля (1-μ) рёбер внутри своего сообщества.
+// Рёбра проводятся конфигурационной моделью отдельно для внутренних и внешних
+// концов; петли, кратные рёбра и внешние концы внутри сообщества отбрасываются,
+// поэтому реальные степени и μ немного отличаются от заданных (см. MixingParameter)
+func GenerateLFR(params LFRParams, rng *rand.Rand) (*Graph, *Partition, error) {
+	if err := params.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	minDegree := lfrMinDegree(params.AvgDegree, params.MaxDegree, params.Tau1)
+	if params.MinCommunity == 0 {
+		params.MinCommunity = minDegree
+	}
+	if params.MaxCommunity == 0 {
+		params.MaxCommunity = params.MaxDegree
+	}
+	if params.MinCommunity > params.MaxCommunity {
+		return nil, nil, fmt.Errorf("LFR: минимальный размер сообщества %d больше максимального %d",
+			params.MinCommunity, params.MaxCommunity)
+	}
+
+	degrees := make([]int, params.N)
+	degreeDist := newPowerLaw(minDegree, params.MaxDegree, params.Tau1)
+	for u := range degrees {
+		degrees[u] = degreeDist.sample(rng)
+	}
+
+	sizes, err := lfrCommunitySizes(params, rng)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	internal := make([]int, params.N)
+	for u, k := range degrees {
+		internal[u] = int(math.Round((1 - params.Mu) * float64(k)))
+	}
+	truth := lfrAssign(internal, sizes, rng)
+
+	g := NewGraph()
+	for u := 0; u < params.N; u++ {
+		g.AddNode(u)
+	}
+	for _, comm := range truth.Communities() {
+		var stubs []int
+		for _, u := range truth.SortedMembers(comm) {
+			for i := 0; i < internal[u]; i++ {
+				stubs = append(stubs, u)
+			}
+		}
+		wireStubs(g, stubs, rng, func(u, v int) bool { return true })
+	}
+	var external []int
+	for u, k := range degrees {
+		for i := internal[u]; i < k; i++ {
+			external = append(external, u)
+		}
+	}
+	wireStubs(g, external, rng, func(u, v int) bool {
+		return truth.Community(u) != truth.Community(v)
+	})
+
+	return g, truth, nil
+}
+
+func (p LFRParams) validate() error {
+	switch {
+	case p.N <= 0:
+		return fmt.Errorf("LFR: число узлов должно быть положительным")
+	case p.MaxDegree <= 0 || p.MaxDegree >= p.N:
+		return fmt.Errorf("LFR: наибольшая степень %d должна быть в (0, %d)", p.MaxDegree, p.N)
+	case p.AvgDegree < 1 || p.AvgDegree > float64(p.MaxDegree):
+		return fmt.Errorf("LFR: средняя степень %g должна быть в [1, %d]", p.AvgDegree, p.MaxDegree)
+	case p.Mu < 0 || p.Mu > 1:
+		return fmt.Errorf("LFR: μ = %g вне [0, 1]", p.Mu)
+	case p.MaxCommunity > p.N:
+		return fmt.Errorf("LFR: наибольшее сообщество %d больше числа узлов %d", p.MaxCommunity, p.N)
+	}
+	return nil
+}
+
+// powerLaw - дискретное распределение P(k) ∝ k^(-tau) на [lo, hi]
+type powerLaw struct {
+	lo  int
+	cdf []float64
+}
+
+func newPowerLaw(lo, hi int, tau float64) *powerLaw {
+	d := &powerLaw{lo: lo, cdf: make([]float64, hi-lo+1)}
+	total := 0.0
+	for k := lo; k <= hi; k++ {
+		total += math.Pow(float64(k), -tau)
+		d.cdf[k-lo] = total
+	}
+	for i := range d.cdf {
+		d.cdf[i] /= total
+	}
+	return d
+}
+
+func (d *powerLaw) sample(rng *rand.Rand) int {
+	i := sort.SearchFloat64s(d.cdf, rng.Float64())
+	return d.lo + min(i, len(d.cdf)-1)
+}
+
+func (d *powerLaw) mean() float64 {
+	mean, prev := 0.0, 0.0
+	for i, c := range d.cdf {
+		mean += float64(d.lo+i) * (c - prev)
+		prev = c
+	}
+	return mean
+}
+
+// lfrMinDegree подбирает наименьшую степень, при которой среднее ближе всего к заданному
+func lfrMinDegree(avg float64, maxDegree int, tau float64) int {
+	best, bestDiff := 1, math.Inf(1)
+	for lo := 1; lo <= maxDegree; lo++ {
+		diff := math.Abs(newPowerLaw(lo, maxDegree, tau).mean() - avg)
+		if diff < bestDiff {
+			best, bestDiff = lo, diff
+		}
+	}
+	return best
+}
+
+// lfrCommunitySizes - размеры сообществ по степенному закону с суммой ровно N
+func lfrCommunitySizes(params LFRParams, rng *rand.Rand) ([]int, error) {
+	dist := newPowerLaw(params.MinCommunity, params.MaxCommunity, params.Tau2)
+	var sizes []int
+	total := 0
+	for total < params.N {
+		size := dist.sample(rng)
+		sizes = append(sizes, size)
+		total += size
+	}
+
+	// лишние узлы снимаются с последнего сообщества; если оно становится
+	// меньше минимума, его узлы раздаются сообществам, где есть место
+	last := len(sizes) - 1
+	sizes[last] -= total - params.N
+	if sizes[last] < params.MinCommunity && last > 0 {
+		rest := sizes[last]
+		sizes = sizes[:last]
+		for i := 0; rest > 0; i = (i + 1) % len(sizes) {
+			if sizes[i] < params.MaxCommunity {
+				sizes[i]++
+				rest--
+			} else if !slices.ContainsFunc(sizes, func(s int) bool { return s < params.MaxCommunity }) {
+				return nil, fmt.Errorf("LFR: %d узлов не помещаются в сообщества размером до %d",
+					params.N, params.MaxCommunity)
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// lfrAssign распределяет узлы по сообществам заданных размеров так, чтобы
+// внутренняя степень узла была меньше размера сообщества. Узлы с большей
+// внутренней степенью размещаются первыми; если подходящего места нет,
+// внутренняя степень урезается (остаток уходит во внешние рёбра)
+func lfrAssign(internal, sizes []int, rng *rand.Rand) *Partition {
+	order := rng.Perm(len(internal))
+	sort.SliceStable(order, func(i, j int) bool { return internal[order[i]] > internal[order[j]] })
+
+	truth := NewPartition(len(internal))
+	free := slices.Clone(sizes)
+	for _, u := range order {
+		var fits, open []int
+		for comm, size := range sizes {
+			if free[comm] == 0 {
+				continue
+			}
+			open = append(open, comm)
+			if internal[u] < size {
+				fits = append(fits, comm)
+			}
+		}
+		var comm int
+		if len(fits) > 0 {
+			comm = fits[rng.Intn(len(fits))]
+		} else {
+			comm = open[0]
+			for _, c := range open {
+				if sizes[c] > sizes[comm] {
+					comm = c
+				}
+			}
+			internal[u] = sizes[comm] - 1
+		}
+		truth.Set(u, comm)
+		free[comm]--
+	}
+	return truth
+}
+
+// wireStubs соединяет концы рёбер случайными парами (конфигурационная модель).
+// Пары-петли, повторные рёбра и пары, не прошедшие allowed, перемешиваются
+// и пробуются снова несколько раз, оставшиеся концы отбрасываются
+func wireStubs(g *Graph, stubs []int, rng *rand.Rand, allowed func(u, v int) bool) {
+	const attempts = 10
+	for attempt := 0; attempt < attempts && len(stubs) > 1; attempt++ {
+		rng.Shuffle(len(stubs), func(i, j int) { stubs[i], stubs[j] = stubs[j], stubs[i] })
+		var rejected []int
+		for i := 0; i+1 < len(stubs); i += 2 {
+			u, v := stubs[i], stubs[i+1]
+			if u == v || g.HasEdge(u, v) || !allowed(u, v) {
+				rejected = append(rejected, u, v)
+				continue
+			}
+			g.AddEdge(u, v)
+		}
+		stubs = rejected
+	}
+}
+
+// MixingParameter - доля веса рёбер между разными сообществами разбиения (μ)
+func MixingParameter(g *Graph, partition *Partition) float64 {
+	between, total := 0.0, 0.0
+	for _, u := range g.GetNodeList() {
+		for _, v := range g.GetNeighbors(u) {
+			w := g.Weight(u, v)
+			if partition.Community(u) != partition.Community(v) {
+				between += w
+			}
+			total += w
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return between / total
+}
+
+// ============================================================
+// ОПИСАНИЕ ГЕНЕРАТОРА СТРОКОЙ
+// ============================================================
+
+// generatorModels - модели, которые можно задать как -graph <модель>[:ключ=значение,...]
+var generatorModels = []string{"sbm", "planted", "lfr", "relaxed-caveman", "er"}
+
+// isGeneratorSpec - описывает ли строка синтетический граф
+func isGeneratorSpec(spec string) bool {
+	model, _, _ := strings.Cut(spec, ":")
+	return slices.Contains(generatorModels, model)
+}
+
+// GenerateFromSpec строит граф по описанию вида "lfr:n=500,mu=0.4,seed=7".
+// Ключи (в скобках - значения по умолчанию), seed (1) - у всех моделей:
+//
+//	sbm:             sizes (32/32/32/32, через /), pin (0.3), pout (0.02)
+//	                 или probs (строки через |, значения через /)
+//	planted:         groups (4), size (32), pin (0.3), pout (0.02)
+//	lfr:             n, k, maxk, mu, t1, t2, minc, maxc (как DefaultLFRParams)
+//	relaxed-caveman: cliques (6), size (5), p (0.1)
+//	er:              n (100), p (0.05)
+func GenerateFromSpec(spec string) (*Graph, *Partition, error) {
+	model, rest, _ := strings.Cut(spec, ":")
+	opts, err := parseGeneratorOptions(rest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", model, err)
+	}
+	rng := NewRand(int64(opts.int("seed", 1)))
+
+	var g *Graph
+	var truth *Partition
+	switch model {
+	case "sbm":
+		sizes := opts.ints("sizes", []int{32, 32, 32, 32})
+		probs := opts.matrix("probs")
+		if probs == nil {
+			pIn, pOut := opts.float("pin", 0.3), opts.float("pout", 0.02)
+			probs = make([][]float64, len(sizes))
+			for a := range probs {
+				probs[a] = make([]float64, len(sizes))
+				for b := range probs[a] {
+					probs[a][b] = pOut
+				}
+				probs[a][a] = pIn
+			}
+		}
+		if opts.err == nil {
+			g, truth, err = GenerateSBM(sizes, probs, rng)
+		}
+	case "planted":
+		groups, size := opts.int("groups", 4), opts.int("size", 32)
+		pIn, pOut := opts.float("pin", 0.3), opts.float("pout", 0.02)
+		if opts.err == nil {
+			g, truth, err = GeneratePlantedPartition(groups, size, pIn, pOut, rng)
+		}
+	case "lfr":
+		d := DefaultLFRParams()
+		params := LFRParams{
+			N:            opts.int("n", d.N),
+			AvgDegree:    opts.float("k", d.AvgDegree),
+			MaxDegree:    opts.int("maxk", d.MaxDegree),
+			Tau1:         opts.float("t1", d.Tau1),
+			Tau2:         opts.float("t2", d.Tau2),
+			Mu:           opts.float("mu", d.Mu),
+			MinCommunity: opts.int("minc", d.MinCommunity),
+			MaxCommunity: opts.int("maxc", d.MaxCommunity),
+		}
+		if opts.err == nil {
+			g, truth, err = GenerateLFR(params, rng)
+		}
+	case "relaxed-caveman":
+		cliques, size, p := opts.int("cliques", 6), opts.int("size", 5), opts.float("p", 0.1)
+		if opts.err == nil {
+			g, truth, err = GenerateRelaxedCaveman(cliques, size, p, rng)
+		}
+	case "er":
+		n, p := opts.int("n", 100), opts.float("p", 0.05)
+		if opts.err == nil {
+			g, truth, err = GenerateErdosRenyi(n, p, rng)
+		}
+	default:
+		return nil, nil, fmt.Errorf("неизвестная модель графа %q (есть: %s)", model, strings.Join(generatorModels, ", "))
+	}
+	if opts.err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", model, opts.err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if unused := opts.unused(); len(unused) > 0 {
+		return nil, nil, fmt.Errorf("%s: неизвестные ключи %s", model, strings.Join(unused, ", "))
+	}
+	return g, truth, nil
+}
+
+// generatorName - имя синтетического графа для файлов результатов
+func generatorName(spec string) string {
+	return strings.NewReplacer(":", "_", ",", "_", "=", "", "/", "-", "|", "-").Replace(spec)
+}
+
+// generatorOptions - разобранные ключ=значение; первая ошибка разбора запоминается
+type generatorOptions struct {
+	values map[string]string
+	used   map[string]bool
+	err    error
+}
+
+func parseGeneratorOptions(s string) (*generatorOptions, error) {
+	opts := &generatorOptions{values: make(map[string]string), used: make(map[string]bool)}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("ожидалось ключ=значение, получено %q", part)
+		}
+		opts.values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return opts, nil
+}
+
+func (o *generatorOptions) lookup(key string) (string, bool) {
+	o.used[key] = true
+	v, ok := o.values[key]
+	return v, ok
+}
+
+func (o *generatorOptions) fail(key, value string) {
+	if o.err == nil {
+		o.err = fmt.Errorf("неверное значение %s=%q", key, value)
+	}
+}
+
+func (o *generatorOptions) int(key string, def int) int {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		o.fail(key, v)
+	}
+	return n
+}
+
+func (o *generatorOptions) float(key string, def float64) float64 {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		o.fail(key, v)
+	}
+	return f
+}
+
+func (o *generatorOptions) ints(key string, def []int) []int {
+	v, ok := o.lookup(key)
+	if !ok {
+		return def
+	}
+	var values []int
+	for _, part := range strings.Split(v, "/") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			o.fail(key, v)
+			return nil
+		}
+		values = append(values, n)
+	}
+	return values
+}
+
+func (o *generatorOptions) matrix(key string) [][]float64 {
+	v, ok := o.lookup(key)
+	if !ok {
+		return nil
+	}
+	var rows [][]float64
+	for _, rowText := range strings.Split(v, "|") {
+		var row []float64
+		for _, part := range strings.Split(rowText, "/") {
+			f, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				o.fail(key, v)
+				return nil
+			}
+			row = append(row, f)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// unused - ключи, которые модель не прочитала (скорее всего опечатки)
+func (o *generatorOptions) unused() []string {
+	var keys []string
+	for key := range o.values {
+		if !o.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
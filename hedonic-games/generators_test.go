@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sameGraph - совпадают ли узлы и взвешенные рёбра двух графов
+func sameGraph(a, b *Graph) bool {
+	nodes := a.GetNodeList()
+	if !slices.Equal(nodes, b.GetNodeList()) || a.NumEdges() != b.NumEdges() {
+		return false
+	}
+	for _, u := range nodes {
+		na, nb := slices.Sorted(slices.Values(a.GetNeighbors(u))), slices.Sorted(slices.Values(b.GetNeighbors(u)))
+		if !slices.Equal(na, nb) {
+			return false
+		}
+		for _, v := range na {
+			if a.Weight(u, v) != b.Weight(u, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TestGeneratorsDeterministic - одно зерно даёт тот же граф и эталон, другое - другой граф
+func TestGeneratorsDeterministic(t *testing.T) {
+	specs := []string{
+		"sbm:sizes=20/30,pin=0.3,pout=0.05",
+		"planted:groups=3,size=15",
+		"lfr:n=200,k=8,maxk=20,minc=10,maxc=40",
+		"relaxed-caveman:cliques=5,size=6,p=0.3",
+		"er:n=60,p=0.1",
+	}
+	for _, spec := range specs {
+		t.Run(generatorName(spec), func(t *testing.T) {
+			g1, truth1, err := GenerateFromSpec(spec + ",seed=5")
+			if err != nil {
+				t.Fatal(err)
+			}
+			g2, truth2, err := GenerateFromSpec(spec + ",seed=5")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameGraph(g1, g2) || NMI(truth1, truth2) != 1 {
+				t.Error("одинаковое зерно дало разные графы")
+			}
+			g3, _, err := GenerateFromSpec(spec + ",seed=6")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sameGraph(g1, g3) {
+				t.Error("разные зёрна дали одинаковые графы")
+			}
+			if g1.NumNodes() != truth1.Len() {
+				t.Errorf("в графе %d узлов, в эталоне %d", g1.NumNodes(), truth1.Len())
+			}
+		})
+	}
+}
+
+// TestGeneratorStructure - размеры, число рёбер и смешивание в предельных случаях
+func TestGeneratorStructure(t *testing.T) {
+	tests := []struct {
+		spec      string
+		nodes     int
+		edges     int // -1 - не проверять
+		groups    int
+		mixing    float64
+		groupSize int // 0 - не проверять
+	}{
+		// pin=1, pout=0: непересекающиеся клики
+		{"planted:groups=3,size=10,pin=1,pout=0", 30, 3 * 45, 3, 0, 10},
+		// pin=0, pout=1: полный двудольный граф
+		{"sbm:sizes=4/6,pin=0,pout=1", 10, 24, 2, 1, 0},
+		// без рёбер все узлы всё равно присутствуют
+		{"er:n=25,p=0", 25, 0, 1, 0, 25},
+		{"relaxed-caveman:cliques=4,size=5,p=0", 20, 4 * 10, 4, 0, 5},
+		// перебрасывание сохраняет число рёбер
+		{"relaxed-caveman:cliques=4,size=5,p=0.5", 20, 4 * 10, 4, -1, 5},
+	}
+	for _, tt := range tests {
+		t.Run(generatorName(tt.spec), func(t *testing.T) {
+			g, truth, err := GenerateFromSpec(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.NumNodes() != tt.nodes {
+				t.Errorf("узлов %d, ожидалось %d", g.NumNodes(), tt.nodes)
+			}
+			if tt.edges >= 0 && g.NumEdges() != tt.edges {
+				t.Errorf("рёбер %d, ожидалось %d", g.NumEdges(), tt.edges)
+			}
+			if truth.NumCommunities() != tt.groups {
+				t.Errorf("сообществ %d, ожидалось %d", truth.NumCommunities(), tt.groups)
+			}
+			if tt.mixing >= 0 {
+				if mu := MixingParameter(g, truth); mu != tt.mixing {
+					t.Errorf("μ = %g, ожидалось %g", mu, tt.mixing)
+				}
+			}
+			for _, comm := range truth.Communities() {
+				if tt.groupSize > 0 && truth.Size(comm) != tt.groupSize {
+					t.Errorf("сообщество %d размера %d, ожидалось %d", comm, truth.Size(comm), tt.groupSize)
+				}
+			}
+		})
+	}
+}
+
+// TestErdosRenyiDensity - число рёбер G(n, p) в пределах нескольких стандартных отклонений
+func TestErdosRenyiDensity(t *testing.T) {
+	const n, p = 200, 0.1
+	for seed := int64(1); seed <= 5; seed++ {
+		g, _, err := GenerateErdosRenyi(n, p, NewRand(seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pairs := float64(n * (n - 1) / 2)
+		mean, sd := pairs*p, math.Sqrt(pairs*p*(1-p))
+		if got := float64(g.NumEdges()); math.Abs(got-mean) > 5*sd {
+			t.Errorf("зерно %d: рёбер %g, ожидалось %g ± %g", seed, got, mean, 5*sd)
+		}
+	}
+}
+
+// TestLFRMixing - реальная доля внешних рёбер LFR близка к заданной μ,
+// размеры сообществ лежат в заданных границах
+func TestLFRMixing(t *testing.T) {
+	for _, mu := range []float64{0.1, 0.3, 0.5} {
+		params := LFRParams{N: 500, AvgDegree: 10, MaxDegree: 30, Tau1: 2, Tau2: 1, Mu: mu, MinCommunity: 20, MaxCommunity: 60}
+		g, truth, err := GenerateLFR(params, NewRand(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := MixingParameter(g, truth); math.Abs(got-mu) > 0.05 {
+			t.Errorf("μ = %g: измерено %g", mu, got)
+		}
+		if truth.Len() != params.N || g.NumNodes() != params.N {
+			t.Errorf("μ = %g: узлов %d в графе и %d в эталоне, ожидалось %d", mu, g.NumNodes(), truth.Len(), params.N)
+		}
+		for _, comm := range truth.Communities() {
+			if size := truth.Size(comm); size < params.MinCommunity || size > params.MaxCommunity {
+				t.Errorf("μ = %g: сообщество %d размера %d вне [%d, %d]", mu, comm, size, params.MinCommunity, params.MaxCommunity)
+			}
+		}
+	}
+}
+
+// TestGeneratorSpecErrors - неверные описания генераторов отклоняются с понятной ошибкой
+func TestGeneratorSpecErrors(t *testing.T) {
+	tests := []struct {
+		spec, want string
+	}{
+		{"planted:groups=3,sizes=10", "неизвестные ключи sizes"},
+		{"er:n=ten", `неверное значение n="ten"`},
+		{"er:n", "ожидалось ключ=значение"},
+		{"grid:n=4", "неизвестная модель"},
+		{"sbm:sizes=5/5,probs=0.5/0.1|0.2/0.5", "несимметрична"},
+		{"sbm:sizes=5/5,probs=0.5/0.1|0.1", "строка 1"},
+		{"sbm:sizes=5/5,probs=0.5/1.5|1.5/0.5", "вне [0, 1]"},
+		{"lfr:n=100,maxk=100", "наибольшая степень"},
+		{"relaxed-caveman:p=2", "вне [0, 1]"},
+	}
+	for _, tt := range tests {
+		t.Run(generatorName(tt.spec), func(t *testing.T) {
+			_, _, err := GenerateFromSpec(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ошибка %v, ожидалась содержащая %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -5,29 +5,215 @@ import (
 )
 
 type Graph struct {
-	Nodes map[int]bool
-	Edges map[int]map[int]bool
+	Nodes   map[int]bool
+	Edges   map[int]map[int]bool
+	Weights map[int]map[int]float64 // вес ребра (у невзвешенного графа все веса 1)
+
+	// Ориентированный граф: дуги хранятся отдельно, а Edges/Weights - его
+	// симметризация (вес ребра = сумма весов дуг в обе стороны), на которой
+	// работают неориентированные алгоритмы
+	Directed bool
+	Arcs     map[int]map[int]float64 // u -> v -> вес дуги u→v (только при Directed)
+	InArcs   map[int]map[int]float64 // v -> u -> вес дуги u→v (только при Directed)
 }
 
 func NewGraph() *Graph {
 	return &Graph{
-		Nodes: make(map[int]bool),
-		Edges: make(map[int]map[int]bool),
+		Nodes:   make(map[int]bool),
+		Edges:   make(map[int]map[int]bool),
+		Weights: make(map[int]map[int]float64),
 	}
 }
 
+// NewDirectedGraph создаёт пустой ориентированный граф
+func NewDirectedGraph() *Graph {
+	g := NewGraph()
+	g.Directed = true
+	g.Arcs = make(map[int]map[int]float64)
+	g.InArcs = make(map[int]map[int]float64)
+	return g
+}
+
+// Clone - независимая копия графа
+func (g *Graph) Clone() *Graph {
+	c := NewGraph()
+	if g.Directed {
+		c = NewDirectedGraph()
+	}
+	for _, u := range g.GetNodeList() {
+		c.AddNode(u)
+		if g.Directed {
+			for _, v := range g.OutNeighbors(u) {
+				c.AddArc(u, v, g.ArcWeight(u, v))
+			}
+			continue
+		}
+		for _, v := range g.GetNeighbors(u) {
+			if u <= v {
+				c.AddWeightedEdge(u, v, g.Weight(u, v))
+			}
+		}
+	}
+	return c
+}
+
 func (g *Graph) AddNode(node int) {
 	g.Nodes[node] = true
 	if g.Edges[node] == nil {
 		g.Edges[node] = make(map[int]bool)
 	}
+	if g.Weights == nil {
+		g.Weights = make(map[int]map[int]float64)
+	}
+	if g.Weights[node] == nil {
+		g.Weights[node] = make(map[int]float64)
+	}
 }
 
 func (g *Graph) AddEdge(u, v int) {
+	g.AddWeightedEdge(u, v, 1.0)
+}
+
+// AddWeightedEdge добавляет ребро с весом w (вес существующего ребра перезаписывается).
+// В ориентированном графе - пару дуг u→v и v→u веса w
+func (g *Graph) AddWeightedEdge(u, v int, w float64) {
+	if g.Directed {
+		g.AddArc(u, v, w)
+		g.AddArc(v, u, w)
+		return
+	}
 	g.AddNode(u)
 	g.AddNode(v)
 	g.Edges[u][v] = true
 	g.Edges[v][u] = true
+	g.Weights[u][v] = w
+	g.Weights[v][u] = w
+}
+
+// AddParallelEdge добавляет к ребру (дуге ориентированного графа) ещё одно
+// кратное ребро веса w: у мультиграфа кратность становится весом
+func (g *Graph) AddParallelEdge(u, v int, w float64) {
+	if g.Directed {
+		g.AddArc(u, v, g.ArcWeight(u, v)+w)
+		return
+	}
+	g.AddWeightedEdge(u, v, g.Weight(u, v)+w)
+}
+
+// AddArc задаёт дугу u→v веса w ориентированного графа (вес существующей дуги
+// перезаписывается) и пересчитывает симметричное ребро
+func (g *Graph) AddArc(u, v int, w float64) {
+	g.AddNode(u)
+	g.AddNode(v)
+	if g.Arcs[u] == nil {
+		g.Arcs[u] = make(map[int]float64)
+	}
+	if g.InArcs[v] == nil {
+		g.InArcs[v] = make(map[int]float64)
+	}
+	g.Arcs[u][v] = w
+	g.InArcs[v][u] = w
+	g.symmetrize(u, v)
+}
+
+// RemoveArc удаляет дугу u→v (ребро остаётся, если есть обратная дуга)
+func (g *Graph) RemoveArc(u, v int) {
+	delete(g.Arcs[u], v)
+	delete(g.InArcs[v], u)
+	g.symmetrize(u, v)
+}
+
+// symmetrize пересчитывает ребро {u, v} по дугам: вес - сумма весов дуг в обе стороны
+func (g *Graph) symmetrize(u, v int) {
+	wuv, okUV := g.Arcs[u][v]
+	wvu, okVU := g.Arcs[v][u]
+	if !okUV && !okVU {
+		delete(g.Edges[u], v)
+		delete(g.Edges[v], u)
+		delete(g.Weights[u], v)
+		delete(g.Weights[v], u)
+		return
+	}
+	w := wuv + wvu
+	if u == v {
+		w = wuv // петля - одна дуга
+	}
+	g.Edges[u][v] = true
+	g.Edges[v][u] = true
+	g.Weights[u][v] = w
+	g.Weights[v][u] = w
+}
+
+// RemoveEdge удаляет ребро (узлы остаются в графе), у ориентированного графа - обе дуги
+func (g *Graph) RemoveEdge(u, v int) {
+	if g.Directed {
+		g.RemoveArc(u, v)
+		g.RemoveArc(v, u)
+		return
+	}
+	delete(g.Edges[u], v)
+	delete(g.Edges[v], u)
+	delete(g.Weights[u], v)
+	delete(g.Weights[v], u)
+}
+
+// RemoveNode удаляет узел вместе со всеми его рёбрами (и дугами)
+func (g *Graph) RemoveNode(node int) {
+	for _, v := range g.GetNeighbors(node) {
+		g.RemoveEdge(node, v)
+	}
+	delete(g.Nodes, node)
+	delete(g.Edges, node)
+	delete(g.Weights, node)
+	if g.Directed {
+		delete(g.Arcs, node)
+		delete(g.InArcs, node)
+	}
+}
+
+// Weight возвращает вес ребра (0, если ребра нет)
+func (g *Graph) Weight(u, v int) float64 {
+	if !g.Edges[u][v] {
+		return 0
+	}
+	if w, ok := g.Weights[u][v]; ok {
+		return w
+	}
+	return 1.0
+}
+
+// Degree возвращает взвешенную степень узла (петля учитывается дважды)
+func (g *Graph) Degree(node int) float64 {
+	d := 0.0
+	for _, nghbr := range g.GetNeighbors(node) {
+		w := g.Weight(node, nghbr)
+		if nghbr == node {
+			w *= 2
+		}
+		d += w
+	}
+	return d
+}
+
+// TotalWeight возвращает суммарный вес рёбер (для невзвешенного графа = NumEdges)
+func (g *Graph) TotalWeight() float64 {
+	total := 0.0
+	for _, node := range g.GetNodeList() {
+		total += g.Degree(node)
+	}
+	return total / 2
+}
+
+// IsWeighted сообщает, есть ли в графе рёбра с весом, отличным от 1
+func (g *Graph) IsWeighted() bool {
+	for u := range g.Weights {
+		for _, w := range g.Weights[u] {
+			if w != 1.0 {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (g *Graph) GetNeighbors(node int) []int {
@@ -55,6 +241,63 @@ func (g *Graph) NumEdges() int {
 	return count / 2
 }
 
+// ArcWeight - вес дуги u→v (у неориентированного графа - вес ребра)
+func (g *Graph) ArcWeight(u, v int) float64 {
+	if !g.Directed {
+		return g.Weight(u, v)
+	}
+	return g.Arcs[u][v]
+}
+
+// OutNeighbors - концы исходящих дуг по возрастанию (у неориентированного графа - соседи)
+func (g *Graph) OutNeighbors(node int) []int {
+	if !g.Directed {
+		return g.GetNeighbors(node)
+	}
+	return SortedCommunityIDs(g.Arcs[node])
+}
+
+// InNeighbors - начала входящих дуг по возрастанию (у неориентированного графа - соседи)
+func (g *Graph) InNeighbors(node int) []int {
+	if !g.Directed {
+		return g.GetNeighbors(node)
+	}
+	return SortedCommunityIDs(g.InArcs[node])
+}
+
+// OutDegree - суммарный вес исходящих дуг (у неориентированного графа - Degree)
+func (g *Graph) OutDegree(node int) float64 {
+	if !g.Directed {
+		return g.Degree(node)
+	}
+	d := 0.0
+	for _, v := range g.OutNeighbors(node) {
+		d += g.Arcs[node][v]
+	}
+	return d
+}
+
+// InDegree - суммарный вес входящих дуг (у неориентированного графа - Degree)
+func (g *Graph) InDegree(node int) float64 {
+	if !g.Directed {
+		return g.Degree(node)
+	}
+	d := 0.0
+	for _, u := range g.InNeighbors(node) {
+		d += g.InArcs[node][u]
+	}
+	return d
+}
+
+// NumArcs - число дуг (у неориентированного графа ребро - две дуги, петля - одна)
+func (g *Graph) NumArcs() int {
+	count := 0
+	for _, node := range g.GetNodeList() {
+		count += len(g.OutNeighbors(node))
+	}
+	return count
+}
+
 func (g *Graph) GetNodeList() []int {
 	var nodes []int
 	for node := range g.Nodes {
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestAMteachersFlags - directed и multigraph из JSON: дуги, сложение кратных
+// рёбер и симметризация (вес ребра - сумма дуг в обе стороны)
+func TestAMteachersFlags(t *testing.T) {
+	const edges = `"nodes": [{"id": "a"}, {"id": "b"}, {"id": "c"}],
+	"edges": [{"source": "a", "target": "b"}, {"source": "a", "target": "b", "weight": 2},
+	          {"source": "b", "target": "a"}, {"source": "a", "target": "c"}]`
+	tests := []struct {
+		directed, multigraph bool
+		ab, ac               float64 // веса рёбер a-b и a-c
+		arcAB, arcBA         float64 // веса дуг (только для ориентированного)
+	}{
+		{false, false, 1, 1, 0, 0}, // повторное ребро перезаписывается
+		{false, true, 4, 1, 0, 0},  // кратности складываются
+		{true, false, 3, 1, 2, 1},
+		{true, true, 4, 1, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("directed=%v,multigraph=%v", tt.directed, tt.multigraph), func(t *testing.T) {
+			var teachers AMteachers
+			data := fmt.Sprintf(`{"directed": %v, "multigraph": %v, %s}`, tt.directed, tt.multigraph, edges)
+			if err := json.Unmarshal([]byte(data), &teachers); err != nil {
+				t.Fatal(err)
+			}
+			g, nameToID, _, missing := teachers.toGraph()
+			if len(missing) > 0 || g.Directed != tt.directed {
+				t.Fatalf("missing %v, directed %v", missing, g.Directed)
+			}
+			a, b, c := nameToID["a"], nameToID["b"], nameToID["c"]
+			if g.Weight(a, b) != tt.ab || g.Weight(b, a) != tt.ab || g.Weight(a, c) != tt.ac {
+				t.Errorf("веса рёбер a-b %g, b-a %g, a-c %g; ожидалось %g, %g", g.Weight(a, b), g.Weight(b, a), g.Weight(a, c), tt.ab, tt.ac)
+			}
+			if !tt.directed {
+				return
+			}
+			if g.ArcWeight(a, b) != tt.arcAB || g.ArcWeight(b, a) != tt.arcBA || g.ArcWeight(c, a) != 0 {
+				t.Errorf("дуги a→b %g, b→a %g, c→a %g; ожидалось %g, %g, 0", g.ArcWeight(a, b), g.ArcWeight(b, a), g.ArcWeight(c, a), tt.arcAB, tt.arcBA)
+			}
+			if g.NumArcs() != 3 || g.OutDegree(a) != tt.arcAB+1 || g.InDegree(a) != tt.arcBA {
+				t.Errorf("дуг %d, out(a) = %g, in(a) = %g", g.NumArcs(), g.OutDegree(a), g.InDegree(a))
+			}
+		})
+	}
+}
+
+// TestRemoveArcKeepsReverse - ребро остаётся, пока есть хотя бы одна дуга
+func TestRemoveArcKeepsReverse(t *testing.T) {
+	g := NewDirectedGraph()
+	g.AddArc(0, 1, 2)
+	g.AddArc(1, 0, 3)
+	g.RemoveArc(0, 1)
+	if !g.HasEdge(0, 1) || g.Weight(0, 1) != 3 || g.ArcWeight(0, 1) != 0 {
+		t.Errorf("после удаления 0→1: ребро %v веса %g, дуга %g", g.HasEdge(0, 1), g.Weight(0, 1), g.ArcWeight(0, 1))
+	}
+	g.RemoveArc(1, 0)
+	if g.HasEdge(0, 1) || g.NumEdges() != 0 || g.NumArcs() != 0 {
+		t.Error("после удаления обеих дуг ребро осталось")
+	}
+}
+
+// TestDirectedModularityKnownValue - цикл 0→1→2→0 и дуга 3→4 (m = 4):
+// Q = [4 - (3·3 + 1·1)/4]/4 = 0.375; встречная дуга внутри группы учитывается дважды
+func TestDirectedModularityKnownValue(t *testing.T) {
+	g := NewDirectedGraph()
+	for _, arc := range [][2]int{{0, 1}, {1, 2}, {2, 0}, {3, 4}} {
+		g.AddArc(arc[0], arc[1], 1)
+	}
+	p := partitionOf(0, 0, 0, 1, 1)
+	if q := ComputeModularity(g, p); math.Abs(q-0.375) > 1e-12 {
+		t.Errorf("Q = %g, ожидалось 0.375", q)
+	}
+	// симметризация: 4 ребра, степени групп 6 и 2: Q = [8 - (36 + 4)/8]/8 = 0.375
+	if q := symmetricModularity(g, p, 1); math.Abs(q-0.375) > 1e-12 {
+		t.Errorf("Q симметризации = %g, ожидалось 0.375", q)
+	}
+	// встречные дуги внутри группы: Лейхт–Ньюман учитывает обе
+	g.AddArc(1, 0, 1)
+	want := (5 - (4.0*4+1)/5) / 5
+	if q := ComputeModularity(g, p); math.Abs(q-want) > 1e-12 {
+		t.Errorf("со встречной дугой Q = %g, ожидалось %g", q, want)
+	}
+}
+
+// weightedPath - путь 0-1-2-3 с весами 3, 1 и ребром 2-3 без веса (вес 1), умноженными на scale
+func weightedPath(scale float64) *Graph {
+	g := NewGraph()
+	g.AddWeightedEdge(0, 1, 3*scale)
+	g.AddWeightedEdge(1, 2, scale)
+	if scale == 1 {
+		g.AddEdge(2, 3)
+	} else {
+		g.AddWeightedEdge(2, 3, scale)
+	}
+	return g
+}
+
+// TestWeightedKnownValues - веса рёбер в степенях, потенциалах (7.1), (7.2),
+// модулярности, полезности агента и целевой функции ML на разбиении {0,1},{2,3}:
+// степени 3, 4, 2, 1, m = 5; P71 = (3 - ½α) + (1 - ½α); P72 = (3 - 12/10) + (1 - 2/10);
+// Q = [8 - (7² + 3²)/10]/10; ML = 4 - ½α·(2² + 2²)
+func TestWeightedKnownValues(t *testing.T) {
+	g := weightedPath(1)
+	for node, want := range []float64{3, 4, 2, 1} {
+		if d := g.Degree(node); d != want {
+			t.Errorf("Degree(%d) = %g, ожидалось %g", node, d, want)
+		}
+	}
+	if w := g.TotalWeight(); w != 5 {
+		t.Errorf("TotalWeight = %g, ожидалось 5", w)
+	}
+	if w := g.Weight(3, 2); w != 1 {
+		t.Errorf("вес ребра без веса = %g, ожидалось 1", w)
+	}
+
+	p := partitionOf(0, 0, 2, 2)
+	hg := NewHedonicGame(*g, 0.5)
+	hg.Partition = p.Clone()
+	ml := NewMLModel(g, 0.5, 1)
+
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"P71", hg.ComputePotential_Formula71(), 3},
+		{"P72", hg.ComputePotential_Formula72(), 2.6},
+		{"Q", ComputeModularity(g, p), 0.22},
+		{"u_1 в {0,1}", hg.ComputeUtility_BetterResponse(1, 0), 3},
+		{"u_1 в {2,3}", hg.ComputeUtility_BetterResponse(1, 2), 0.5},
+		{"ML", ml.ComputeObjectiveFunction(p), 2},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-12 {
+			t.Errorf("%s = %g, ожидалось %g", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// модулярность не зависит от масштаба весов
+	if q := ComputeModularity(weightedPath(2.5), p); math.Abs(q-0.22) > 1e-12 {
+		t.Errorf("Q при весах ×2.5 = %g, ожидалось 0.22", q)
+	}
+}
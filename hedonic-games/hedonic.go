@@ -1,73 +1,125 @@
 package main
 
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"time"
+)
+
 type HedonicGame struct {
-	G          Graph
-	Partition  map[int]int
-	Alpha      float64
-	TargetK    int     // желаемое число сообществ (-1 = не важно скока)
-	Beta       float64 // Параметр модулярности
-	Iterations int
+	G           Graph
+	Partition   *Partition
+	Alpha       float64
+	TargetK     int     // желаемое число сообществ (-1 = не важно скока)
+	Beta        float64 // Параметр модулярности
+	Iterations  int
+	Rng         *rand.Rand      // источник случайности (для воспроизводимости задаётся зерном)
+	Trace       []MoveRecord    // ходы последней динамики улучшающих ответов
+	Preference  Preference      // модель предпочтений (nil - друзья/незнакомцы со штрафом Alpha)
+	Constraints *Constraints    // жёсткие ограничения на разбиение (nil - нет)
+	Resolution  float64         // γ в потенциале (7.2) (0 - 1, обычная модулярность)
+	NullModel   NullModel       // нулевая модель потенциала (7.2) ("" - Ньюман–Гирван)
+	Cancel      <-chan struct{} // закрытие прерывает динамику после текущего прохода (nil - нет)
+
+	// Агрегаты по сообществам для инкрементального пересчёта потенциала.
+	// Поддерживаются MoveNode, пересобираются RebuildAggregates.
+	// Размеры и состав сообществ хранит сам Partition.
+	commEdges   map[int]float64 // m(S_k) - суммарный вес рёбер внутри сообщества
+	commDegree  map[int]float64 // сумма взвешенных степеней узлов сообщества
+	totalWeight float64         // m - суммарный вес рёбер графа
+	degree      map[int]float64 // взвешенные степени узлов (Graph.Degree сортирует соседей)
+
+	// Только для ориентированного графа (потенциал (7.2) Лейхта–Ньюмана)
+	commOut   map[int]float64 // сумма исходящих степеней узлов сообщества
+	commIn    map[int]float64 // сумма входящих степеней узлов сообщества
+	arcTotal  float64         // суммарный вес дуг
+	outDegree map[int]float64 // исходящие степени узлов
+	inDegree  map[int]float64 // входящие степени узлов
 }
 
-func NewHedonicGame(g Graph, alpha float64) *HedonicGame {
-	partition := make(map[int]int)
-	for i := 0; i < g.NumNodes(); i++ {
-		partition[i] = i // начальное разбиение - каждый в своем комьюнити в одиночку
+// NewRand создаёт генератор с заданным зерном
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// ResolveSeed возвращает зерно для запуска: 0 заменяется случайным,
+// чтобы его можно было сохранить и повторить запуск
+func ResolveSeed(seed int64) int64 {
+	if seed == 0 {
+		return time.Now().UnixNano()
 	}
+	return seed
+}
+
+func NewHedonicGame(g Graph, alpha float64) *HedonicGame {
+	// начальное разбиение - каждый в своем комьюнити в одиночку
 	return &HedonicGame{
 		G:          g,
-		Partition:  partition,
+		Partition:  singletonPartition(&g),
 		Alpha:      alpha,
 		TargetK:    -1,
 		Iterations: 0,
+		Rng:        NewRand(ResolveSeed(0)),
 	}
 
 }
 
-func NewHedonicGameWithTargetK(g Graph, alpha float64, targetK int) *HedonicGame {
-	partition := initializeRandomPartition(&g, targetK)
-	return &HedonicGame{
-		G:          g,
-		Partition:  partition,
-		Alpha:      alpha,
-		TargetK:    targetK,
-		Iterations: 0,
+// singletonPartition - каждый узел графа в сообществе со своим номером
+// (номера узлов могут идти с пропусками, например после RemoveNode)
+func singletonPartition(g *Graph) *Partition {
+	nodes := g.GetNodeList()
+	if len(nodes) == 0 || nodes[len(nodes)-1] == len(nodes)-1 {
+		return NewSingletonPartition(len(nodes))
 	}
+	p := NewPartition(nodeCapacity(nodes))
+	for _, node := range nodes {
+		p.Set(node, node)
+	}
+	return p
 }
 
-// GetCommunityStructure возвращает структуру коммьюнити
+// NewHedonicGameWithTargetK создаёт игру ровно с targetK сообществами (не больше числа узлов)
+// и случайным начальным разбиением; rng == nil - генератор со случайным зерном
+func NewHedonicGameWithTargetK(g Graph, alpha float64, targetK int, rng *rand.Rand) (*HedonicGame, error) {
+	return NewHedonicGameWithConstraints(g, alpha, &Constraints{ExactK: min(targetK, g.NumNodes())}, rng)
+}
+
+// SetSeed задаёт зерно генератора игры
+func (hg *HedonicGame) SetSeed(seed int64) {
+	hg.Rng = NewRand(seed)
+}
+
+// GetCommunityStructure возвращает структуру коммьюнити (узлы внутри упорядочены)
 func (hg *HedonicGame) GetCommunityStructure() map[int][]int {
-	comms := make(map[int][]int)
-	for node, comm := range hg.Partition {
-		comms[comm] = append(comms[comm], node)
-	}
-	return comms
+	return hg.Partition.Groups()
 }
 
-// GetNumberOfCommunities возвращает количество кластеров
-func (hg *HedonicGame) GetNumberOfCommunities() int {
-	return len(hg.GetCommunityStructure())
+// SortedCommunityIDs возвращает номера сообществ по возрастанию,
+// чтобы суммирование и перебор не зависели от порядка обхода map
+func SortedCommunityIDs[V any](comms map[int]V) []int {
+	return slices.Sorted(maps.Keys(comms))
 }
 
-func NumCommunities(partition map[int]int) int {
-	comms := make(map[int]bool)
-	for _, comm := range partition {
-		comms[comm] = true
-	}
-	return len(comms)
+// GetNumberOfCommunities возвращает количество кластеров
+func (hg *HedonicGame) GetNumberOfCommunities() int {
+	return hg.Partition.NumCommunities()
 }
 
 // ========== Формула (7.1) со стр. 183 ==========
 // P(Π) = Σ_k [m(S_k) - n(S_k)(n(S_k)-1)α/2]
-// где m(S_k) - число ребер в кластере k
+// где m(S_k) - число (суммарный вес) ребер в кластере k
 //      n(S_k) - число узлов в кластере k
+// Ориентированный вариант: P(Π) = Σ_k [a(S_k) - n(S_k)(n(S_k)-1)α], a(S_k) - вес дуг
+// внутри кластера: каждая упорядоченная пара (i, j) даёт A_ij - α, поэтому α -
+// порог плотности дуг. a(S_k) - это m(S_k) симметризованного графа, а штраф удваивается
 
 // ComputePotential_Formula71 вычисляет потенциал по формуле (7.1)
 func (hg *HedonicGame) ComputePotential_Formula71() float64 {
-	communities := hg.GetCommunityStructure()
 	P := 0.0
 
-	for _, nodes := range communities {
+	for _, comm := range hg.Partition.Communities() {
+		nodes := hg.Partition.SortedMembers(comm)
 		m_sk := 0.0 // Количество ребер в кластере
 		n_sk := float64(len(nodes))
 
@@ -76,14 +128,12 @@ func (hg *HedonicGame) ComputePotential_Formula71() float64 {
 			for j := i + 1; j < len(nodes); j++ {
 				u := nodes[i]
 				v := nodes[j]
-				if hg.G.HasEdge(u, v) {
-					m_sk += 1.0
-				}
+				m_sk += hg.G.Weight(u, v)
 			}
 		}
 
 		// P(Π) += m(S_k) - n(S_k)(n(S_k)-1)α/2
-		contribution := m_sk - (n_sk * (n_sk - 1) * hg.Alpha / 2.0)
+		contribution := m_sk - (n_sk * (n_sk - 1) * hg.Alpha / 2.0 * hg.pairFactor())
 		P += contribution
 	}
 
@@ -91,34 +141,52 @@ func (hg *HedonicGame) ComputePotential_Formula71() float64 {
 }
 
 // ========== Формула (7.2) со стр. 184 ==========
-// P(Π) = Σ_k Σ_{i,j ∈ S_k, i≠j} (A_ij - d_i*d_j/(2m))
+// P(Π) = Σ_k Σ_{i,j ∈ S_k, i≠j} (A_ij - γ·d_i*d_j/(2m))
+// Для взвешенного графа A_ij - вес ребра, d_i - сила узла, m - суммарный вес.
+// γ - разрешение Райхардта–Борнхольдта (γ = 1 - модулярность Ньюмана–Гирван,
+// больше γ - мельче сообщества). При NullModel = cpm (модель Поттса с константой)
+// ожидаемый вес пары равен γ: P(Π) = Σ_k [m(S_k) - γ·n(S_k)(n(S_k)-1)/2],
+// то есть формула (7.1) с α = γ
+
+// Ориентированный вариант (Лейхт–Ньюман): P(Π) = Σ_k Σ_{i≠j ∈ S_k} (A_ij - γ·d_i^out*d_j^in/m),
+// m - суммарный вес дуг
 
 // ComputePotential_Formula72 вычисляет потенциал по формуле (7.2) - модулярность
 func (hg *HedonicGame) ComputePotential_Formula72() float64 {
-	communities := hg.GetCommunityStructure()
-	m := float64(hg.G.NumEdges())
+	gamma := hg.resolution()
+	if hg.NullModel == NullModelCPM {
+		return ComputeCPM(&hg.G, hg.Partition, gamma)
+	}
+	if hg.G.Directed {
+		return hg.directedPotential72(gamma)
+	}
+
+	m := hg.G.TotalWeight()
 	if m == 0 {
 		return 0
 	}
 
 	P := 0.0
 
-	for _, nodes := range communities {
+	for _, comm := range hg.Partition.Communities() {
+		nodes := hg.Partition.SortedMembers(comm)
+		degree := make([]float64, len(nodes))
+		for i, u := range nodes {
+			degree[i] = hg.G.Degree(u)
+		}
+
 		for i := 0; i < len(nodes); i++ {
 			for j := i + 1; j < len(nodes); j++ {
 				u := nodes[i]
 				v := nodes[j]
 
-				A_ij := 0.0
-				if hg.G.HasEdge(u, v) {
-					A_ij = 1.0
-				}
+				A_ij := hg.G.Weight(u, v)
 
-				d_u := float64(len(hg.G.Edges[u]))
-				d_v := float64(len(hg.G.Edges[v]))
+				d_u := degree[i]
+				d_v := degree[j]
 
-				// P += A_ij - d_u*d_v/(2m)
-				P += A_ij - (d_u * d_v / (2 * m))
+				// P += A_ij - γ·d_u*d_v/(2m)
+				P += A_ij - gamma*(d_u*d_v/(2*m))
 			}
 		}
 	}
@@ -126,6 +194,53 @@ func (hg *HedonicGame) ComputePotential_Formula72() float64 {
 	return P
 }
 
+// directedPotential72 - потенциал (7.2) Лейхта–Ньюмана, за O(Σ|S_k|² + n)
+func (hg *HedonicGame) directedPotential72(gamma float64) float64 {
+	m := 0.0
+	for _, u := range hg.G.GetNodeList() {
+		m += hg.G.OutDegree(u)
+	}
+	if m == 0 {
+		return 0
+	}
+
+	P := 0.0
+	for _, comm := range hg.Partition.Communities() {
+		nodes := hg.Partition.SortedMembers(comm)
+		out, in, self := 0.0, 0.0, 0.0
+		for i, u := range nodes {
+			dOut, dIn := hg.G.OutDegree(u), hg.G.InDegree(u)
+			out += dOut
+			in += dIn
+			self += dOut * dIn
+			for _, v := range nodes[i+1:] {
+				P += hg.G.Weight(u, v) // A_uv + A_vu
+			}
+		}
+		// Σ_{i≠j} d_i^out*d_j^in = Out·In - Σ_i d_i^out*d_i^in
+		P -= gamma * (out*in - self) / m
+	}
+
+	return P
+}
+
+// pairFactor - сколько пар узлов штрафуется на неупорядоченную пару:
+// 2 в ориентированном графе (пары упорядочены), иначе 1
+func (hg *HedonicGame) pairFactor() float64 {
+	if hg.G.Directed {
+		return 2
+	}
+	return 1
+}
+
+// resolution - γ потенциала (7.2) (0 - 1)
+func (hg *HedonicGame) resolution() float64 {
+	if hg.Resolution == 0 {
+		return 1
+	}
+	return hg.Resolution
+}
+
 // ComputePotentialCurrent вычисляет текущий потенциал
 func (hg *HedonicGame) ComputePotentialCurrent(useModularity bool) float64 {
 	if useModularity {
@@ -135,14 +250,34 @@ func (hg *HedonicGame) ComputePotentialCurrent(useModularity bool) float64 {
 }
 
 // ComputeUtility_BetterResponse вычисляет полезность для динамики наилучших ответов (стр. 178)
+// u_i(S) = (друзья в S) - α·(незнакомцы в S), где S - коалиция community вместе с node,
+// друзья - соседи узла (с весами рёбер), незнакомцы - остальные члены S кроме самого узла
 func (hg *HedonicGame) ComputeUtility_BetterResponse(node, community int) float64 {
+	members := []int{node}
+	for _, other := range hg.Partition.SortedMembers(community) {
+		if other != node {
+			members = append(members, other)
+		}
+	}
+	return hg.coalitionUtility(node, members)
+}
+
+// coalitionUtility - полезность узла в произвольной коалиции members (node входит в members)
+func (hg *HedonicGame) coalitionUtility(node int, members []int) float64 {
+	if hg.Preference != nil {
+		return hg.Preference.Value(&hg.G, node, members)
+	}
+
 	friends := 0.0
 	strangers := 0.0
 
-	for neighbor := range hg.G.Edges[node] {
-		if hg.Partition[neighbor] == community {
-			friends += 1.0
-		} else if hg.Partition[neighbor] != community {
+	for _, other := range members {
+		if other == node {
+			continue
+		}
+		if hg.G.HasEdge(node, other) {
+			friends += hg.G.Weight(node, other)
+		} else {
 			strangers += 1.0
 		}
 	}
@@ -150,119 +285,316 @@ func (hg *HedonicGame) ComputeUtility_BetterResponse(node, community int) float6
 	return friends - hg.Alpha*strangers
 }
 
-// FindNashStablePartition_WithPotential находит Нэш-стабильное разбиение
-// Если TargetK > 0, пытается достичь примерно K сообществ
-func (hg *HedonicGame) FindNashStablePartition_WithPotential(maxIterations int, useModularity bool) map[int]int {
-	for iter := 0; iter < maxIterations; iter++ {
-		changed := false
-		nodes := hg.G.GetNodeList()
+// ========== Агрегаты и приращения потенциала ==========
+// При перемещении узла v из A в B меняются только слагаемые сообществ A и B,
+// поэтому приращение потенциала считается за O(deg(v)), без полного пересчёта.
+
+// RebuildAggregates пересобирает агрегаты сообществ по текущему Partition.
+// Нужно вызывать после прямого изменения hg.Partition.
+func (hg *HedonicGame) RebuildAggregates() {
+	hg.commEdges = make(map[int]float64, hg.Partition.NumCommunities())
+	hg.commDegree = make(map[int]float64, hg.Partition.NumCommunities())
+	hg.totalWeight = hg.G.TotalWeight()
+	hg.degree = make(map[int]float64, hg.G.NumNodes())
+	if hg.G.Directed {
+		hg.commOut = make(map[int]float64, hg.Partition.NumCommunities())
+		hg.commIn = make(map[int]float64, hg.Partition.NumCommunities())
+		hg.arcTotal = 0
+		hg.outDegree = make(map[int]float64, hg.G.NumNodes())
+		hg.inDegree = make(map[int]float64, hg.G.NumNodes())
+	}
 
-		for _, node := range nodes {
-			oldComm := hg.Partition[node]
-			bestComm := oldComm
-			bestPotential := hg.ComputePotentialCurrent(useModularity)
-
-			// Получаем все соседние коммьюнити
-			neighborComms := make(map[int]bool)
-			for neighbor := range hg.G.Edges[node] {
-				neighborComms[hg.Partition[neighbor]] = true
+	for _, node := range hg.Partition.Nodes() {
+		comm := hg.Partition.Community(node)
+		hg.degree[node] = hg.G.Degree(node)
+		hg.commDegree[comm] += hg.degree[node]
+		if hg.G.Directed {
+			out, in := hg.G.OutDegree(node), hg.G.InDegree(node)
+			hg.outDegree[node], hg.inDegree[node] = out, in
+			hg.commOut[comm] += out
+			hg.commIn[comm] += in
+			hg.arcTotal += out
+		}
+		if _, ok := hg.commEdges[comm]; !ok {
+			hg.commEdges[comm] = 0
+		}
+		for _, neighbor := range hg.G.GetNeighbors(node) {
+			if node < neighbor && hg.Partition.Community(neighbor) == comm {
+				hg.commEdges[comm] += hg.G.Weight(node, neighbor)
 			}
+		}
+	}
+}
 
-			// Пробуем каждую соседнюю коммьюнити
-			for comm := range neighborComms {
-				hg.Partition[node] = comm
-				newPotential := hg.ComputePotentialCurrent(useModularity)
+// MoveNode переносит узел в сообщество comm, обновляя агрегаты за O(deg)
+func (hg *HedonicGame) MoveNode(node, comm int) {
+	oldComm := hg.Partition.Community(node)
+	if oldComm == comm {
+		return
+	}
+	links := hg.communityLinks(node)
+	deg := hg.nodeDegree(node)
+
+	hg.Partition.Set(node, comm)
+
+	hg.commEdges[oldComm] -= links[oldComm]
+	hg.commDegree[oldComm] -= deg
+	if hg.G.Directed {
+		out, in := hg.nodeArcDegrees(node)
+		hg.commOut[oldComm] -= out
+		hg.commIn[oldComm] -= in
+		hg.commOut[comm] += out
+		hg.commIn[comm] += in
+	}
+	if hg.Partition.Size(oldComm) == 0 {
+		delete(hg.commEdges, oldComm)
+		delete(hg.commDegree, oldComm)
+		delete(hg.commOut, oldComm)
+		delete(hg.commIn, oldComm)
+	}
 
-				if newPotential > bestPotential {
-					bestPotential = newPotential
-					bestComm = comm
-				}
-			}
+	hg.commEdges[comm] += links[comm]
+	hg.commDegree[comm] += deg
+}
 
-			// Условие на создание новой коммьюнити
-			currentK := hg.GetNumberOfCommunities()
-			canCreateNew := hg.TargetK < 0 || currentK < hg.TargetK
+// communityLinks возвращает вес рёбер от узла до каждого соседнего сообщества
+func (hg *HedonicGame) communityLinks(node int) map[int]float64 {
+	links := make(map[int]float64)
+	for _, neighbor := range hg.G.GetNeighbors(node) {
+		if neighbor == node {
+			continue
+		}
+		links[hg.Partition.Community(neighbor)] += hg.G.Weight(node, neighbor)
+	}
+	return links
+}
 
-			if canCreateNew {
-				hg.Partition[node] = node
-				newPotential := hg.ComputePotentialCurrent(useModularity)
-				if newPotential > bestPotential {
-					bestComm = node
-				}
-			}
+// communityMembers - состав сообщества (по возрастанию номера)
+func (hg *HedonicGame) communityMembers(comm int) []int {
+	return hg.Partition.SortedMembers(comm)
+}
+
+// nodeDegree - взвешенная степень узла из агрегатов (узел вне агрегатов - по графу)
+func (hg *HedonicGame) nodeDegree(node int) float64 {
+	if d, ok := hg.degree[node]; ok {
+		return d
+	}
+	return hg.G.Degree(node)
+}
+
+// nodeArcDegrees - исходящая и входящая степени узла ориентированного графа из агрегатов
+func (hg *HedonicGame) nodeArcDegrees(node int) (out, in float64) {
+	if out, ok := hg.outDegree[node]; ok {
+		return out, hg.inDegree[node]
+	}
+	return hg.G.OutDegree(node), hg.G.InDegree(node)
+}
+
+// MoveGain_Formula71 - приращение потенциала (7.1) при переходе узла в сообщество target
+// ΔP = [m_v(B) - α·n(B)] - [m_v(A) - α·(n(A)-1)] (в ориентированном графе штраф 2α)
+func (hg *HedonicGame) MoveGain_Formula71(node, target int) float64 {
+	return hg.moveGain71(node, target, hg.communityLinks(node))
+}
+
+// MoveGain_Formula72 - приращение потенциала (7.2) при переходе узла в сообщество target
+// ΔP = [m_v(B) - γ·d_v·D(B)/(2m)] - [m_v(A) - γ·d_v·(D(A)-d_v)/(2m)]
+// (для cpm - [m_v(B) - γ·n(B)] - [m_v(A) - γ·(n(A)-1)], в ориентированном графе
+// штраф 2γ; для Лейхта–Ньюмана ожидаемый вес γ·(d_v^out·In(B) + d_v^in·Out(B))/m)
+func (hg *HedonicGame) MoveGain_Formula72(node, target int) float64 {
+	return hg.moveGain72(node, target, hg.communityLinks(node))
+}
+
+// MoveGain - приращение текущего потенциала при переходе узла в target
+func (hg *HedonicGame) MoveGain(node, target int, useModularity bool) float64 {
+	return hg.moveGain(node, target, hg.communityLinks(node), useModularity)
+}
+
+// Если задана Preference, в режиме (7.1) используется прирост полезности самого агента:
+// для моделей с точным потенциалом он совпадает с приростом потенциала
+func (hg *HedonicGame) moveGain(node, target int, links map[int]float64, useModularity bool) float64 {
+	if useModularity {
+		return hg.moveGain72(node, target, links)
+	}
+	if hg.Preference != nil {
+		return hg.preferenceGain(node, target)
+	}
+	return hg.moveGain71(node, target, links)
+}
+
+// preferenceGain - прирост полезности агента по Preference при переходе в target
+func (hg *HedonicGame) preferenceGain(node, target int) float64 {
+	from := hg.Partition.Community(node)
+	if from == target {
+		return 0
+	}
+	joined := append(hg.communityMembers(target), node)
+	return hg.Preference.Value(&hg.G, node, joined) - hg.Preference.Value(&hg.G, node, hg.communityMembers(from))
+}
+
+func (hg *HedonicGame) moveGain71(node, target int, links map[int]float64) float64 {
+	from := hg.Partition.Community(node)
+	if from == target {
+		return 0
+	}
+	nFrom := float64(hg.Partition.Size(from) - 1)
+	nTo := float64(hg.Partition.Size(target))
+	alpha := hg.Alpha * hg.pairFactor()
+
+	return (links[target] - alpha*nTo) - (links[from] - alpha*nFrom)
+}
+
+func (hg *HedonicGame) moveGain72(node, target int, links map[int]float64) float64 {
+	from := hg.Partition.Community(node)
+	if from == target {
+		return 0
+	}
+	gamma := hg.resolution()
+	if hg.NullModel == NullModelCPM {
+		nFrom := float64(hg.Partition.Size(from) - 1)
+		nTo := float64(hg.Partition.Size(target))
+		gamma *= hg.pairFactor()
+		return (links[target] - gamma*nTo) - (links[from] - gamma*nFrom)
+	}
+	if hg.G.Directed {
+		m := hg.arcTotal
+		if m == 0 {
+			return 0
+		}
+		out, in := hg.nodeArcDegrees(node)
+		expected := func(commOut, commIn float64) float64 {
+			return gamma * (out*commIn + in*commOut) / m
+		}
+		return (links[target] - expected(hg.commOut[target], hg.commIn[target])) -
+			(links[from] - expected(hg.commOut[from]-out, hg.commIn[from]-in))
+	}
+	m := hg.totalWeight
+	if m == 0 {
+		return 0
+	}
+	d := hg.nodeDegree(node)
+	dFrom := hg.commDegree[from] - d
+	dTo := hg.commDegree[target]
 
+	return (links[target] - gamma*d*dTo/(2*m)) - (links[from] - gamma*d*dFrom/(2*m))
+}
+
+// FindNashStablePartition_WithPotential находит Нэш-стабильное разбиение
+// При заданных Constraints ходы ограничены допустимыми (см. findConstrainedPartition);
+// несовместные ограничения возвращаются ошибкой, разбиение не меняется
+func (hg *HedonicGame) FindNashStablePartition_WithPotential(maxIterations int, useModularity bool) (*Partition, error) {
+	hg.RebuildAggregates()
+
+	if ci, err := hg.constraintIndex(); err != nil {
+		return hg.Partition, err
+	} else if ci != nil {
+		return hg.findConstrainedPartition(ci, maxIterations, useModularity), nil
+	}
+
+	for iter := 0; iter < maxIterations && !isCancelled(hg.Cancel); iter++ {
+		changed := false
+		nodes := hg.G.GetNodeList()
+
+		for _, node := range nodes {
 			// Устанавливаем лучшую коммьюнити
-			if bestComm != oldComm {
-				hg.Partition[node] = bestComm
+			if bestComm := hg.bestPotentialResponse(node, useModularity); bestComm != hg.Partition.Community(node) {
+				hg.MoveNode(node, bestComm)
 				changed = true
-			} else {
-				hg.Partition[node] = oldComm
 			}
 		}
 
 		hg.Iterations = iter + 1
 
-		// Если много кластеров, объединяем мелкие
-		if hg.TargetK > 0 && hg.GetNumberOfCommunities() > hg.TargetK*2 {
-			hg.mergeMallCommunities()
-		}
-
 		if !changed {
 			break
 		}
 	}
 
-	return hg.Partition
+	return hg.Partition, nil
 }
 
-// mergeMallCommunities объединяет мелкие кластеры с соседними
-func (hg *HedonicGame) mergeMallCommunities() {
-	comms := hg.GetCommunityStructure()
+// bestPotentialResponse - сообщество с наибольшим приростом потенциала для узла
+// (соседнее, при Preference - любое, или новое; текущее, если улучшения нет)
+func (hg *HedonicGame) bestPotentialResponse(node int, useModularity bool) int {
+	bestComm := hg.Partition.Community(node)
+	bestGain := 0.0
+
+	// Рёбра до соседних коммьюнити
+	links := hg.communityLinks(node)
+
+	// Пробуем каждую соседнюю коммьюнити (при Preference - каждую)
+	for _, comm := range hg.candidateCommunities(links) {
+		gain := hg.moveGain(node, comm, links, useModularity)
+		if gain > bestGain {
+			bestGain = gain
+			bestComm = comm
+		}
+	}
 
-	for commID, nodes := range comms {
-		if len(nodes) <= 1 {
-			// Найдём соседа с другим ID и присоединимся к нему
-			if len(nodes) > 0 {
-				node := nodes[0]
-				for neighbor := range hg.G.Edges[node] {
-					if hg.Partition[neighbor] != commID {
-						hg.Partition[node] = hg.Partition[neighbor]
-						break
-					}
-				}
-			}
+	// Пробуем уйти в новую коммьюнити: номер node может принадлежать
+	// другому сообществу, поэтому берётся свободный
+	if hg.Partition.Size(hg.Partition.Community(node)) > 1 {
+		fresh := hg.freshCommunityID(node)
+		if gain := hg.moveGain(node, fresh, links, useModularity); gain > bestGain {
+			bestComm = fresh
 		}
 	}
+	return bestComm
 }
 
 // IsNashStable проверяет, является ли разбиение Нэш-стабильным
-func (hg *HedonicGame) IsNashStable(useModularity bool) bool {
-	currentPotential := hg.ComputePotentialCurrent(useModularity)
+// (при Constraints - относительно допустимых ходов; несовместные ограничения - ошибка)
+func (hg *HedonicGame) IsNashStable(useModularity bool) (bool, error) {
+	hg.RebuildAggregates()
+
+	// При ограничениях учитываются только допустимые ходы блоков
+	ci, err := hg.constraintIndex()
+	if err != nil {
+		return false, err
+	}
+	if ci != nil {
+		gain := func(block []int, target int) float64 {
+			return hg.blockPotentialGain(block, target, useModularity)
+		}
+		for _, rep := range ci.order {
+			if _, g := hg.bestConstrainedMove(ci, ci.blocks[rep], gain); g > 1e-9 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
 	nodes := hg.G.GetNodeList()
 
 	for _, node := range nodes {
-		oldComm := hg.Partition[node]
+		oldComm := hg.Partition.Community(node)
 
 		// Пробуем переместить в другие коммьюнити
-		neighborComms := make(map[int]bool)
-		for neighbor := range hg.G.Edges[node] {
-			neighborComms[hg.Partition[neighbor]] = true
-		}
+		links := hg.communityLinks(node)
 
-		for comm := range neighborComms {
+		for _, comm := range hg.candidateCommunities(links) {
 			if comm == oldComm {
 				continue
 			}
 
-			hg.Partition[node] = comm
-			newPotential := hg.ComputePotentialCurrent(useModularity)
-			hg.Partition[node] = oldComm
-
-			if newPotential > currentPotential+1e-9 {
-				return false
+			if hg.moveGain(node, comm, links, useModularity) > 1e-9 {
+				return false, nil
 			}
 		}
+
+		// И уйти в одиночку
+		if hg.Partition.Size(oldComm) > 1 && hg.moveGain(node, hg.freshCommunityID(node), links, useModularity) > 1e-9 {
+			return false, nil
+		}
 	}
 
-	return true
+	return true, nil
+}
+
+// candidateCommunities - сообщества, куда узлу имеет смысл переходить: соседние
+// (links), а при Preference - все непустые: для анонимных, дробных и аддитивно
+// сепарабельных предпочтений выгодной может оказаться и коалиция без соседей
+func (hg *HedonicGame) candidateCommunities(links map[int]float64) []int {
+	if hg.Preference != nil {
+		return hg.Partition.Communities()
+	}
+	return SortedCommunityIDs(links)
 }
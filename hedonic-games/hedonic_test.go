@@ -0,0 +1,226 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// weightedKarate - karate с весами 1..3 и петлёй (петли не входят в приращения, но входят в степени)
+func weightedKarate() *Graph {
+	karate := LoadKarateClub()
+	g := NewGraph()
+	for _, u := range karate.GetNodeList() {
+		g.AddNode(u)
+		for _, v := range karate.GetNeighbors(u) {
+			if u < v {
+				g.AddWeightedEdge(u, v, float64(1+(u+v)%3))
+			}
+		}
+	}
+	g.AddWeightedEdge(5, 5, 2)
+	return g
+}
+
+// randomDirectedGraph - ориентированный граф с весами, частью взаимных дуг и петлёй
+func randomDirectedGraph(n, arcs int, rng *rand.Rand) *Graph {
+	g := NewDirectedGraph()
+	for u := 0; u < n; u++ {
+		g.AddNode(u)
+	}
+	for i := 0; i < arcs; i++ {
+		u, v := rng.Intn(n), rng.Intn(n)
+		if u != v {
+			g.AddArc(u, v, float64(1+rng.Intn(3)))
+		}
+	}
+	g.AddArc(3, 3, 1)
+	return g
+}
+
+// partitionOf - разбиение узлов 0..n-1 по списку сообществ
+func partitionOf(comms ...int) *Partition {
+	p := NewPartition(len(comms))
+	for node, comm := range comms {
+		p.Set(node, comm)
+	}
+	return p
+}
+
+// edgeGraph - невзвешенный граф на узлах 0..n-1 с рёбрами edges
+func edgeGraph(n int, edges ...[2]int) *Graph {
+	g := NewGraph()
+	for u := 0; u < n; u++ {
+		g.AddNode(u)
+	}
+	for _, e := range edges {
+		g.AddEdge(e[0], e[1])
+	}
+	return g
+}
+
+// randomGame - игра со случайным разбиением на k сообществ и собранными агрегатами
+func randomGame(g *Graph, alpha float64, k int, rng *rand.Rand) *HedonicGame {
+	hg := NewHedonicGame(*g, alpha)
+	hg.Partition = NewPartition(g.NumNodes())
+	for _, u := range g.GetNodeList() {
+		hg.Partition.Set(u, rng.Intn(k))
+	}
+	hg.RebuildAggregates()
+	return hg
+}
+
+// equilibrate - динамика по потенциалу до равновесия (не более 100 итераций)
+func equilibrate(t *testing.T, hg *HedonicGame, useModularity bool) {
+	t.Helper()
+	if _, err := hg.FindNashStablePartition_WithPotential(100, useModularity); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nashStable - IsNashStable, ошибка ограничений прерывает тест
+func nashStable(t *testing.T, hg *HedonicGame, useModularity bool) bool {
+	t.Helper()
+	stable, err := hg.IsNashStable(useModularity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stable
+}
+
+// aggregatesMatch сравнивает агрегаты игры со свежей пересборкой по тому же разбиению
+func aggregatesMatch(t *testing.T, hg *HedonicGame) {
+	t.Helper()
+	fresh := &HedonicGame{G: hg.G, Partition: hg.Partition.Clone()}
+	fresh.RebuildAggregates()
+
+	near := func(name string, got, want map[int]float64) {
+		t.Helper()
+		for k, w := range want {
+			if math.Abs(got[k]-w) > 1e-9 {
+				t.Errorf("%s[%d] = %g, после пересборки %g", name, k, got[k], w)
+			}
+		}
+		for k, v := range got {
+			if _, ok := want[k]; !ok && math.Abs(v) > 1e-9 {
+				t.Errorf("%s[%d] = %g, после пересборки нет", name, k, v)
+			}
+		}
+	}
+	near("commEdges", hg.commEdges, fresh.commEdges)
+	near("commDegree", hg.commDegree, fresh.commDegree)
+	near("degree", hg.degree, fresh.degree)
+	if math.Abs(hg.totalWeight-fresh.totalWeight) > 1e-9 {
+		t.Errorf("totalWeight = %g, после пересборки %g", hg.totalWeight, fresh.totalWeight)
+	}
+	if hg.G.Directed {
+		near("commOut", hg.commOut, fresh.commOut)
+		near("commIn", hg.commIn, fresh.commIn)
+		near("outDegree", hg.outDegree, fresh.outDegree)
+		near("inDegree", hg.inDegree, fresh.inDegree)
+		if math.Abs(hg.arcTotal-fresh.arcTotal) > 1e-9 {
+			t.Errorf("arcTotal = %g, после пересборки %g", hg.arcTotal, fresh.arcTotal)
+		}
+	}
+}
+
+// TestMoveGainMatchesPotential - приращения moveGain71/72 равны разности полного
+// потенциала до и после MoveNode, агрегаты после ходов совпадают с пересборкой
+func TestMoveGainMatchesPotential(t *testing.T) {
+	tests := []struct {
+		name          string
+		graph         func(rng *rand.Rand) *Graph
+		useModularity bool
+		resolution    float64
+		nullModel     NullModel
+	}{
+		{name: "undirected/7.1", graph: func(*rand.Rand) *Graph { return LoadKarateClub() }},
+		{name: "undirected/7.2", graph: func(*rand.Rand) *Graph { return LoadKarateClub() }, useModularity: true},
+		{name: "weighted/7.1", graph: func(*rand.Rand) *Graph { return weightedKarate() }},
+		{name: "weighted/7.2 γ=1.5", graph: func(*rand.Rand) *Graph { return weightedKarate() }, useModularity: true, resolution: 1.5},
+		{name: "weighted/cpm", graph: func(*rand.Rand) *Graph { return weightedKarate() }, useModularity: true, resolution: 0.2, nullModel: NullModelCPM},
+		{name: "directed/7.1", graph: func(rng *rand.Rand) *Graph { return randomDirectedGraph(30, 120, rng) }},
+		{name: "directed/7.2", graph: func(rng *rand.Rand) *Graph { return randomDirectedGraph(30, 120, rng) }, useModularity: true},
+		{name: "directed/cpm", graph: func(rng *rand.Rand) *Graph { return randomDirectedGraph(30, 120, rng) }, useModularity: true, resolution: 0.3, nullModel: NullModelCPM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := NewRand(1)
+			g := tt.graph(rng)
+			hg := randomGame(g, 0.4, 4, rng)
+			hg.Resolution, hg.NullModel = tt.resolution, tt.nullModel
+
+			nodes := g.GetNodeList()
+			for move := 0; move < 300; move++ {
+				node := nodes[rng.Intn(len(nodes))]
+				target := rng.Intn(4)
+				if move%5 == 0 {
+					target = node // уход в новое сообщество (или в сообщество с тем же номером)
+				}
+
+				before := hg.ComputePotentialCurrent(tt.useModularity)
+				links := hg.communityLinks(node)
+				var gain float64
+				if tt.useModularity {
+					gain = hg.moveGain72(node, target, links)
+				} else {
+					gain = hg.moveGain71(node, target, links)
+				}
+				hg.MoveNode(node, target)
+				after := hg.ComputePotentialCurrent(tt.useModularity)
+
+				if math.Abs(after-before-gain) > 1e-9 {
+					t.Fatalf("ход %d: узел %d в %d: приращение %g, разность потенциалов %g",
+						move, node, target, gain, after-before)
+				}
+			}
+			aggregatesMatch(t, hg)
+		})
+	}
+}
+
+// TestGoAloneUsesFreshCommunity - уход в одиночку идёт в свободное сообщество,
+// даже если номер узла занят другим (сжатые номера после PartitionFromMap),
+// а IsNashStable проверяет и этот ход
+func TestGoAloneUsesFreshCommunity(t *testing.T) {
+	// клика 0,2..8, узел 1 висит на узле 0, отдельная клика 9,10,11
+	clique := []int{0, 2, 3, 4, 5, 6, 7, 8}
+	var edges [][2]int
+	for i, u := range clique {
+		for _, v := range clique[i+1:] {
+			edges = append(edges, [2]int{u, v})
+		}
+	}
+	edges = append(edges, [2]int{0, 1}, [2]int{9, 10}, [2]int{9, 11}, [2]int{10, 11})
+	g := edgeGraph(12, edges...)
+
+	start := map[int]int{9: 1, 10: 1, 11: 1}
+	for u := 0; u <= 8; u++ {
+		start[u] = 0
+	}
+	// узел 1 в чужой клике без рёбер: выгодно уйти в одиночку
+	stranded := map[int]int{1: 1}
+	for u, c := range start {
+		if u != 1 {
+			stranded[u] = c
+		}
+	}
+
+	for _, useModularity := range []bool{false, true} {
+		hg := NewHedonicGame(*g, 0.5)
+		hg.SetSeed(1)
+		hg.Partition = PartitionFromMap(stranded)
+		if nashStable(t, hg, useModularity) {
+			t.Errorf("7.2=%v: IsNashStable = true, хотя узлу 1 выгодно уйти в одиночку", useModularity)
+		}
+
+		hg.Partition = PartitionFromMap(start)
+		equilibrate(t, hg, useModularity)
+		if hg.Partition.Community(1) == hg.Partition.Community(9) {
+			t.Errorf("7.2=%v: узел 1 оказался в клике 9,10,11, с которой у него нет рёбер", useModularity)
+		}
+		if !nashStable(t, hg, useModularity) {
+			t.Errorf("7.2=%v: разбиение после динамики не Нэш-стабильно", useModularity)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+// hierarchy.go - иерархические (вложенные) сообщества: дендрограмма уровней
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HierarchyMode - способ построения иерархии
+type HierarchyMode string
+
+const (
+	// HierarchyAggregate - снизу вверх: сообщества сворачиваются в узлы
+	// (рёбра суммируются, внутренние становятся петлями), и динамика
+	// повторяется на свёрнутом графе с убывающим α (γ), пока сообщества сливаются
+	HierarchyAggregate HierarchyMode = "aggregate"
+	// HierarchyRecursive - сверху вниз: динамика повторяется внутри каждого
+	// сообщества с растущим α (γ для потенциала (7.2), Louvain и Leiden), пока сообщества делятся
+	HierarchyRecursive HierarchyMode = "recursive"
+)
+
+// ParseHierarchyMode разбирает имя способа построения иерархии
+func ParseHierarchyMode(name string) (HierarchyMode, error) {
+	switch mode := HierarchyMode(name); mode {
+	case HierarchyAggregate, HierarchyRecursive:
+		return mode, nil
+	}
+	return "", fmt.Errorf("неизвестный способ построения иерархии %q", name)
+}
+
+// HierarchyOptions - параметры построения иерархии
+type HierarchyOptions struct {
+	Mode      HierarchyMode
+	MaxLevels int     // максимум уровней под корнем (0 - пока сообщества меняются)
+	Growth    float64 // во сколько раз α (γ) растёт к мелкому уровню (0 - 2, 1 - не меняется)
+	MinSize   int     // recursive: сообщества меньше не делятся (0 - 3)
+}
+
+// HierarchyNode - вершина дендрограммы: сообщество и его подсообщества
+type HierarchyNode struct {
+	Level     int     // 0 - корень (весь граф)
+	Members   []int   // узлы исходного графа по возрастанию
+	Parameter float64 // α (γ), с которым найдены дочерние сообщества (0 - нет дочерних)
+	Children  []*HierarchyNode
+}
+
+// Hierarchy - дендрограмма сообществ. Дочерние сообщества упорядочены
+// по наименьшему узлу, поэтому путь узла от корня однозначен
+type Hierarchy struct {
+	Mode HierarchyMode
+	Root *HierarchyNode
+}
+
+// Depth - число уровней под корнем
+func (h *Hierarchy) Depth() int {
+	var depth func(n *HierarchyNode) int
+	depth = func(n *HierarchyNode) int {
+		d := 0
+		for _, child := range n.Children {
+			d = max(d, 1+depth(child))
+		}
+		return d
+	}
+	return depth(h.Root)
+}
+
+// Paths - путь каждого узла от корня: номера дочерних сообществ на каждом уровне
+// (от крупного к мелкому; у ветвей разной глубины пути разной длины)
+func (h *Hierarchy) Paths() map[int][]int {
+	paths := make(map[int][]int, len(h.Root.Members))
+	var walk func(n *HierarchyNode, path []int)
+	walk = func(n *HierarchyNode, path []int) {
+		if len(n.Children) == 0 {
+			for _, node := range n.Members {
+				paths[node] = slices.Clone(path)
+			}
+			return
+		}
+		for i, child := range n.Children {
+			walk(child, append(path, i))
+		}
+	}
+	walk(h.Root, nil)
+	return paths
+}
+
+// Cut - разбиение на уровне level (1 - верхний); ветви короче level берутся листьями
+func (h *Hierarchy) Cut(level int) *Partition {
+	p := NewPartition(nodeCapacity(h.Root.Members))
+	comm := 0
+	var walk func(n *HierarchyNode)
+	walk = func(n *HierarchyNode) {
+		if n.Level == level || len(n.Children) == 0 {
+			for _, node := range n.Members {
+				p.Set(node, comm)
+			}
+			comm++
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(h.Root)
+	return p
+}
+
+// Leaves - разбиение на листья дендрограммы (самый мелкий уровень)
+func (h *Hierarchy) Leaves() *Partition {
+	return h.Cut(h.Depth())
+}
+
+// String - дерево сообществ с размерами, по строке на вершину
+func (h *Hierarchy) String() string {
+	var b strings.Builder
+	var walk func(n *HierarchyNode, path string)
+	walk = func(n *HierarchyNode, path string) {
+		fmt.Fprintf(&b, "%s%s: %d узлов", strings.Repeat("  ", n.Level), path, len(n.Members))
+		if len(n.Children) > 0 {
+			fmt.Fprintf(&b, ", %d подсообществ (параметр %g)", len(n.Children), n.Parameter)
+		}
+		b.WriteByte('\n')
+		for i, child := range n.Children {
+			walk(child, fmt.Sprintf("%s/%d", path, i))
+		}
+	}
+	walk(h.Root, "")
+	return b.String()
+}
+
+// ============================================================
+// ПОСТРОЕНИЕ
+// ============================================================
+
+// BuildHierarchy строит дендрограмму запусками cfg (см. HierarchyMode).
+// cfg.TargetK и ограничения действуют только на первом разбиении;
+// зерно i-го запуска - cfg.Seed + i (cfg.Seed = 0 - случайное)
+func BuildHierarchy(g *Graph, cfg RunConfig, opts HierarchyOptions) (*Hierarchy, error) {
+	cfg.Seed = ResolveSeed(cfg.Seed)
+	if levelsByResolution(cfg) && cfg.Resolution == 0 {
+		cfg.Resolution = 1
+	}
+	switch opts.Mode {
+	case HierarchyAggregate:
+		return buildAggregateHierarchy(g, cfg, opts)
+	case HierarchyRecursive:
+		return buildRecursiveHierarchy(g, cfg, opts)
+	}
+	return nil, fmt.Errorf("неизвестный способ построения иерархии %q", opts.Mode)
+}
+
+// nextLevelConfig - запуск следующего уровня: без K и ограничений, со своим зерном
+func nextLevelConfig(cfg RunConfig, seed int64) RunConfig {
+	cfg.TargetK = -1
+	cfg.Constraints = Constraints{}
+	cfg.Seed = seed
+	return cfg
+}
+
+// levelsByResolution - по уровням меняется γ (потенциал (7.2), Louvain, Leiden), а не α
+func levelsByResolution(cfg RunConfig) bool {
+	return cfg.UseModularity || cfg.Algorithm == "louvain" || cfg.Algorithm == "leiden"
+}
+
+// hierarchyParameter - параметр, который меняется по уровням
+func hierarchyParameter(cfg RunConfig) float64 {
+	if levelsByResolution(cfg) {
+		return cfg.Resolution
+	}
+	return cfg.Alpha
+}
+
+// scaleParameter умножает параметр уровня на factor
+func scaleParameter(cfg RunConfig, factor float64) RunConfig {
+	if levelsByResolution(cfg) {
+		cfg.Resolution *= factor
+	} else {
+		cfg.Alpha *= factor
+	}
+	return cfg
+}
+
+func (opts HierarchyOptions) growth() float64 {
+	if opts.Growth <= 0 {
+		return 2
+	}
+	return opts.Growth
+}
+
+// nodeCapacity - размер разбиения под узлы по возрастанию
+func nodeCapacity(nodes []int) int {
+	if len(nodes) == 0 {
+		return 0
+	}
+	return nodes[len(nodes)-1] + 1
+}
+
+// AggregateGraph сворачивает сообщества разбиения (номера 0..K-1) в узлы:
+// вес ребра - суммарный вес рёбер между сообществами, вес петли - внутри сообщества.
+// Суммарный вес и степени сообществ совпадают с исходным графом.
+// Ориентированный граф сворачивается в ориентированный (дуги складываются)
+func AggregateGraph(g *Graph, partition *Partition) *Graph {
+	agg := NewGraph()
+	if g.Directed {
+		agg = NewDirectedGraph()
+	}
+	for _, comm := range partition.Communities() {
+		agg.AddNode(comm)
+	}
+	if g.Directed {
+		for _, u := range g.GetNodeList() {
+			for _, v := range g.OutNeighbors(u) {
+				agg.AddParallelEdge(partition.Community(u), partition.Community(v), g.ArcWeight(u, v))
+			}
+		}
+		return agg
+	}
+	weights := make(map[[2]int]float64)
+	for _, u := range g.GetNodeList() {
+		for _, v := range g.GetNeighbors(u) {
+			if u > v {
+				continue
+			}
+			cu, cv := partition.Community(u), partition.Community(v)
+			weights[[2]int{min(cu, cv), max(cu, cv)}] += g.Weight(u, v)
+		}
+	}
+	keys := make([][2]int, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	for _, key := range keys {
+		agg.AddWeightedEdge(key[0], key[1], weights[key])
+	}
+	return agg
+}
+
+// maxIdleLevels - сколько раз подряд aggregate уменьшает α (γ) без слияний
+const maxIdleLevels = 8
+
+// buildAggregateHierarchy - снизу вверх, на каждом уровне α (γ) делится на Growth.
+// В потенциале (7.1) свёрнутое сообщество
+// считается одним агентом (α за пару сообществ, а не за пару узлов), поэтому
+// для этого режима естественнее потенциал (7.2): свёртка сохраняет модулярность
+func buildAggregateHierarchy(g *Graph, cfg RunConfig, opts HierarchyOptions) (*Hierarchy, error) {
+	nodes := g.GetNodeList()
+	partition, _, err := RunExperiment(g, cfg)
+	if err != nil {
+		return nil, err
+	}
+	partition.Relabel()
+	levels := []*Partition{partition} // от мелкого к крупному
+	parameters := []float64{hierarchyParameter(cfg)}
+
+	current := AggregateGraph(g, partition)
+	next := cfg
+	for run, idle := 1, 0; (opts.MaxLevels <= 0 || len(levels) < opts.MaxLevels) && current.NumNodes() > 1; run++ {
+		next = scaleParameter(nextLevelConfig(next, cfg.Seed+int64(run)), 1/opts.growth())
+		merged, _, err := RunExperiment(current, next)
+		if err != nil {
+			return nil, err
+		}
+		merged.Relabel()
+		if merged.NumCommunities() == current.NumNodes() {
+			// при этом α (γ) не слилось ничего - пробуем меньшее, но не бесконечно
+			// (компоненты связности не сливаются никогда)
+			if idle++; idle >= maxIdleLevels || opts.growth() == 1 {
+				break
+			}
+			continue
+		}
+		idle = 0
+		prev := levels[len(levels)-1]
+		coarse := NewPartition(nodeCapacity(nodes))
+		for _, node := range nodes {
+			coarse.Set(node, merged.Community(prev.Community(node)))
+		}
+		levels = append(levels, coarse)
+		parameters = append(parameters, hierarchyParameter(next))
+		current = AggregateGraph(current, merged)
+	}
+
+	root := &HierarchyNode{Members: nodes}
+	attachLevels(root, levels, parameters, len(levels)-1)
+	return &Hierarchy{Mode: HierarchyAggregate, Root: root}, nil
+}
+
+// attachLevels подвешивает к вершине сообщества уровня levels[index] внутри неё
+// и рекурсивно более мелкие уровни (parameters[i] - α (γ) уровня i)
+func attachLevels(parent *HierarchyNode, levels []*Partition, parameters []float64, index int) {
+	if index < 0 {
+		return
+	}
+	level := levels[index]
+	groups := make(map[int][]int)
+	for _, node := range parent.Members {
+		comm := level.Community(node)
+		groups[comm] = append(groups[comm], node)
+	}
+	if len(groups) == 1 {
+		attachLevels(parent, levels, parameters, index-1) // уровень не делит сообщество
+		return
+	}
+	for _, members := range sortedGroups(groups) {
+		child := &HierarchyNode{Level: parent.Level + 1, Members: members}
+		attachLevels(child, levels, parameters, index-1)
+		parent.Children = append(parent.Children, child)
+	}
+	parent.Parameter = parameters[index]
+}
+
+// sortedGroups - группы узлов по возрастанию наименьшего узла (узлы в группах по возрастанию)
+func sortedGroups(groups map[int][]int) [][]int {
+	result := make([][]int, 0, len(groups))
+	for _, comm := range SortedCommunityIDs(groups) {
+		members := slices.Clone(groups[comm])
+		slices.Sort(members)
+		result = append(result, members)
+	}
+	slices.SortFunc(result, func(a, b []int) int { return a[0] - b[0] })
+	return result
+}
+
+// inducedSubgraph - подграф на узлах members с номерами 0..len-1 (members[i] -> i)
+func inducedSubgraph(g *Graph, members []int) *Graph {
+	index := make(map[int]int, len(members))
+	sub := NewGraph()
+	if g.Directed {
+		sub = NewDirectedGraph()
+	}
+	for i, node := range members {
+		index[node] = i
+		sub.AddNode(i)
+	}
+	if g.Directed {
+		for i, u := range members {
+			for _, v := range g.OutNeighbors(u) {
+				if j, ok := index[v]; ok {
+					sub.AddArc(i, j, g.ArcWeight(u, v))
+				}
+			}
+		}
+		return sub
+	}
+	for i, u := range members {
+		for _, v := range g.GetNeighbors(u) {
+			if j, ok := index[v]; ok && i <= j {
+				sub.AddWeightedEdge(i, j, g.Weight(u, v))
+			}
+		}
+	}
+	return sub
+}
+
+// buildRecursiveHierarchy - сверху вниз: сообщество делится запуском на своём
+// подграфе с параметром, умноженным на Growth; деление прекращается, если
+// сообщество не распалось, меньше MinSize или достигнута глубина MaxLevels
+func buildRecursiveHierarchy(g *Graph, cfg RunConfig, opts HierarchyOptions) (*Hierarchy, error) {
+	minSize := opts.MinSize
+	if minSize <= 0 {
+		minSize = 3
+	}
+
+	seed, run := cfg.Seed, 0
+	var split func(n *HierarchyNode, sub *Graph, cfg RunConfig) error
+	split = func(n *HierarchyNode, sub *Graph, cfg RunConfig) error {
+		if len(n.Members) < minSize || (opts.MaxLevels > 0 && n.Level >= opts.MaxLevels) {
+			return nil
+		}
+		cfg.Seed = seed + int64(run)
+		run++
+		partition, _, err := RunExperiment(sub, cfg)
+		if err != nil {
+			return err
+		}
+		if k := partition.NumCommunities(); k <= 1 || k == len(n.Members) {
+			return nil // не распалось или распалось на одиночек
+		}
+		groups := make(map[int][]int)
+		for _, i := range partition.Nodes() {
+			comm := partition.Community(i)
+			groups[comm] = append(groups[comm], n.Members[i])
+		}
+		n.Parameter = hierarchyParameter(cfg)
+		next := scaleParameter(nextLevelConfig(cfg, 0), opts.growth())
+		for _, members := range sortedGroups(groups) {
+			child := &HierarchyNode{Level: n.Level + 1, Members: members}
+			n.Children = append(n.Children, child)
+			if err := split(child, inducedSubgraph(g, members), next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	nodes := g.GetNodeList()
+	root := &HierarchyNode{Members: nodes}
+	if err := split(root, inducedSubgraph(g, nodes), cfg); err != nil {
+		return nil, err
+	}
+	return &Hierarchy{Mode: HierarchyRecursive, Root: root}, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+)
+
+// TestHierarchyCutAndPaths - уровни, пути и срезы дендрограммы с ветвями разной глубины
+func TestHierarchyCutAndPaths(t *testing.T) {
+	h := &Hierarchy{Root: &HierarchyNode{Members: []int{0, 1, 2, 3, 4, 5}, Children: []*HierarchyNode{
+		{Level: 1, Members: []int{0, 1, 2, 3}, Children: []*HierarchyNode{
+			{Level: 2, Members: []int{0, 1}},
+			{Level: 2, Members: []int{2, 3}},
+		}},
+		{Level: 1, Members: []int{4, 5}},
+	}}}
+	if h.Depth() != 2 {
+		t.Errorf("Depth = %d, ожидалось 2", h.Depth())
+	}
+	wantPaths := map[int][]int{0: {0, 0}, 1: {0, 0}, 2: {0, 1}, 3: {0, 1}, 4: {1}, 5: {1}}
+	if paths := h.Paths(); !maps.EqualFunc(paths, wantPaths, slices.Equal[[]int]) {
+		t.Errorf("Paths = %v, ожидалось %v", paths, wantPaths)
+	}
+	if top := h.Cut(1).ToMap(); !maps.Equal(top, map[int]int{0: 0, 1: 0, 2: 0, 3: 0, 4: 1, 5: 1}) {
+		t.Errorf("Cut(1) = %v", top)
+	}
+	if leaves := h.Leaves().ToMap(); !maps.Equal(leaves, map[int]int{0: 0, 1: 0, 2: 1, 3: 1, 4: 2, 5: 2}) {
+		t.Errorf("Leaves = %v", leaves)
+	}
+}
+
+// TestAggregateGraphPreservesModularity - свёртка сохраняет суммарный вес, степени
+// сообществ и модулярность (одиночки свёрнутого графа - исходное разбиение)
+func TestAggregateGraphPreservesModularity(t *testing.T) {
+	rng := NewRand(6)
+	graphs := map[string]*Graph{"weighted": weightedKarate(), "directed": randomDirectedGraph(30, 120, rng)}
+	for name, g := range graphs {
+		t.Run(name, func(t *testing.T) {
+			partition := randomGame(g, 0.3, 5, rng).Partition
+			partition.Relabel()
+			agg := AggregateGraph(g, partition)
+			singletons := NewSingletonPartition(agg.NumNodes())
+			if agg.Directed != g.Directed || agg.NumNodes() != partition.NumCommunities() {
+				t.Fatalf("свёрнутый граф: %d узлов (ориентирован %v)", agg.NumNodes(), agg.Directed)
+			}
+			if math.Abs(agg.TotalWeight()-g.TotalWeight()) > 1e-9 {
+				t.Errorf("суммарный вес %g, ожидалось %g", agg.TotalWeight(), g.TotalWeight())
+			}
+			for _, comm := range partition.Communities() {
+				degree := 0.0
+				for _, node := range partition.Members(comm) {
+					degree += g.Degree(node)
+				}
+				if math.Abs(agg.Degree(comm)-degree) > 1e-9 {
+					t.Errorf("степень сообщества %d: %g, ожидалось %g", comm, agg.Degree(comm), degree)
+				}
+			}
+			for _, gamma := range []float64{0.5, 1, 2} {
+				want, got := ComputeModularityResolution(g, partition, gamma), ComputeModularityResolution(agg, singletons, gamma)
+				if math.Abs(got-want) > 1e-9 {
+					t.Errorf("γ = %g: модулярность свёртки %g, исходного разбиения %g", gamma, got, want)
+				}
+			}
+		})
+	}
+}
+
+// checkDendrogram - дочерние сообщества делят родителя без пересечений,
+// упорядочены по наименьшему узлу и лежат на следующем уровне
+func checkDendrogram(t *testing.T, n *HierarchyNode) {
+	t.Helper()
+	if !slices.IsSorted(n.Members) {
+		t.Errorf("узлы сообщества уровня %d не упорядочены", n.Level)
+	}
+	if len(n.Children) == 0 {
+		return
+	}
+	var union []int
+	for i, child := range n.Children {
+		if child.Level != n.Level+1 {
+			t.Errorf("дочернее сообщество на уровне %d под уровнем %d", child.Level, n.Level)
+		}
+		if i > 0 && child.Members[0] <= n.Children[i-1].Members[0] {
+			t.Error("дочерние сообщества не упорядочены по наименьшему узлу")
+		}
+		union = append(union, child.Members...)
+		checkDendrogram(t, child)
+	}
+	slices.Sort(union)
+	if !slices.Equal(union, n.Members) {
+		t.Errorf("дочерние сообщества уровня %d не делят родителя: %v против %v", n.Level+1, union, n.Members)
+	}
+}
+
+// TestBuildHierarchy - вложенные клики: 3 группы по 3 клики из 5 узлов.
+// Оба способа дают корректную дендрограмму, листья не разрезаны верхним уровнем
+func TestBuildHierarchy(t *testing.T) {
+	g := NewGraph()
+	rng := NewRand(1)
+	for u := 0; u < 45; u++ {
+		g.AddNode(u)
+		for v := 0; v < u; v++ {
+			switch {
+			case u/5 == v/5:
+				g.AddEdge(u, v)
+			case u/15 == v/15 && rng.Float64() < 0.3:
+				g.AddEdge(u, v)
+			case rng.Float64() < 0.01:
+				g.AddEdge(u, v)
+			}
+		}
+	}
+	configs := map[string]RunConfig{
+		"louvain":          {Algorithm: "louvain", Seed: 1, TargetK: -1},
+		"hedonic-resolved": {Algorithm: "hedonic", Alpha: 0.3, UseModularity: true, MaxIterations: 200, Seed: 1, TargetK: -1},
+	}
+	for name, cfg := range configs {
+		for _, mode := range []HierarchyMode{HierarchyAggregate, HierarchyRecursive} {
+			t.Run(fmt.Sprintf("%s/%s", name, mode), func(t *testing.T) {
+				h, err := BuildHierarchy(g, cfg, HierarchyOptions{Mode: mode})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if h.Depth() < 1 {
+					t.Fatalf("пустая иерархия:\n%s", h)
+				}
+				checkDendrogram(t, h.Root)
+				top, leaves := h.Cut(1), h.Leaves()
+				if top.Len() != 45 || leaves.Len() != 45 {
+					t.Fatalf("срезы покрывают %d и %d узлов из 45", top.Len(), leaves.Len())
+				}
+				for _, node := range leaves.Nodes() {
+					for _, other := range leaves.Members(leaves.Community(node)) {
+						if top.Community(other) != top.Community(node) {
+							t.Fatalf("лист разрезан верхним уровнем:\n%s", h)
+						}
+					}
+				}
+				if name != "louvain" {
+					return
+				}
+				// Louvain при γ = 1 находит группы: aggregate начинает с них снизу
+				// и дальше только сливает, recursive делит с них сверху
+				groups := leaves
+				if mode == HierarchyRecursive {
+					groups = top
+				}
+				if nmi := NMI(groups, CavemanCliques(3, 15)); nmi < 1-1e-12 {
+					t.Errorf("уровень γ = 1 не совпадает с группами (NMI %g):\n%s", nmi, h)
+				}
+			})
+		}
+	}
+	if _, err := BuildHierarchy(g, configs["louvain"], HierarchyOptions{Mode: "sideways"}); err == nil {
+		t.Error("неизвестный способ построения должен быть отклонён")
+	}
+}
@@ -0,0 +1,199 @@
+// leiden.go - метод Leiden (Traag, Waltman, van Eck, 2019): Louvain с уточнением
+// разбиения перед свёрткой, поэтому сообщества всегда связны
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// moveNodesFast - быстрое локальное перемещение Leiden: очередь узлов,
+// после перехода узла в очередь возвращаются его соседи из других сообществ.
+// maxSweeps ограничивает число извлечений из очереди (maxSweeps·n, 0 - без ограничения).
+// Возвращает, был ли хоть один переход
+func (s *levelState) moveNodesFast(rng *rand.Rand, maxSweeps int) bool {
+	n := s.cg.len()
+	queue := rng.Perm(n)
+	inQueue := make([]bool, n)
+	for i := range inQueue {
+		inQueue[i] = true
+	}
+	moved := false
+	for pops := 0; len(queue) > 0 && (maxSweeps <= 0 || pops < maxSweeps*n); pops++ {
+		node := queue[0]
+		queue = queue[1:]
+		inQueue[node] = false
+		if !s.bestMove(node) {
+			continue
+		}
+		moved = true
+		for _, v := range s.cg.neighbors[node] {
+			if !inQueue[v] && s.comm[v] != s.comm[node] {
+				inQueue[v] = true
+				queue = append(queue, v)
+			}
+		}
+	}
+	return moved
+}
+
+// refine - уточнение разбиения comm (номера 0..k-1): внутри каждого сообщества S
+// узлы начинают одиночками и в случайном порядке сливаются с хорошо связанными
+// с S подгруппами; подгруппа T выбирается с вероятностью ∝ exp(ΔH/θ) среди ΔH ≥ 0.
+// Узел или подгруппа хорошо связаны, если вес их связей с остальной частью S
+// не меньше ожидаемого в нулевой модели. Возвращает уточнённое разбиение
+func (cg *communityGraph) refine(q qualityModel, comm []int, k int, rng *rand.Rand, theta float64) []int {
+	n := cg.len()
+	refined := identity(n)
+	refDegree := make([]float64, n)
+	refSize := make([]int, n)
+	refExt := make([]float64, n) // вес связей подгруппы с остальной частью своего S
+	singleton := make([]bool, n)
+
+	groups := make([][]int, k)
+	groupDegree := make([]float64, k)
+	groupSize := make([]int, k)
+	inner := make([]float64, n) // вес связей узла с остальной частью своего S
+	for i := 0; i < n; i++ {
+		c := comm[i]
+		groups[c] = append(groups[c], i)
+		groupDegree[c] += cg.degree[i]
+		groupSize[c] += cg.size[i]
+		for j, v := range cg.neighbors[i] {
+			if comm[v] == c {
+				inner[i] += cg.weights[i][j]
+			}
+		}
+		refDegree[i] = cg.degree[i]
+		refSize[i] = cg.size[i]
+		refExt[i] = inner[i]
+		singleton[i] = true
+	}
+
+	links := make([]float64, n)
+	linked := make([]bool, n)
+	var touched, candidates []int
+	var weights []float64
+	for c, group := range groups {
+		degS, sizeS := groupDegree[c], groupSize[c]
+		for _, idx := range rng.Perm(len(group)) {
+			v := group[idx]
+			own := refined[v]
+			if !singleton[own] {
+				continue // к узлу уже присоединились
+			}
+			if inner[v] < q.expected(cg.degree[v], cg.size[v], degS-cg.degree[v], sizeS-cg.size[v]) {
+				continue
+			}
+
+			for _, t := range touched {
+				links[t] = 0
+				linked[t] = false
+			}
+			touched = touched[:0]
+			for j, u := range cg.neighbors[v] {
+				if comm[u] != c {
+					continue
+				}
+				t := refined[u]
+				if !linked[t] {
+					linked[t] = true
+					touched = append(touched, t)
+				}
+				links[t] += cg.weights[v][j]
+			}
+
+			candidates, weights = candidates[:0], weights[:0]
+			maxGain := math.Inf(-1)
+			for _, t := range touched {
+				if refExt[t] < q.expected(refDegree[t], refSize[t], degS-refDegree[t], sizeS-refSize[t]) {
+					continue
+				}
+				gain := links[t] - q.expected(cg.degree[v], cg.size[v], refDegree[t], refSize[t])
+				if gain < 0 {
+					continue
+				}
+				candidates = append(candidates, t)
+				weights = append(weights, gain)
+				maxGain = math.Max(maxGain, gain)
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+
+			total := 0.0
+			for i, gain := range weights {
+				weights[i] = math.Exp((gain - maxGain) / theta)
+				total += weights[i]
+			}
+			target := candidates[len(candidates)-1]
+			for i, r := 0, rng.Float64()*total; i < len(candidates); i++ {
+				if r < weights[i] {
+					target = candidates[i]
+					break
+				}
+				r -= weights[i]
+			}
+
+			refined[v] = target
+			refDegree[target] += cg.degree[v]
+			refSize[target] += cg.size[v]
+			refExt[target] += inner[v] - 2*links[target]
+			singleton[target] = false
+			refDegree[own], refSize[own], refExt[own] = 0, 0, 0
+		}
+	}
+	return refined
+}
+
+// Leiden - быстрое локальное перемещение, уточнение и свёртка уточнённых подгрупп;
+// начальное разбиение свёрнутого графа наследуется от неуточнённого.
+// Останавливается, когда каждое сообщество - один узел уровня
+func Leiden(g *Graph, opts MultilevelOptions, rng *rand.Rand) *MultilevelResult {
+	cg, nodes := newCommunityGraph(g)
+	q := newQualityModel(opts, cg.m)
+	theta := opts.Theta
+	if theta <= 0 {
+		theta = 0.01
+	}
+
+	nodeOf := identity(len(nodes)) // исходный узел -> узел текущего уровня
+	comm := identity(cg.len())
+	result := &MultilevelResult{}
+	for level := 0; (opts.MaxLevels <= 0 || level < opts.MaxLevels) && !isCancelled(opts.Cancel); level++ {
+		s := newLevelState(cg, q, comm)
+		s.moveNodesFast(rng, opts.MaxSweeps)
+		comm = s.comm
+		k := compactLabels(comm)
+
+		levelOf := make([]int, len(nodeOf))
+		for i := range nodeOf {
+			levelOf[i] = comm[nodeOf[i]]
+		}
+		result.addLevel(levelPartition(nodes, levelOf))
+		if k == cg.len() {
+			break
+		}
+
+		refined := cg.refine(q, comm, k, rng, theta)
+		kr := compactLabels(refined)
+		if kr == cg.len() {
+			// уточнение ничего не слило - сворачиваем сами сообщества, как Louvain
+			refined, kr = comm, k
+		}
+		next := make([]int, kr)
+		for i, r := range refined {
+			next[r] = comm[i]
+		}
+		for i := range nodeOf {
+			nodeOf[i] = refined[nodeOf[i]]
+		}
+		cg = cg.aggregate(refined, kr)
+		comm = next
+	}
+	for i := range nodeOf {
+		nodeOf[i] = comm[nodeOf[i]]
+	}
+	result.finish(g, nodes, nodeOf, opts)
+	return result
+}
@@ -18,8 +18,9 @@ type Node struct {
 
 // Edge представляет ребро в JSON файле
 type Edge struct {
-	Source string `json:"source"`
-	Target string `json:"target"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Weight *float64 `json:"weight,omitempty"` // сила связи (если не задана - 1)
 }
 
 // AMteachers представляет структуру графа учителей из JSON
@@ -75,17 +76,46 @@ func LoadAMteachers(filePath string) (*AMteachers, error) {
 // 3. Добавить все узлы
 // 4. Добавить все рёбра
 //
+// При directed=true строится ориентированный граф (дуги source→target),
+// при multigraph=true повторные рёбра не перезаписываются, а складываются:
+// кратность (сумма весов) становится весом ребра.
+//
 // Возвращает:
 //
 //	*Graph - граф в нашем формате
 //	map[string]int - соответствие имя учителя → числовой ID
 //	map[int]string - соответствие числовой ID → имя учителя
 func (t *AMteachers) ToGraph() (*Graph, map[string]int, map[int]string) {
-	g := NewGraph()
+	g, nameToID, idToName, missing := t.toGraph()
+
+	fmt.Printf("\n📍 Соответствие узлов:\n")
+	for i := 0; i < len(t.Nodes); i++ {
+		fmt.Printf("  %d → %s\n", i, idToName[i])
+	}
+	for _, name := range missing {
+		fmt.Printf("⚠️ узел %s не найден\n", name)
+	}
+
+	fmt.Printf("\n✅ Граф создан: %d узлов, %d рёбер\n",
+		g.NumNodes(), g.NumEdges())
+	if g.Directed {
+		fmt.Printf("   ориентированный: %d дуг\n", g.NumArcs())
+	}
+
+	return g, nameToID, idToName
+}
+
+// toGraph - ToGraph без вывода в stdout (для сервиса).
+// missing - концы рёбер, которых нет среди узлов, в порядке рёбер; такие рёбра пропускаются
+func (t *AMteachers) toGraph() (g *Graph, nameToID map[string]int, idToName map[int]string, missing []string) {
+	g = NewGraph()
+	if t.IsDirected {
+		g = NewDirectedGraph()
+	}
 
 	// Создать соответствие: имя учителя → числовой ID
-	nameToID := make(map[string]int)
-	idToName := make(map[int]string)
+	nameToID = make(map[string]int)
+	idToName = make(map[int]string)
 
 	// Шаг 1: добавить все узлы и создать соответствие
 	for i, node := range t.Nodes {
@@ -94,32 +124,35 @@ func (t *AMteachers) ToGraph() (*Graph, map[string]int, map[int]string) {
 		g.AddNode(i)
 	}
 
-	fmt.Printf("\n📍 Соответствие узлов:\n")
-	for i := 0; i < len(t.Nodes); i++ {
-		fmt.Printf("  %d → %s\n", i, idToName[i])
-	}
-
 	// Шаг 2: добавить все рёбра (преобразовав имена в ID)
 	for _, edge := range t.Edges {
 		u, ok1 := nameToID[edge.Source]
 		v, ok2 := nameToID[edge.Target]
 
 		if ok1 && ok2 {
-			g.AddEdge(u, v)
+			w := 1.0
+			if edge.Weight != nil {
+				w = *edge.Weight
+			}
+			switch {
+			case t.IsMultigraph:
+				g.AddParallelEdge(u, v, w)
+			case t.IsDirected:
+				g.AddArc(u, v, w)
+			default:
+				g.AddWeightedEdge(u, v, w)
+			}
 		} else {
 			if !ok1 {
-				fmt.Printf("⚠️ узел %s не найден\n", edge.Source)
+				missing = append(missing, edge.Source)
 			}
 			if !ok2 {
-				fmt.Printf("⚠️ узел %s не найден\n", edge.Target)
+				missing = append(missing, edge.Target)
 			}
 		}
 	}
 
-	fmt.Printf("\n✅ Граф создан: %d узлов, %d рёбер\n",
-		g.NumNodes(), g.NumEdges())
-
-	return g, nameToID, idToName
+	return g, nameToID, idToName, missing
 }
 
 // ============================================================
@@ -168,6 +201,26 @@ func LoadKarateClub() *Graph {
 	return G
 }
 
+// KarateClubFactions - фракции клуба после раскола по Zachary (1977):
+// 0 - сторонники инструктора (Mr. Hi), 1 - сторонники администратора (Officer).
+// Нумерация узлов как в LoadKarateClub, но список рёбер LoadKarateClub
+// отличается от оригинального, поэтому эталон для него приблизительный
+func KarateClubFactions() *Partition {
+	mrHi := map[int]bool{
+		0: true, 1: true, 2: true, 3: true, 4: true, 5: true, 6: true, 7: true, 8: true,
+		10: true, 11: true, 12: true, 13: true, 16: true, 17: true, 19: true, 21: true,
+	}
+	truth := NewPartition(34)
+	for node := 0; node < 34; node++ {
+		if mrHi[node] {
+			truth.Set(node, 0)
+		} else {
+			truth.Set(node, 1)
+		}
+	}
+	return truth
+}
+
 // LoadCavemanGraph загружает граф "пещерных людей"
 func LoadCavemanGraph(numCliques, cliqueSize int) *Graph {
 	G := NewGraph()
@@ -192,6 +245,15 @@ func LoadCavemanGraph(numCliques, cliqueSize int) *Graph {
 	return G
 }
 
+// CavemanCliques - эталон для LoadCavemanGraph: каждая клика - своё сообщество
+func CavemanCliques(numCliques, cliqueSize int) *Partition {
+	truth := NewPartition(numCliques * cliqueSize)
+	for node := 0; node < numCliques*cliqueSize; node++ {
+		truth.Set(node, node/cliqueSize)
+	}
+	return truth
+}
+
 // ============================================================
 // ПЕЧАТЬ ИНФОРМАЦИИ О ГРАФЕ
 // ============================================================
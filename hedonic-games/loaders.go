@@ -0,0 +1,817 @@
+// loaders.go - загрузка графов из файлов разных форматов
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GraphFormat - формат файла с графом
+type GraphFormat string
+
+const (
+	FormatNodeLink GraphFormat = "json"     // node-link JSON (как ds/relations_graph.json)
+	FormatEdgelist GraphFormat = "edgelist" // список рёбер через пробелы или запятые
+	FormatGML      GraphFormat = "gml"
+	FormatGraphML  GraphFormat = "graphml"
+	FormatPajek    GraphFormat = "pajek"
+)
+
+// ============================================================
+// ОПРЕДЕЛЕНИЕ ФОРМАТА
+// ============================================================
+
+// DetectGraphFormat определяет формат по расширению, а если оно ничего
+// не говорит - по первой значимой строке файла
+func DetectGraphFormat(filePath string) (GraphFormat, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return FormatNodeLink, nil
+	case ".gml":
+		return FormatGML, nil
+	case ".graphml":
+		return FormatGraphML, nil
+	case ".net", ".pajek":
+		return FormatPajek, nil
+	case ".edgelist", ".edges", ".el", ".csv", ".tsv":
+		return FormatEdgelist, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+		lower := strings.ToLower(line)
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return FormatNodeLink, nil
+		case strings.HasPrefix(line, "<"):
+			return FormatGraphML, nil
+		case strings.HasPrefix(lower, "*vertices"), strings.HasPrefix(lower, "*network"):
+			return FormatPajek, nil
+		case strings.HasPrefix(lower, "graph") || strings.HasPrefix(lower, "creator"):
+			return FormatGML, nil
+		}
+		return FormatEdgelist, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("%s: %w", filePath, err)
+	}
+	return FormatEdgelist, nil
+}
+
+// LoadGraphFile загружает граф, определяя формат автоматически
+// Возвращает ту же тройку, что и AMteachers.ToGraph
+func LoadGraphFile(filePath string) (*Graph, map[string]int, map[int]string, error) {
+	format, err := DetectGraphFormat(filePath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return LoadGraphFileAs(filePath, format)
+}
+
+// LoadGraphFileAs загружает граф заданного формата
+func LoadGraphFileAs(filePath string, format GraphFormat) (*Graph, map[string]int, map[int]string, error) {
+	switch format {
+	case FormatNodeLink:
+		return loadNodeLink(filePath)
+	case FormatEdgelist:
+		return LoadEdgelist(filePath)
+	case FormatGML:
+		return LoadGML(filePath)
+	case FormatGraphML:
+		return LoadGraphML(filePath)
+	case FormatPajek:
+		return LoadPajek(filePath)
+	}
+	return nil, nil, nil, fmt.Errorf("неизвестный формат графа %q", format)
+}
+
+// loadNodeLink - node-link JSON через LoadAMteachers, с номером строки в ошибке
+func loadNodeLink(filePath string) (*Graph, map[string]int, map[int]string, error) {
+	teachers, err := LoadAMteachers(filePath)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		var offset int64 = -1
+		if errors.As(err, &syntaxErr) {
+			offset = syntaxErr.Offset
+		} else if errors.As(err, &typeErr) {
+			offset = typeErr.Offset
+		}
+		if offset >= 0 {
+			if data, readErr := os.ReadFile(filePath); readErr == nil {
+				line := 1 + bytes.Count(data[:min(int(offset), len(data))], []byte("\n"))
+				return nil, nil, nil, fmt.Errorf("%s:%d: %w", filePath, line, err)
+			}
+		}
+		return nil, nil, nil, err
+	}
+
+	g, nameToID, idToName := teachers.ToGraph()
+	return g, nameToID, idToName, nil
+}
+
+// ============================================================
+// ПОСТРОЕНИЕ ГРАФА ПО ИМЕНАМ УЗЛОВ
+// ============================================================
+
+// graphBuilder присваивает узлам числовые ID в порядке первого появления
+type graphBuilder struct {
+	g        *Graph
+	nameToID map[string]int
+	idToName map[int]string
+}
+
+func newGraphBuilder() *graphBuilder {
+	return &graphBuilder{
+		g:        NewGraph(),
+		nameToID: make(map[string]int),
+		idToName: make(map[int]string),
+	}
+}
+
+func (b *graphBuilder) node(name string) int {
+	id, ok := b.nameToID[name]
+	if !ok {
+		id = len(b.nameToID)
+		b.nameToID[name] = id
+		b.idToName[id] = name
+		b.g.AddNode(id)
+	}
+	return id
+}
+
+func (b *graphBuilder) result() (*Graph, map[string]int, map[int]string, error) {
+	return b.g, b.nameToID, b.idToName, nil
+}
+
+// lineError - ошибка с указанием файла и строки
+func lineError(filePath string, line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", filePath, line, fmt.Sprintf(format, args...))
+}
+
+// ============================================================
+// СПИСОК РЁБЕР
+// ============================================================
+
+// edgelistHeaders - имена колонок, по которым узнаётся строка-заголовок CSV
+var edgelistHeaders = map[string]bool{
+	"source": true, "from": true, "src": true, "u": true, "node1": true,
+}
+
+// LoadEdgelist загружает граф из списка рёбер ("u v" или "u v w")
+// Разделители - пробелы, табуляция, запятая или точка с запятой.
+// Строки, начинающиеся с '#' или '%', пропускаются, как и заголовок CSV.
+// Узлам присваиваются ID в порядке первого появления.
+func LoadEdgelist(filePath string) (*Graph, map[string]int, map[int]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	b := newGraphBuilder()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	firstData := true
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		fields := splitEdgelistLine(line)
+		if firstData {
+			firstData = false
+			if edgelistHeaders[strings.ToLower(fields[0])] {
+				continue
+			}
+		}
+		if len(fields) < 2 {
+			return nil, nil, nil, lineError(filePath, lineNum, "ожидалось \"u v [w]\", получено %q", line)
+		}
+
+		u := b.node(fields[0])
+		v := b.node(fields[1])
+		if len(fields) >= 3 {
+			w, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return nil, nil, nil, lineError(filePath, lineNum, "неверный вес %q", fields[2])
+			}
+			b.g.AddWeightedEdge(u, v, w)
+		} else {
+			b.g.AddEdge(u, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return b.result()
+}
+
+func splitEdgelistLine(line string) []string {
+	if strings.ContainsAny(line, ",;") {
+		parts := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ';' })
+		fields := make([]string, 0, len(parts))
+		for _, part := range parts {
+			fields = append(fields, strings.Trim(strings.TrimSpace(part), `"`))
+		}
+		return fields
+	}
+	return strings.Fields(line)
+}
+
+// ============================================================
+// GML
+// ============================================================
+
+// gmlToken - лексема GML: ключ, значение или скобка
+type gmlToken struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+func tokenizeGML(filePath string, data []byte) ([]gmlToken, error) {
+	var tokens []gmlToken
+	text := []rune(string(data))
+	line := 1
+
+	for i := 0; i < len(text); {
+		r := text[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case r == '[' || r == ']':
+			tokens = append(tokens, gmlToken{text: string(r), line: line})
+			i++
+		case r == '"':
+			start, startLine := i+1, line
+			i++
+			for i < len(text) && text[i] != '"' {
+				if text[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i >= len(text) {
+				return nil, lineError(filePath, startLine, "незакрытая строка")
+			}
+			tokens = append(tokens, gmlToken{text: string(text[start:i]), quoted: true, line: startLine})
+			i++
+		default:
+			start := i
+			for i < len(text) && !unicode.IsSpace(text[i]) && text[i] != '[' && text[i] != ']' {
+				i++
+			}
+			tokens = append(tokens, gmlToken{text: string(text[start:i]), line: line})
+		}
+	}
+	return tokens, nil
+}
+
+// gmlRecord - содержимое блока node [...] или edge [...]
+type gmlRecord struct {
+	values map[string]gmlToken
+	line   int
+}
+
+// LoadGML загружает граф в формате GML
+// Имя узла - label, а если его нет - id. Вес ребра - атрибут weight или value.
+func LoadGML(filePath string) (*Graph, map[string]int, map[int]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+
+	tokens, err := tokenizeGML(filePath, data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var nodes, edges []gmlRecord
+	depth := 0
+	inGraph := false
+	var current *gmlRecord
+	var currentKind string
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.text == "]" && !tok.quoted:
+			if depth == 0 {
+				return nil, nil, nil, lineError(filePath, tok.line, "лишняя ']'")
+			}
+			depth--
+			if current != nil && depth == 1 {
+				if currentKind == "node" {
+					nodes = append(nodes, *current)
+				} else {
+					edges = append(edges, *current)
+				}
+				current = nil
+			}
+			if depth == 0 {
+				inGraph = false
+			}
+			continue
+		case tok.text == "[" && !tok.quoted:
+			return nil, nil, nil, lineError(filePath, tok.line, "'[' без ключа")
+		}
+
+		if i+1 >= len(tokens) {
+			return nil, nil, nil, lineError(filePath, tok.line, "ключ %q без значения", tok.text)
+		}
+		key := strings.ToLower(tok.text)
+		value := tokens[i+1]
+		i++
+
+		if value.text == "[" && !value.quoted {
+			depth++
+			switch {
+			case depth == 1 && key == "graph":
+				inGraph = true
+			case depth == 2 && inGraph && (key == "node" || key == "edge"):
+				current = &gmlRecord{values: make(map[string]gmlToken), line: tok.line}
+				currentKind = key
+			}
+			continue
+		}
+
+		if current != nil && depth == 2 {
+			current.values[key] = value
+		}
+	}
+	if depth != 0 {
+		return nil, nil, nil, lineError(filePath, tokens[len(tokens)-1].line, "не закрыто %d блоков '['", depth)
+	}
+
+	b := newGraphBuilder()
+	gmlIDs := make(map[string]int)
+
+	for _, rec := range nodes {
+		id, ok := rec.values["id"]
+		if !ok {
+			return nil, nil, nil, lineError(filePath, rec.line, "у узла нет id")
+		}
+		name := id.text
+		if label, ok := rec.values["label"]; ok {
+			name = label.text
+		}
+		if _, dup := gmlIDs[id.text]; dup {
+			return nil, nil, nil, lineError(filePath, rec.line, "повторный id узла %q", id.text)
+		}
+		gmlIDs[id.text] = b.node(name)
+	}
+
+	for _, rec := range edges {
+		source, ok1 := rec.values["source"]
+		target, ok2 := rec.values["target"]
+		if !ok1 || !ok2 {
+			return nil, nil, nil, lineError(filePath, rec.line, "у ребра нет source или target")
+		}
+		u, ok1 := gmlIDs[source.text]
+		v, ok2 := gmlIDs[target.text]
+		if !ok1 {
+			return nil, nil, nil, lineError(filePath, rec.line, "неизвестный узел %q", source.text)
+		}
+		if !ok2 {
+			return nil, nil, nil, lineError(filePath, rec.line, "неизвестный узел %q", target.text)
+		}
+
+		weightTok, hasWeight := rec.values["weight"]
+		if !hasWeight {
+			weightTok, hasWeight = rec.values["value"]
+		}
+		if hasWeight {
+			w, err := strconv.ParseFloat(weightTok.text, 64)
+			if err != nil {
+				return nil, nil, nil, lineError(filePath, weightTok.line, "неверный вес %q", weightTok.text)
+			}
+			b.g.AddWeightedEdge(u, v, w)
+		} else {
+			b.g.AddEdge(u, v)
+		}
+	}
+
+	return b.result()
+}
+
+// ============================================================
+// GraphML
+// ============================================================
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID string `xml:"id,attr"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// LoadGraphML загружает граф в формате GraphML
+// Имя узла - его id. Вес ребра - data с ключом, у которого attr.name="weight".
+func LoadGraphML(filePath string) (*Graph, map[string]int, map[int]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	b := newGraphBuilder()
+	weightKeys := make(map[string]bool)
+	decoder := xml.NewDecoder(file)
+
+	for {
+		line, _ := decoder.InputPos()
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, _ = decoder.InputPos()
+			return nil, nil, nil, graphMLError(filePath, line, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		line, _ = decoder.InputPos()
+
+		switch start.Name.Local {
+		case "key":
+			var key graphMLKey
+			if err := decoder.DecodeElement(&key, &start); err != nil {
+				return nil, nil, nil, graphMLError(filePath, line, err)
+			}
+			if strings.EqualFold(key.Name, "weight") && (key.For == "edge" || key.For == "all" || key.For == "") {
+				weightKeys[key.ID] = true
+			}
+
+		case "node":
+			var node graphMLNode
+			if err := decoder.DecodeElement(&node, &start); err != nil {
+				return nil, nil, nil, graphMLError(filePath, line, err)
+			}
+			if node.ID == "" {
+				return nil, nil, nil, lineError(filePath, line, "у узла нет id")
+			}
+			b.node(node.ID)
+
+		case "edge":
+			var edge graphMLEdge
+			if err := decoder.DecodeElement(&edge, &start); err != nil {
+				return nil, nil, nil, graphMLError(filePath, line, err)
+			}
+			if edge.Source == "" || edge.Target == "" {
+				return nil, nil, nil, lineError(filePath, line, "у ребра нет source или target")
+			}
+			u := b.node(edge.Source)
+			v := b.node(edge.Target)
+
+			weighted := false
+			for _, d := range edge.Data {
+				if !weightKeys[d.Key] {
+					continue
+				}
+				w, err := strconv.ParseFloat(strings.TrimSpace(d.Value), 64)
+				if err != nil {
+					return nil, nil, nil, lineError(filePath, line, "неверный вес %q", d.Value)
+				}
+				b.g.AddWeightedEdge(u, v, w)
+				weighted = true
+			}
+			if !weighted {
+				b.g.AddEdge(u, v)
+			}
+		}
+	}
+
+	return b.result()
+}
+
+// graphMLError - ошибка разбора GraphML: у синтаксической ошибки XML своя строка
+// (позиция декодера к этому моменту может отставать от неё)
+func graphMLError(filePath string, line int, err error) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line = syntaxErr.Line
+	}
+	return lineError(filePath, line, "%v", err)
+}
+
+// ============================================================
+// Pajek .net
+// ============================================================
+
+// LoadPajek загружает граф в формате Pajek (.net)
+// Поддерживаются разделы *Vertices, *Edges, *Arcs, *Edgeslist и *Arcslist.
+// Имя узла - метка в кавычках, а если её нет - номер вершины.
+func LoadPajek(filePath string) (*Graph, map[string]int, map[int]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	b := newGraphBuilder()
+	pajekIDs := make(map[string]int) // номер вершины в файле → ID
+	section := ""
+
+	vertex := func(ref string) int {
+		if id, ok := pajekIDs[ref]; ok {
+			return id
+		}
+		id := b.node(ref)
+		pajekIDs[ref] = id
+		return id
+	}
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "*") {
+			section = strings.ToLower(strings.Fields(line)[0])
+			switch section {
+			case "*vertices", "*edges", "*arcs", "*edgeslist", "*arcslist", "*network":
+			default:
+				return nil, nil, nil, lineError(filePath, lineNum, "неизвестный раздел %q", section)
+			}
+			continue
+		}
+
+		switch section {
+		case "*vertices":
+			ref, label, err := parsePajekVertex(line)
+			if err != nil {
+				return nil, nil, nil, lineError(filePath, lineNum, "%v", err)
+			}
+			if _, dup := pajekIDs[ref]; dup {
+				return nil, nil, nil, lineError(filePath, lineNum, "повторная вершина %s", ref)
+			}
+			pajekIDs[ref] = b.node(label)
+
+		case "*edges", "*arcs":
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, nil, nil, lineError(filePath, lineNum, "ожидалось \"u v [w]\", получено %q", line)
+			}
+			u, v := vertex(fields[0]), vertex(fields[1])
+			if len(fields) >= 3 {
+				w, err := strconv.ParseFloat(fields[2], 64)
+				if err != nil {
+					return nil, nil, nil, lineError(filePath, lineNum, "неверный вес %q", fields[2])
+				}
+				b.g.AddWeightedEdge(u, v, w)
+			} else {
+				b.g.AddEdge(u, v)
+			}
+
+		case "*edgeslist", "*arcslist":
+			fields := strings.Fields(line)
+			u := vertex(fields[0])
+			for _, ref := range fields[1:] {
+				b.g.AddEdge(u, vertex(ref))
+			}
+
+		default:
+			return nil, nil, nil, lineError(filePath, lineNum, "данные вне раздела: %q", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return b.result()
+}
+
+// parsePajekVertex разбирает строку раздела *Vertices: номер и необязательная метка
+func parsePajekVertex(line string) (string, string, error) {
+	fields := strings.Fields(line)
+	ref := fields[0]
+	if _, err := strconv.Atoi(ref); err != nil {
+		return "", "", fmt.Errorf("неверный номер вершины %q", ref)
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(line, ref))
+	if rest == "" {
+		return ref, ref, nil
+	}
+	if strings.HasPrefix(rest, `"`) {
+		end := strings.Index(rest[1:], `"`)
+		if end < 0 {
+			return "", "", fmt.Errorf("незакрытая метка вершины %s", ref)
+		}
+		return ref, rest[1 : end+1], nil
+	}
+	return ref, strings.Fields(rest)[0], nil
+}
+
+// ============================================================
+// ЭТАЛОННЫЕ РАЗБИЕНИЯ
+// ============================================================
+
+// groundTruthHeaders - имена первой колонки, по которым узнаётся заголовок CSV разметки
+var groundTruthHeaders = map[string]bool{
+	"node": true, "id": true, "name": true,
+}
+
+// LoadGroundTruth загружает эталонную разметку узлов графа
+// *.json - формат ExportPartitionToJSON; иначе текст "узел метка" по строке
+// (разделители как в списке рёбер, метки - произвольные строки).
+// Узлы без метки в эталон не входят и при сравнении пропускаются.
+func LoadGroundTruth(filePath string, nameToID map[string]int) (*Partition, error) {
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		communities, err := LoadPartitionFromJSON(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		assignment := make(map[int]int, len(communities))
+		for name, comm := range communities {
+			id, ok := nameToID[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: узел %q отсутствует в графе", filePath, name)
+			}
+			if comm < 0 {
+				return nil, fmt.Errorf("%s: узел %q: отрицательный номер сообщества %d", filePath, name, comm)
+			}
+			assignment[id] = comm
+		}
+		return PartitionFromMap(assignment), nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	truth := NewPartition(len(nameToID))
+	labels := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	firstData := true
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		fields := splitEdgelistLine(line)
+		if firstData {
+			firstData = false
+			if _, known := nameToID[fields[0]]; !known && groundTruthHeaders[strings.ToLower(fields[0])] {
+				continue
+			}
+		}
+		if len(fields) < 2 {
+			return nil, lineError(filePath, lineNum, "ожидалось \"узел метка\", получено %q", line)
+		}
+
+		id, ok := nameToID[fields[0]]
+		if !ok {
+			return nil, lineError(filePath, lineNum, "узел %q отсутствует в графе", fields[0])
+		}
+		label, ok := labels[fields[1]]
+		if !ok {
+			label = len(labels)
+			labels[fields[1]] = label
+		}
+		truth.Set(id, label)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return truth, nil
+}
+
+// LoadPreferenceValues загружает таблицы ценностей моделей предпочтений.
+// Строка "i j v" - ценность агента j для агента i (несимметрична: для
+// симметричной нужны обе строки), "k v" - полезность коалиции из k агентов.
+// Разделители и комментарии - как в списке рёбер. Непомеченные пары - 0;
+// размеры без строки берут значение ближайшего меньшего заданного (0, если его нет)
+func LoadPreferenceValues(filePath string, nameToID map[string]int) (PreferenceValues, error) {
+	var values PreferenceValues
+	file, err := os.Open(filePath)
+	if err != nil {
+		return values, fmt.Errorf("Ошибка чтения файла %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	n := 0
+	for _, id := range nameToID {
+		n = max(n, id+1)
+	}
+	sizes := make(map[int]float64)
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		fields := splitEdgelistLine(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return values, lineError(filePath, lineNum, "ожидалось \"i j v\" или \"k v\", получено %q", line)
+		}
+		v, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return values, lineError(filePath, lineNum, "неверная ценность %q", fields[len(fields)-1])
+		}
+
+		if len(fields) == 2 {
+			k, err := strconv.Atoi(fields[0])
+			if err != nil || k < 1 || k > n {
+				return values, lineError(filePath, lineNum, "размер коалиции %q вне 1..%d", fields[0], n)
+			}
+			sizes[k] = v
+			continue
+		}
+
+		i, ok := nameToID[fields[0]]
+		if !ok {
+			return values, lineError(filePath, lineNum, "узел %q отсутствует в графе", fields[0])
+		}
+		j, ok := nameToID[fields[1]]
+		if !ok {
+			return values, lineError(filePath, lineNum, "узел %q отсутствует в графе", fields[1])
+		}
+		if values.Matrix == nil {
+			values.Matrix = make([][]float64, n)
+			for row := range values.Matrix {
+				values.Matrix[row] = make([]float64, n)
+			}
+		}
+		values.Matrix[i][j] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return values, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	if len(sizes) > 0 {
+		ks := SortedCommunityIDs(sizes)
+		values.Sizes = make([]float64, ks[len(ks)-1]+1)
+		for k := 1; k < len(values.Sizes); k++ {
+			if v, ok := sizes[k]; ok {
+				values.Sizes[k] = v
+			} else {
+				values.Sizes[k] = values.Sizes[k-1]
+			}
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemp пишет content во временный файл name и возвращает путь
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadGraphFormats - один и тот же взвешенный граф во всех форматах
+// (формат определяется по расширению или по содержимому)
+func TestLoadGraphFormats(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+	}{
+		{"edgelist", "g.edgelist", "# комментарий\na b 2\nb c\nc a 1\nc d 3\n"},
+		{"csv header", "g.csv", "source,target,weight\na,b,2\nb,c,1\nc,a,1\nc,d,3\n"},
+		{"edgelist by content", "g.txt", "% комментарий\na\tb\t2\nb\tc\nc\ta\nc\td\t3\n"},
+		{"gml", "g.gml", `graph [
+  node [ id 1 label "a" ]
+  node [ id 2 label "b" ]
+  node [ id 3 label "c" ]
+  node [ id 4 label "d" ]
+  edge [ source 1 target 2 weight 2 ]
+  edge [ source 2 target 3 ]
+  edge [ source 3 target 1 value 1 ]
+  edge [ source 3 target 4 weight 3.0 ]
+]
+`},
+		{"graphml", "g.graphml", `<?xml version="1.0"?>
+<graphml>
+  <key id="w" for="edge" attr.name="weight" attr.type="double"/>
+  <graph edgedefault="undirected">
+    <node id="a"/><node id="b"/><node id="c"/><node id="d"/>
+    <edge source="a" target="b"><data key="w">2</data></edge>
+    <edge source="b" target="c"/>
+    <edge source="c" target="a"/>
+    <edge source="c" target="d"><data key="w">3</data></edge>
+  </graph>
+</graphml>
+`},
+		{"pajek", "g.net", `*Vertices 4
+1 "a"
+2 "b"
+3 "c"
+4 "d"
+*Edges
+1 2 2
+2 3
+3 1
+3 4 3
+`},
+		{"pajek edgeslist", "g.pajek", "*Vertices 4\n1 \"a\"\n2 \"b\"\n3 \"c\"\n4 \"d\"\n*Edgeslist\n3 1 2\n*Edges\n1 2 2\n3 4 3\n"},
+		{"node-link", "g.json", `{"directed": false, "multigraph": false, "graph": {},
+ "nodes": [{"id": "a"}, {"id": "b"}, {"id": "c"}, {"id": "d"}],
+ "edges": [{"source": "a", "target": "b", "weight": 2}, {"source": "b", "target": "c"},
+           {"source": "c", "target": "a"}, {"source": "c", "target": "d", "weight": 3}]}
+`},
+	}
+	wantWeights := map[[2]string]float64{
+		{"a", "b"}: 2, {"b", "c"}: 1, {"c", "a"}: 1, {"c", "d"}: 3, {"a", "d"}: 0, {"b", "d"}: 0,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, nameToID, idToName, err := LoadGraphFile(writeTemp(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.NumNodes() != 4 || g.NumEdges() != 4 {
+				t.Fatalf("узлов %d, рёбер %d, ожидалось 4 и 4", g.NumNodes(), g.NumEdges())
+			}
+			for pair, want := range wantWeights {
+				u, v := nameToID[pair[0]], nameToID[pair[1]]
+				if got := g.Weight(u, v); got != want {
+					t.Errorf("вес %s-%s = %g, ожидался %g", pair[0], pair[1], got, want)
+				}
+			}
+			for name, id := range nameToID {
+				if idToName[id] != name {
+					t.Errorf("idToName[%d] = %q, ожидалось %q", id, idToName[id], name)
+				}
+			}
+		})
+	}
+}
+
+// TestLoadGraphErrorsHaveLine - ошибки разбора указывают файл и строку
+func TestLoadGraphErrorsHaveLine(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+		line                string
+	}{
+		{"edgelist single field", "g.edgelist", "a b\nb c\nlonely\n", ":3:"},
+		{"edgelist bad weight", "g.edgelist", "a b 1\n\nb c heavy\n", ":3:"},
+		{"gml unknown node", "g.gml", "graph [\n  node [ id 1 ]\n  edge [ source 1 target 7 ]\n]\n", ":3:"},
+		{"gml stray bracket", "g.gml", "graph [\n  node [ id 1 ]\n]\n]\n", ":4:"},
+		{"gml bad weight", "g.gml", "graph [\n node [ id 1 ]\n node [ id 2 ]\n edge [ source 1 target 2\n weight heavy ]\n]\n", ":5:"},
+		{"graphml bad weight", "g.graphml", "<graphml>\n<key id=\"w\" for=\"edge\" attr.name=\"weight\"/>\n<graph>\n<edge source=\"a\" target=\"b\"><data key=\"w\">x</data></edge>\n</graph>\n</graphml>\n", ":4:"},
+		{"graphml broken xml", "g.graphml", "<graphml>\n<graph>\n<node id=\"a\">\n</graph>\n", ":4:"},
+		{"pajek bad vertex", "g.net", "*Vertices 2\n1 \"a\"\nx \"b\"\n", ":3:"},
+		{"pajek unknown section", "g.net", "*Vertices 1\n1\n*Hyperedges\n", ":3:"},
+		{"pajek bad weight", "g.net", "*Vertices 2\n1\n2\n*Edges\n1 2 w\n", ":5:"},
+		{"node-link syntax", "g.json", "{\"nodes\": [\n  {\"id\": \"a\"},\n  {\"id\": \"b\"\n]}\n", ":4:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, tt.file, tt.content)
+			_, _, _, err := LoadGraphFile(path)
+			if err == nil {
+				t.Fatal("нет ошибки")
+			}
+			if !strings.Contains(err.Error(), path+tt.line) {
+				t.Errorf("ошибка %q не указывает %s%s", err, filepath.Base(path), tt.line)
+			}
+		})
+	}
+}
+
+// TestLoadGroundTruth - разметка "узел метка" с заголовком, комментариями и
+// неизвестными графу узлами; JSON - в формате ExportPartitionToJSON
+func TestLoadGroundTruth(t *testing.T) {
+	nameToID := map[string]int{"a": 0, "b": 1, "c": 2, "d": 3}
+	truth, err := LoadGroundTruth(writeTemp(t, "truth.txt", "node label\n# комментарий\na x\nb x\nc y\n"), nameToID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if truth.Community(0) != truth.Community(1) || truth.Community(0) == truth.Community(2) {
+		t.Errorf("разметка %v", truth.ToMap())
+	}
+	if truth.Community(3) >= 0 {
+		t.Errorf("неразмеченный узел d попал в сообщество %d", truth.Community(3))
+	}
+	if _, err := LoadGroundTruth(writeTemp(t, "truth.txt", "a x\nzz y\n"), nameToID); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Errorf("неизвестный узел: ошибка %v, ожидалась с номером строки 2", err)
+	}
+}
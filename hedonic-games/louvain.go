@@ -0,0 +1,368 @@
+// louvain.go - базовый метод Louvain (Blondel и др., 2008) на том же Graph
+package main
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+)
+
+// MultilevelOptions - параметры Louvain и Leiden
+type MultilevelOptions struct {
+	Resolution float64         // γ (0 - 1)
+	NullModel  NullModel       // ng - модулярность, cpm - модель Поттса с константой ("" - ng)
+	MaxSweeps  int             // максимум проходов локального перемещения на уровне (0 - без ограничения)
+	MaxLevels  int             // максимум уровней агрегирования (0 - без ограничения)
+	Theta      float64         // случайность уточнения Leiden (0 - 0.01)
+	Cancel     <-chan struct{} // закрытие прерывает агрегирование перед следующим уровнем (nil - нет)
+}
+
+// MultilevelResult - итог многоуровневого метода
+type MultilevelResult struct {
+	Partition *Partition   // итоговое разбиение исходных узлов (номера 0..K-1)
+	Levels    []*Partition // разбиение исходных узлов после каждого уровня (от мелкого к крупному)
+	Quality   float64      // Q_γ или H_CPM итогового разбиения (см. SymmetricResolutionQuality)
+}
+
+// qualityModel - ожидаемый вес связей между группами узлов в нулевой модели.
+// Приращение качества при переходе узла в сообщество - links - expected
+// (как moveGain72 гедонической игры)
+type qualityModel struct {
+	gamma float64
+	cpm   bool
+	twoM  float64
+}
+
+func newQualityModel(opts MultilevelOptions, m float64) qualityModel {
+	gamma := opts.Resolution
+	if gamma == 0 {
+		gamma = 1
+	}
+	return qualityModel{gamma: gamma, cpm: opts.NullModel == NullModelCPM, twoM: 2 * m}
+}
+
+// expected - ожидаемый вес связей групп A и B (степени и число исходных узлов)
+func (q qualityModel) expected(degA float64, sizeA int, degB float64, sizeB int) float64 {
+	if q.cpm {
+		return q.gamma * float64(sizeA) * float64(sizeB)
+	}
+	if q.twoM == 0 {
+		return 0
+	}
+	return q.gamma * degA * degB / q.twoM
+}
+
+// ============================================================
+// АГРЕГИРОВАННЫЙ ГРАФ
+// ============================================================
+
+// communityGraph - граф уровня: узел - группа исходных узлов, петля - вес рёбер
+// внутри группы. Списки соседей без петель, по возрастанию номера
+type communityGraph struct {
+	neighbors [][]int
+	weights   [][]float64
+	loops     []float64 // вес рёбер внутри узла
+	degree    []float64 // сумма степеней исходных узлов
+	size      []int     // число исходных узлов
+	m         float64   // суммарный вес рёбер
+}
+
+// newCommunityGraph строит граф уровня 0; nodes - исходные узлы по возрастанию
+func newCommunityGraph(g *Graph) (*communityGraph, []int) {
+	nodes := g.GetNodeList()
+	index := make(map[int]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+	n := len(nodes)
+	cg := &communityGraph{
+		neighbors: make([][]int, n),
+		weights:   make([][]float64, n),
+		loops:     make([]float64, n),
+		degree:    make([]float64, n),
+		size:      make([]int, n),
+	}
+	for i, u := range nodes {
+		cg.size[i] = 1
+		for _, v := range g.GetNeighbors(u) {
+			w := g.Weight(u, v)
+			if v == u {
+				cg.loops[i] += w
+				cg.degree[i] += 2 * w
+				continue
+			}
+			cg.neighbors[i] = append(cg.neighbors[i], index[v])
+			cg.weights[i] = append(cg.weights[i], w)
+			cg.degree[i] += w
+		}
+		cg.m += cg.degree[i]
+	}
+	cg.m /= 2
+	return cg, nodes
+}
+
+func (cg *communityGraph) len() int {
+	return len(cg.size)
+}
+
+// aggregate сворачивает сообщества comm (номера 0..K-1) в узлы нового графа
+func (cg *communityGraph) aggregate(comm []int, k int) *communityGraph {
+	agg := &communityGraph{
+		neighbors: make([][]int, k),
+		weights:   make([][]float64, k),
+		loops:     make([]float64, k),
+		degree:    make([]float64, k),
+		size:      make([]int, k),
+		m:         cg.m,
+	}
+	links := make([]map[int]float64, k)
+	for i := range cg.size {
+		c := comm[i]
+		agg.loops[c] += cg.loops[i]
+		agg.degree[c] += cg.degree[i]
+		agg.size[c] += cg.size[i]
+		for j, v := range cg.neighbors[i] {
+			w := cg.weights[i][j]
+			if comm[v] == c {
+				agg.loops[c] += w / 2 // ребро внутри сообщества встречается с обоих концов
+				continue
+			}
+			if links[c] == nil {
+				links[c] = make(map[int]float64)
+			}
+			links[c][comm[v]] += w
+		}
+	}
+	for c := range links {
+		for _, d := range SortedCommunityIDs(links[c]) {
+			agg.neighbors[c] = append(agg.neighbors[c], d)
+			agg.weights[c] = append(agg.weights[c], links[c][d])
+		}
+	}
+	return agg
+}
+
+// compactLabels перенумеровывает сообщества в 0..K-1 в порядке первого узла, возвращает K
+func compactLabels(comm []int) int {
+	label := make(map[int]int)
+	for i, c := range comm {
+		l, ok := label[c]
+		if !ok {
+			l = len(label)
+			label[c] = l
+		}
+		comm[i] = l
+	}
+	return len(label)
+}
+
+// ============================================================
+// ЛОКАЛЬНОЕ ПЕРЕМЕЩЕНИЕ
+// ============================================================
+
+// levelState - сообщества узлов уровня и их агрегаты
+type levelState struct {
+	cg         *communityGraph
+	q          qualityModel
+	comm       []int
+	commDegree []float64
+	commSize   []int
+	members    []int // число узлов уровня в сообществе
+	empty      []int // номера пустых сообществ
+
+	links   []float64 // вес связей узла с сообществами (рабочий буфер)
+	linked  []bool
+	touched []int
+}
+
+// newLevelState - состояние с начальным разбиением comm (номера < cg.len())
+func newLevelState(cg *communityGraph, q qualityModel, comm []int) *levelState {
+	n := cg.len()
+	s := &levelState{
+		cg:         cg,
+		q:          q,
+		comm:       comm,
+		commDegree: make([]float64, n),
+		commSize:   make([]int, n),
+		members:    make([]int, n),
+		links:      make([]float64, n),
+		linked:     make([]bool, n),
+	}
+	for i, c := range comm {
+		s.commDegree[c] += cg.degree[i]
+		s.commSize[c] += cg.size[i]
+		s.members[c]++
+	}
+	for c := n - 1; c >= 0; c-- {
+		if s.members[c] == 0 {
+			s.empty = append(s.empty, c)
+		}
+	}
+	return s
+}
+
+// collectLinks заполняет links весами связей узла с соседними сообществами
+func (s *levelState) collectLinks(node int) {
+	for _, c := range s.touched {
+		s.links[c] = 0
+		s.linked[c] = false
+	}
+	s.touched = s.touched[:0]
+	for j, v := range s.cg.neighbors[node] {
+		c := s.comm[v]
+		if !s.linked[c] {
+			s.linked[c] = true
+			s.touched = append(s.touched, c)
+		}
+		s.links[c] += s.cg.weights[node][j]
+	}
+}
+
+func (s *levelState) detach(node int) {
+	c := s.comm[node]
+	s.commDegree[c] -= s.cg.degree[node]
+	s.commSize[c] -= s.cg.size[node]
+	s.members[c]--
+	if s.members[c] == 0 {
+		s.empty = append(s.empty, c)
+	}
+}
+
+func (s *levelState) attach(node, c int) {
+	if s.members[c] == 0 {
+		if last := len(s.empty) - 1; s.empty[last] == c {
+			s.empty = s.empty[:last] // обычно узел возвращается в только что опустевшее
+		} else {
+			s.empty = slices.DeleteFunc(s.empty, func(e int) bool { return e == c })
+		}
+	}
+	s.comm[node] = c
+	s.commDegree[c] += s.cg.degree[node]
+	s.commSize[c] += s.cg.size[node]
+	s.members[c]++
+}
+
+// gain - прирост качества от вступления отсоединённого узла в сообщество c
+func (s *levelState) gain(node, c int) float64 {
+	return s.links[c] - s.q.expected(s.cg.degree[node], s.cg.size[node], s.commDegree[c], s.commSize[c])
+}
+
+// bestMove переносит узел в лучшее сообщество (соседнее, своё или пустое),
+// при равенстве остаётся на месте. Возвращает, сменилось ли сообщество
+func (s *levelState) bestMove(node int) bool {
+	old := s.comm[node]
+	s.collectLinks(node)
+	s.detach(node)
+
+	best, bestGain := old, s.gain(node, old)
+	for _, c := range s.touched {
+		if g := s.gain(node, c); g > bestGain+1e-12 {
+			best, bestGain = c, g
+		}
+	}
+	if bestGain < -1e-12 && s.members[old] > 0 {
+		best = s.empty[len(s.empty)-1] // одному лучше: прирост в пустом сообществе 0
+	}
+	s.attach(node, best)
+	return best != old
+}
+
+// moveNodes - проходы локального перемещения Louvain в случайном порядке
+// до отсутствия улучшений. Возвращает, был ли хоть один переход
+func (s *levelState) moveNodes(rng *rand.Rand, maxSweeps int) bool {
+	order := rng.Perm(s.cg.len())
+	moved := false
+	for sweep := 0; maxSweeps <= 0 || sweep < maxSweeps; sweep++ {
+		changed := false
+		for _, node := range order {
+			if s.bestMove(node) {
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		moved = true
+	}
+	return moved
+}
+
+// ============================================================
+// LOUVAIN
+// ============================================================
+
+// Louvain - локальное перемещение узлов и свёртка сообществ в узлы,
+// пока перемещения улучшают качество. rng задаёт порядок обхода узлов
+func Louvain(g *Graph, opts MultilevelOptions, rng *rand.Rand) *MultilevelResult {
+	cg, nodes := newCommunityGraph(g)
+	q := newQualityModel(opts, cg.m)
+
+	nodeOf := make([]int, len(nodes)) // исходный узел -> узел текущего уровня
+	for i := range nodeOf {
+		nodeOf[i] = i
+	}
+	result := &MultilevelResult{}
+	for level := 0; (opts.MaxLevels <= 0 || level < opts.MaxLevels) && !isCancelled(opts.Cancel); level++ {
+		s := newLevelState(cg, q, identity(cg.len()))
+		if !s.moveNodes(rng, opts.MaxSweeps) {
+			break
+		}
+		k := compactLabels(s.comm)
+		for i := range nodeOf {
+			nodeOf[i] = s.comm[nodeOf[i]]
+		}
+		result.addLevel(levelPartition(nodes, nodeOf))
+		if k == cg.len() {
+			break
+		}
+		cg = cg.aggregate(s.comm, k)
+	}
+	result.finish(g, nodes, nodeOf, opts)
+	return result
+}
+
+// identity - разбиение на одиночки 0..n-1
+func identity(n int) []int {
+	comm := make([]int, n)
+	for i := range comm {
+		comm[i] = i
+	}
+	return comm
+}
+
+// levelPartition - разбиение исходных узлов по их узлам уровня
+func levelPartition(nodes, nodeOf []int) *Partition {
+	p := NewPartition(nodeCapacity(nodes))
+	for i, node := range nodes {
+		p.Set(node, nodeOf[i])
+	}
+	p.Relabel()
+	return p
+}
+
+// addLevel запоминает разбиение уровня, если оно не одиночки и не повтор предыдущего
+func (r *MultilevelResult) addLevel(p *Partition) {
+	if p.NumCommunities() == p.Len() {
+		return
+	}
+	if n := len(r.Levels); n > 0 && maps.Equal(r.Levels[n-1].ToMap(), p.ToMap()) {
+		return
+	}
+	r.Levels = append(r.Levels, p)
+}
+
+// finish заполняет итоговое разбиение и его качество. Ориентированный граф оба
+// метода видят через симметризацию (Edges/Weights), поэтому и качество - её,
+// а не модулярность Лейхта–Ньюмана, которую они не оптимизируют
+func (r *MultilevelResult) finish(g *Graph, nodes, nodeOf []int, opts MultilevelOptions) {
+	r.Partition = levelPartition(nodes, nodeOf)
+	gamma := opts.Resolution
+	if gamma == 0 {
+		gamma = 1
+	}
+	model := opts.NullModel
+	if model == "" {
+		model = NullModelNewmanGirvan
+	}
+	r.Quality = SymmetricResolutionQuality(g, r.Partition, gamma, model)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// multilevelMethods - Louvain и Leiden с общей сигнатурой
+var multilevelMethods = []struct {
+	name string
+	run  func(*Graph, MultilevelOptions, *rand.Rand) *MultilevelResult
+}{
+	{"louvain", Louvain},
+	{"leiden", Leiden},
+}
+
+// ringOfCliques - k клик по size узлов, соседние клики соединены одним ребром
+func ringOfCliques(k, size int) *Graph {
+	g := NewGraph()
+	for u := 0; u < k*size; u++ {
+		g.AddNode(u)
+		for v := u + 1; v < (u/size+1)*size; v++ {
+			g.AddEdge(u, v)
+		}
+	}
+	for c := 0; c < k; c++ {
+		g.AddEdge(c*size, ((c+1)%k)*size+1)
+	}
+	return g
+}
+
+// connectedWithin - связен ли каждый класс разбиения как подграф
+func connectedWithin(g *Graph, p *Partition) bool {
+	for _, comm := range p.Communities() {
+		members := p.Members(comm)
+		seen := map[int]bool{members[0]: true}
+		stack := []int{members[0]}
+		for len(stack) > 0 {
+			u := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, v := range g.GetNeighbors(u) {
+				if !seen[v] && p.Community(v) == comm {
+					seen[v] = true
+					stack = append(stack, v)
+				}
+			}
+		}
+		if len(seen) != len(members) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestMultilevelRecoversCliques - кольцо клик разбивается ровно на клики
+func TestMultilevelRecoversCliques(t *testing.T) {
+	g := ringOfCliques(8, 5)
+	for _, m := range multilevelMethods {
+		t.Run(m.name, func(t *testing.T) {
+			result := m.run(g, MultilevelOptions{}, NewRand(1))
+			if nmi := NMI(result.Partition, CavemanCliques(8, 5)); math.Abs(nmi-1) > 1e-12 {
+				t.Errorf("NMI с кликами = %g, разбиение на %d сообществ", nmi, result.Partition.NumCommunities())
+			}
+		})
+	}
+}
+
+// TestMultilevelQuality - отчётное качество совпадает с модулярностью итогового
+// разбиения и не убывает по уровням; уровни Louvain только укрупняются
+// (Leiden может перераспределить узлы уточнённых подгрупп)
+func TestMultilevelQuality(t *testing.T) {
+	karate := LoadKarateClub()
+	for _, m := range multilevelMethods {
+		for seed := int64(1); seed <= 5; seed++ {
+			t.Run(fmt.Sprintf("%s/seed=%d", m.name, seed), func(t *testing.T) {
+				result := m.run(karate, MultilevelOptions{}, NewRand(seed))
+				q := ComputeModularity(karate, result.Partition)
+				if math.Abs(result.Quality-q) > 1e-12 {
+					t.Errorf("Quality = %g, модулярность разбиения %g", result.Quality, q)
+				}
+				prev := ComputeModularity(karate, NewSingletonPartition(karate.NumNodes()))
+				for i, level := range result.Levels {
+					if lq := ComputeModularity(karate, level); lq < prev-1e-12 {
+						t.Errorf("модулярность упала на уровне %d: %g -> %g", i, prev, lq)
+					} else {
+						prev = lq
+					}
+				}
+				if n := len(result.Levels); n > 0 && !maps.Equal(result.Levels[n-1].ToMap(), result.Partition.ToMap()) {
+					t.Error("последний уровень не совпадает с итоговым разбиением")
+				}
+				if m.name != "louvain" {
+					return
+				}
+				for i := 1; i < len(result.Levels); i++ {
+					fine, coarse := result.Levels[i-1], result.Levels[i]
+					for _, node := range fine.Nodes() {
+						for _, other := range fine.Members(fine.Community(node)) {
+							if coarse.Community(other) != coarse.Community(node) {
+								t.Fatalf("уровень %d разделил сообщество уровня %d", i, i-1)
+							}
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestMultilevelDeterministic - одно зерно даёт одно разбиение
+func TestMultilevelDeterministic(t *testing.T) {
+	g, _, err := GenerateFromSpec("lfr:n=300,k=10,maxk=30,mu=0.4,minc=15,maxc=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range multilevelMethods {
+		a := m.run(g, MultilevelOptions{}, NewRand(7))
+		b := m.run(g, MultilevelOptions{}, NewRand(7))
+		if !maps.Equal(a.Partition.ToMap(), b.Partition.ToMap()) || a.Quality != b.Quality {
+			t.Errorf("%s: одинаковое зерно дало разные разбиения", m.name)
+		}
+	}
+}
+
+// TestLeidenConnected - сообщества Leiden связны (Louvain этого не гарантирует)
+func TestLeidenConnected(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		g, _, err := GenerateFromSpec(fmt.Sprintf("lfr:n=200,k=6,maxk=20,mu=0.5,minc=10,maxc=40,seed=%d", seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := Leiden(g, MultilevelOptions{}, NewRand(seed)); !connectedWithin(g, result.Partition) {
+			t.Errorf("зерно %d: несвязное сообщество Leiden", seed)
+		}
+	}
+}
+
+// TestMultilevelCPM - при CPM с γ > 1 на невзвешенном графе слияние любых двух
+// узлов невыгодно, при малом γ компоненты связности сливаются целиком
+func TestMultilevelCPM(t *testing.T) {
+	g := ringOfCliques(4, 4)
+	for _, m := range multilevelMethods {
+		t.Run(m.name, func(t *testing.T) {
+			high := m.run(g, MultilevelOptions{NullModel: NullModelCPM, Resolution: 1.5}, NewRand(1))
+			if k := high.Partition.NumCommunities(); k != g.NumNodes() {
+				t.Errorf("γ = 1.5: %d сообществ, ожидались одиночки (%d)", k, g.NumNodes())
+			}
+			if high.Quality != 0 {
+				t.Errorf("γ = 1.5: качество одиночек %g, ожидался 0", high.Quality)
+			}
+			low := m.run(g, MultilevelOptions{NullModel: NullModelCPM, Resolution: 0.01}, NewRand(1))
+			if k := low.Partition.NumCommunities(); k != 1 {
+				t.Errorf("γ = 0.01: %d сообществ, ожидалось одно", k)
+			}
+			want := SymmetricResolutionQuality(g, low.Partition, 0.01, NullModelCPM)
+			if math.Abs(low.Quality-want) > 1e-12 {
+				t.Errorf("γ = 0.01: Quality = %g, H_CPM = %g", low.Quality, want)
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@
 package main
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
 )
 
 type MLModel struct {
@@ -12,7 +16,14 @@ type MLModel struct {
 	Pin     float64
 	Alpha   float64
 	Beta    float64
-	TargetK int // желаемое число сообществ (-1 = не ограничено)
+	TargetK int        // желаемое число сообществ (-1 = не ограничено)
+	Rng     *rand.Rand // источник случайности сэмплера
+
+	Constraints *Constraints    // жёсткие ограничения на разбиение (nil - нет)
+	Objective   MLObjective     // что сэмплируем и максимизируем ("" - потенциал с Alpha)
+	Cancel      <-chan struct{} // закрытие прерывает отжиг и выбор K (nil - нет)
+
+	adjacency *weightedAdjacency // списки соседей для DC-SBM (строятся при первом вызове)
 }
 
 // NewMLModel создаёт модель без ограничения на число кластеров
@@ -22,33 +33,44 @@ func NewMLModel(g *Graph, alpha, beta float64) *MLModel {
 		Alpha:   alpha,
 		Beta:    beta,
 		TargetK: -1,
+		Rng:     NewRand(ResolveSeed(0)),
 	}
 }
 
-// NewMLModelWithTargetK создаёт модель с желаемым числом сообществ
+// NewMLModelWithTargetK создаёт модель ровно с targetK сообществами
+// (сэмплировать - ConstrainedGibbsSweep от FeasiblePartition)
 func NewMLModelWithTargetK(g *Graph, alpha, beta float64, targetK int) *MLModel {
 	return &MLModel{
-		G:       g,
-		Alpha:   alpha,
-		Beta:    beta,
-		TargetK: targetK,
+		G:           g,
+		Alpha:       alpha,
+		Beta:        beta,
+		TargetK:     targetK,
+		Rng:         NewRand(ResolveSeed(0)),
+		Constraints: &Constraints{ExactK: targetK},
 	}
 }
 
-func (ml *MLModel) ComputeLikelihood(partition map[int]int) float64 {
+// SetSeed задаёт зерно генератора сэмплера
+func (ml *MLModel) SetSeed(seed int64) {
+	ml.Rng = NewRand(seed)
+}
+
+// ComputeLikelihood - логарифм правдоподобия модели с заложенным разбиением
+// (Бернулли: Pin внутри сообществ, Pout между ними) при наилучших Pin/Pout.
+// Считаются рёбра, а не их веса: на взвешенном графе это правдоподобие его
+// структуры, и цели likelihood/em на нём запрещены (CheckGraph)
+func (ml *MLModel) ComputeLikelihood(partition *Partition) float64 {
 	ml.computeOptimalProbs(partition)
 
 	if ml.Pin <= 0 || ml.Pin >= 1 || ml.Pout <= 0 || ml.Pout >= 1 {
 		return math.Inf(-1)
 	}
 
-	comms := make(map[int][]int)
-	for node, comm := range partition {
-		comms[comm] = append(comms[comm], node)
-	}
+	commIDs := partition.Communities()
 
 	mk := make(map[int]int)
-	for comm, nodes := range comms {
+	for _, comm := range commIDs {
+		nodes := partition.Members(comm)
 		count := 0
 		for i, u := range nodes {
 			for _, v := range nodes[i+1:] {
@@ -62,14 +84,16 @@ func (ml *MLModel) ComputeLikelihood(partition map[int]int) float64 {
 
 	ll := 0.0
 
-	for _, m := range mk {
+	for _, comm := range commIDs {
+		m := mk[comm]
 		if m > 0 {
 			ll += float64(m) * math.Log(ml.Pin)
 		}
 	}
 
-	for comm, nodes := range comms {
-		maxEdges := len(nodes) * (len(nodes) - 1) / 2
+	for _, comm := range commIDs {
+		nk := partition.Size(comm)
+		maxEdges := nk * (nk - 1) / 2
 		missingEdges := maxEdges - mk[comm]
 
 		if missingEdges > 0 {
@@ -93,8 +117,8 @@ func (ml *MLModel) ComputeLikelihood(partition map[int]int) float64 {
 	n := float64(ml.G.NumNodes())
 	sumNk2 := 0.0
 
-	for _, nodes := range comms {
-		nk := float64(len(nodes))
+	for _, comm := range commIDs {
+		nk := float64(partition.Size(comm))
 		sumNk2 += nk * nk
 	}
 
@@ -108,22 +132,16 @@ func (ml *MLModel) ComputeLikelihood(partition map[int]int) float64 {
 	return ll
 }
 
-func (ml *MLModel) ComputeObjectiveFunction(partition map[int]int) float64 {
-	comms := make(map[int][]int)
-	for node, comm := range partition {
-		comms[comm] = append(comms[comm], node)
-	}
-
-	var totalInnerEdges int
+func (ml *MLModel) ComputeObjectiveFunction(partition *Partition) float64 {
+	var totalInnerEdges float64 // суммарный вес внутренних рёбер
 	var sumNk2 float64
 
-	for _, nodes := range comms {
-		innerEdges := 0
+	for _, comm := range partition.Communities() {
+		nodes := partition.Members(comm)
+		innerEdges := 0.0
 		for i, u := range nodes {
 			for _, v := range nodes[i+1:] {
-				if ml.G.HasEdge(u, v) {
-					innerEdges++
-				}
+				innerEdges += ml.G.Weight(u, v)
 			}
 		}
 		totalInnerEdges += innerEdges
@@ -131,20 +149,171 @@ func (ml *MLModel) ComputeObjectiveFunction(partition map[int]int) float64 {
 		sumNk2 += nk * nk
 	}
 
-	P := float64(totalInnerEdges) - 0.5*sumNk2*ml.Alpha
+	P := totalInnerEdges - 0.5*sumNk2*ml.Alpha
 	return P
 }
 
-func (ml *MLModel) computeOptimalProbs(partition map[int]int) {
-	comms := make(map[int][]int)
-	for node, comm := range partition {
-		comms[comm] = append(comms[comm], node)
+// ============================================================
+// ПРАВДОПОДОБИЕ МОДЕЛИ С ЗАЛОЖЕННЫМ РАЗБИЕНИЕМ
+// ============================================================
+
+// MLObjective - целевая функция сэмплера и оптимизации
+type MLObjective string
+
+const (
+	// ObjectivePotential - потенциал m_in - ½α·Σn_k² с заданным α (прокси правдоподобия)
+	ObjectivePotential MLObjective = "potential"
+	// ObjectiveLikelihood - профильное правдоподобие: Pin/Pout подбираются
+	// по максимуму для каждого разбиения-кандидата
+	ObjectiveLikelihood MLObjective = "likelihood"
+	// ObjectiveEM - попеременная максимизация: перед каждым проходом Pin/Pout
+	// оцениваются по текущему разбиению, α выводится из них (AlphaFromProbs),
+	// проход сэмплирует правдоподобие при фиксированных Pin/Pout
+	ObjectiveEM MLObjective = "em"
+	// ObjectiveDCSBM - правдоподобие SBM с поправкой на степени (Karrer, Newman 2011):
+	// хабы не выделяются в отдельные сообщества только из-за большой степени
+	ObjectiveDCSBM MLObjective = "dcsbm"
+)
+
+// ParseMLObjective разбирает имя целевой функции ("" - потенциал)
+func ParseMLObjective(name string) (MLObjective, error) {
+	switch objective := MLObjective(name); objective {
+	case "":
+		return ObjectivePotential, nil
+	case ObjectivePotential, ObjectiveLikelihood, ObjectiveEM, ObjectiveDCSBM:
+		return objective, nil
+	}
+	return "", fmt.Errorf("неизвестная целевая функция ML %q", name)
+}
+
+// CheckGraph проверяет, что цель определена на графе. Правдоподобие и EM -
+// модель Бернулли: ребро либо есть, либо нет, поэтому веса рёбер в ней
+// не выражаются, и взвешенный граф отклоняется (взвешенные цели - potential
+// и dcsbm)
+func (o MLObjective) CheckGraph(g *Graph) error {
+	if (o == ObjectiveLikelihood || o == ObjectiveEM) && g.IsWeighted() {
+		return fmt.Errorf("цель ML %s не учитывает веса рёбер: для взвешенного графа используйте potential или dcsbm", o)
+	}
+	return nil
+}
+
+// AlphaFromProbs - α, при котором потенциал m_in - ½α·Σn_k² совпадает
+// с логарифмом правдоподобия при фиксированных Pin, Pout с точностью до
+// множителя и константы:
+// α = log((1-Pout)/(1-Pin)) / log(Pin(1-Pout) / (Pout(1-Pin)))
+// ok = false, если Pin = Pout (разбиение не влияет на правдоподобие)
+func AlphaFromProbs(pin, pout float64) (float64, bool) {
+	logOdds := math.Log(pin * (1 - pout) / (pout * (1 - pin)))
+	if logOdds == 0 || math.IsNaN(logOdds) || math.IsInf(logOdds, 0) {
+		return 0, false
+	}
+	return math.Log((1-pout)/(1-pin)) / logOdds, true
+}
+
+// pairCounts - рёбра и пары узлов внутри сообществ (рёбра без учёта весов, см. CheckGraph)
+func (ml *MLModel) pairCounts(partition *Partition) (edgesIn, pairsIn float64) {
+	for _, comm := range partition.Communities() {
+		nodes := partition.Members(comm)
+		for i, u := range nodes {
+			for _, v := range nodes[i+1:] {
+				if ml.G.HasEdge(u, v) {
+					edgesIn++
+				}
+			}
+		}
+		pairsIn += pairs(len(nodes))
+	}
+	return edgesIn, pairsIn
+}
+
+// bernoulliLogLikelihood - log P(k успехов из n) при вероятности p (0·log 0 = 0)
+func bernoulliLogLikelihood(k, n, p float64) float64 {
+	ll := 0.0
+	if k > 0 {
+		ll += k * math.Log(p)
+	}
+	if n-k > 0 {
+		ll += (n - k) * math.Log(1-p)
+	}
+	return ll
+}
+
+// ProfileLikelihood - логарифм правдоподобия при наилучших для разбиения
+// Pin = m_in / пар_in и Pout = m_out / пар_out (модель не меняется)
+func (ml *MLModel) ProfileLikelihood(partition *Partition) float64 {
+	return ml.profileLikelihood(ml.pairCounts(partition))
+}
+
+// profileLikelihood - ProfileLikelihood по числу рёбер и пар внутри сообществ
+func (ml *MLModel) profileLikelihood(edgesIn, pairsIn float64) float64 {
+	edgesOut := float64(ml.G.NumEdges()) - edgesIn
+	pairsOut := pairs(ml.G.NumNodes()) - pairsIn
+
+	ll := 0.0
+	if pairsIn > 0 {
+		ll += bernoulliLogLikelihood(edgesIn, pairsIn, edgesIn/pairsIn)
+	}
+	if pairsOut > 0 {
+		ll += bernoulliLogLikelihood(edgesOut, pairsOut, edgesOut/pairsOut)
+	}
+	return ll
+}
+
+// FixedLikelihood - логарифм правдоподобия при текущих ml.Pin, ml.Pout
+func (ml *MLModel) FixedLikelihood(partition *Partition) float64 {
+	return ml.fixedLikelihood(ml.pairCounts(partition))
+}
+
+// fixedLikelihood - FixedLikelihood по числу рёбер и пар внутри сообществ
+func (ml *MLModel) fixedLikelihood(edgesIn, pairsIn float64) float64 {
+	edgesOut := float64(ml.G.NumEdges()) - edgesIn
+	pairsOut := pairs(ml.G.NumNodes()) - pairsIn
+	return bernoulliLogLikelihood(edgesIn, pairsIn, ml.Pin) + bernoulliLogLikelihood(edgesOut, pairsOut, ml.Pout)
+}
+
+// FitAlpha - M-шаг: оценивает Pin/Pout по разбиению и выводит из них α
+// (если Pin = Pout, α не меняется)
+func (ml *MLModel) FitAlpha(partition *Partition) float64 {
+	ml.computeOptimalProbs(partition)
+	if alpha, ok := AlphaFromProbs(ml.Pin, ml.Pout); ok {
+		ml.Alpha = alpha
 	}
+	return ml.Alpha
+}
 
+// objective - значение, которое максимизируется (и пишется в историю цели)
+func (ml *MLModel) objective(partition *Partition) float64 {
+	switch ml.Objective {
+	case ObjectiveLikelihood, ObjectiveEM:
+		return ml.ProfileLikelihood(partition)
+	case ObjectiveDCSBM:
+		return ml.DCSBMLogLikelihood(partition)
+	}
+	return ml.ComputeObjectiveFunction(partition)
+}
+
+// energy - логарифм ненормированной вероятности разбиения в сэмплере (до множителя β);
+// сам сэмплер считает её приращения по агрегатам (samplerState.energyChange)
+func (ml *MLModel) energy(partition *Partition) float64 {
+	switch ml.Objective {
+	case ObjectiveLikelihood:
+		return ml.ProfileLikelihood(partition)
+	case ObjectiveEM:
+		return ml.FixedLikelihood(partition)
+	case ObjectiveDCSBM:
+		return ml.DCSBMLogLikelihood(partition)
+	}
+	return ml.ComputeObjectiveFunction(partition)
+}
+
+// computeOptimalProbs - оценки Pin/Pout по разбиению (доли рёбер среди пар,
+// без весов, как ComputeLikelihood)
+func (ml *MLModel) computeOptimalProbs(partition *Partition) {
 	var totalMk int
 	var sumNk2 float64
 
-	for _, nodes := range comms {
+	for _, comm := range partition.Communities() {
+		nodes := partition.Members(comm)
 		mk := 0
 		for i, u := range nodes {
 			for _, v := range nodes[i+1:] {
@@ -195,130 +364,217 @@ func (ml *MLModel) computeOptimalProbs(partition map[int]int) {
 }
 
 type GibbsSamplingResult struct {
-	BestPartition    map[int]int
+	BestPartition    *Partition
 	BestObjective    float64
 	ObjectiveHistory []float64
 	OptimalAlpha     float64
 	OptimalPin       float64
 	OptimalPout      float64
 	NumCommunities   int
-	ConvergedAt      int
+	ConvergedAt      int // проход Гиббса (индекс в ObjectiveHistory) последнего улучшения
 	TotalIterations  int
+
+	LikelihoodHistory []float64 // профильное правдоподобие после каждого прохода (параллельно ObjectiveHistory)
+	AlphaHistory      []float64 // α, выведенное из Pin/Pout (только ObjectiveEM)
+
+	Temperatures []TemperatureStats // статистика по температурам отжига
+	Reheats      int                // выполнено повторных нагревов
+	StopReason   string             // schedule | plateau | stable | cancelled - почему закончился последний проход
 }
 
+// MaximumLikelihoodImproved перебирает α и случайные начальные разбиения,
+// для каждого запуска отжигая по расписанию schedule (прежнее поведение -
+// LegacySchedule) с целевой функцией objective, и возвращает лучший запуск;
+// истории цели и правдоподобия - всех запусков подряд.
+// При ObjectiveEM α из alphaValues - только начальные значения.
+// Запуски (α × инициализация) независимы и идут на пуле из workers воркеров
+// (≤ 0 - по числу процессоров). Зерно каждого запуска заранее берётся из rng,
+// поэтому результат не зависит от числа воркеров
 func MaximumLikelihoodImproved(
 	g *Graph,
 	alphaValues []float64,
-	numIterations int,
-	betaValues []float64,
-	numBetaIterations int,
+	schedule AnnealingSchedule,
+	objective MLObjective,
 	numInitializations int,
-	burnInIterations int,
-) *GibbsSamplingResult {
-	bestPartition := make(map[int]int)
-	bestObjective := math.Inf(-1)
-	bestAlpha := 0.0
-	bestPin := 0.5
-	bestPout := 0.5
-	bestConvergedAt := 0
-	bestTotalIterations := 0
-	objectiveHistory := make([]float64, 0)
+	rng *rand.Rand,
+	workers int,
+) (*GibbsSamplingResult, error) {
+	return MaximumLikelihoodImprovedCheckpointed(g, alphaValues, schedule, objective, numInitializations, rng, workers, MLCheckpointOptions{})
+}
+
+// MaximumLikelihoodImprovedCheckpointed - MaximumLikelihoodImproved с контрольными
+// точками в opts.Path; прерванный перебор продолжает ResumeMaximumLikelihoodImproved
+func MaximumLikelihoodImprovedCheckpointed(
+	g *Graph,
+	alphaValues []float64,
+	schedule AnnealingSchedule,
+	objective MLObjective,
+	numInitializations int,
+	rng *rand.Rand,
+	workers int,
+	opts MLCheckpointOptions,
+) (*GibbsSamplingResult, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	if err := objective.CheckGraph(g); err != nil {
+		return nil, err
+	}
+	if rng == nil {
+		rng = NewRand(ResolveSeed(0))
+	}
 
+	cp := &MLCheckpoint{
+		NumNodes:           g.NumNodes(),
+		NumEdges:           g.NumEdges(),
+		TotalWeight:        g.TotalWeight(),
+		Alphas:             alphaValues,
+		NumInitializations: numInitializations,
+		Schedule:           schedule,
+		Objective:          objective,
+	}
 	for _, alpha := range alphaValues {
 		for init := 0; init < numInitializations; init++ {
-			partition := initializeRandomPartition(g, rand.Intn(5)+3)
-			ml := NewMLModel(g, alpha, 0.0)
-
-			currentBest := math.Inf(-1)
-			currentBestPartition := copyPartition(partition)
-			convergedAt := 0
+			cp.Runs = append(cp.Runs, MLRunCheckpoint{Alpha: alpha, Seed: rng.Int63()})
+		}
+	}
+	return runMaximumLikelihood(g, cp, workers, opts)
+}
 
-			for _, beta := range betaValues {
-				ml.Beta = beta
+// runMaximumLikelihood доделывает незаконченные запуски контрольной точки cp
+// и сворачивает результаты всех запусков
+func runMaximumLikelihood(g *Graph, cp *MLCheckpoint, workers int, opts MLCheckpointOptions) (*GibbsSamplingResult, error) {
+	saver := newCheckpointSaver(cp, opts)
+	var pending []int
+	for i, run := range cp.Runs {
+		if run.Result == nil {
+			pending = append(pending, i)
+		}
+	}
 
-				for iter := 0; iter < burnInIterations; iter++ {
-					for _, node := range g.GetNodeList() {
-						partition[node] = selectNewCommunityImproved(ml, node, partition)
-					}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(pending)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res, err := cp.annealRun(g, i, saver)
+				if err != nil {
+					saver.fail(err)
+					continue
 				}
+				saver.finish(i, res)
+			}
+		}()
+	}
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if saver.err != nil {
+		return nil, saver.err
+	}
 
-				changeCount := 0
-
-				for iter := 0; iter < numBetaIterations; iter++ {
-					for _, node := range g.GetNodeList() {
-						partition[node] = selectNewCommunityImproved(ml, node, partition)
-					}
+	best := &GibbsSamplingResult{
+		BestPartition: NewPartition(g.NumNodes()),
+		BestObjective: math.Inf(-1),
+		OptimalPin:    0.5,
+		OptimalPout:   0.5,
+	}
+	objectiveHistory := make([]float64, 0)
+	var likelihoodHistory, alphaHistory []float64
+
+	// свёртка в исходном порядке запусков: при равенстве побеждает более ранний
+	for _, run := range cp.Runs {
+		offset := len(objectiveHistory)
+		objectiveHistory = append(objectiveHistory, run.Result.ObjectiveHistory...)
+		likelihoodHistory = append(likelihoodHistory, run.Result.LikelihoodHistory...)
+		alphaHistory = append(alphaHistory, run.Result.AlphaHistory...)
+		if run.Result.BestObjective > best.BestObjective {
+			best = run.Result
+			best.ConvergedAt += offset
+			best.TotalIterations = len(objectiveHistory)
+		}
+	}
+	best.ObjectiveHistory = objectiveHistory
+	best.LikelihoodHistory = likelihoodHistory
+	best.AlphaHistory = alphaHistory
+	best.NumCommunities = best.BestPartition.NumCommunities()
+	return best, nil
+}
 
-					objective := ml.ComputeObjectiveFunction(partition)
-					objectiveHistory = append(objectiveHistory, objective)
+// ConstrainedGibbsSweep - проход Гиббса по блокам обязательных связей ml.Constraints:
+// блок сэмплирует сообщество только среди допустимых переходов,
+// поэтому допустимое разбиение остаётся допустимым
+func (ml *MLModel) ConstrainedGibbsSweep(partition *Partition) error {
+	ci, err := ml.Constraints.compile(ml.G)
+	if err != nil {
+		return err
+	}
+	st := ml.newSamplerState(partition)
 
-					if objective > currentBest {
-						currentBest = objective
-						currentBestPartition = copyPartition(partition)
-						changeCount = 0
-						convergedAt = iter
-					} else {
-						changeCount++
-					}
+	for _, rep := range ci.order {
+		block := ci.blocks[rep]
+		from := partition.Community(rep)
 
-					if changeCount > 5 && iter > 10 {
-						break
-					}
+		commsToTry := map[int]bool{from: true}
+		for _, node := range block {
+			for neighbor := range ml.G.Edges[node] {
+				if comm := partition.Community(neighbor); ci.canMove(partition, block, comm) {
+					commsToTry[comm] = true
 				}
 			}
-
-			if currentBest > bestObjective {
-				bestObjective = currentBest
-				bestPartition = copyPartition(currentBestPartition)
-				bestAlpha = alpha
-				bestConvergedAt = convergedAt
-				bestTotalIterations = len(objectiveHistory)
-
-				ml.ComputeLikelihood(bestPartition)
-				bestPin = ml.Pin
-				bestPout = ml.Pout
+		}
+		if ml.Rng.Float64() < 0.15 {
+			if fresh := partition.MaxCommunity() + 1; ci.canMove(partition, block, fresh) {
+				commsToTry[fresh] = true
 			}
 		}
-	}
 
-	return &GibbsSamplingResult{
-		BestPartition:    bestPartition,
-		BestObjective:    bestObjective,
-		ObjectiveHistory: objectiveHistory,
-		OptimalAlpha:     bestAlpha,
-		OptimalPin:       bestPin,
-		OptimalPout:      bestPout,
-		NumCommunities:   NumCommunities(bestPartition),
-		ConvergedAt:      bestConvergedAt,
-		TotalIterations:  bestTotalIterations,
+		links := st.blockLinks(block)
+		st.moveBlock(block, links, sampleBlockCommunity(ml, st, block, links, commsToTry))
 	}
+	return nil
 }
 
-// Оптимизация с учетом целевого числа кластеров
-func selectNewCommunityImprovedWithTarget(ml *MLModel, node int, partition map[int]int) int {
-	oldComm := partition[node]
+// selectNewCommunityImproved сэмплирует новое сообщество узла (не переносит его)
+func selectNewCommunityImproved(ml *MLModel, st *samplerState, node int, links blockLinks) int {
+	partition := st.partition
+	oldComm := partition.Community(node)
 	commsToTry := make(map[int]bool)
 	commsToTry[oldComm] = true
 
 	for neighbor := range ml.G.Edges[node] {
-		commsToTry[partition[neighbor]] = true
+		commsToTry[partition.Community(neighbor)] = true
 	}
 
-	// Вероятность создать новую коммьюнити
-	currentK := NumCommunities(partition)
-	canCreateNew := ml.TargetK < 0 || currentK < ml.TargetK
-
-	if canCreateNew && rand.Float64() < 0.15 {
-		newComm := rand.Intn(currentK + 3)
+	if ml.Rng.Float64() < 0.15 {
+		newComm := ml.Rng.Intn(partition.NumCommunities() + 3)
 		commsToTry[newComm] = true
 	}
 
+	return sampleBlockCommunity(ml, st, []int{node}, links, commsToTry)
+}
+
+// sampleBlockCommunity выбирает сообщество для блока узлов, переходящих вместе
+// (links - его связи, см. samplerState.blockLinks), с вероятностью ∝ exp(β·ΔE).
+// ΔE считается по агрегатам st за O(1) на кандидата (для DC-SBM - за число
+// соседних сообществ блока). Кандидаты перебираются по возрастанию номера,
+// чтобы выбор зависел только от ГСЧ
+func sampleBlockCommunity(ml *MLModel, st *samplerState, block []int, links blockLinks, commsToTry map[int]bool) int {
+	oldComm := st.partition.Community(block[0])
+	candidates := SortedCommunityIDs(commsToTry)
+
 	energies := make(map[int]float64)
 	var maxEnergy float64 = math.Inf(-1)
 
-	for comm := range commsToTry {
-		partition[node] = comm
-		energy := ml.ComputeObjectiveFunction(partition)
+	for _, comm := range candidates {
+		energy := st.energyChange(len(block), links, oldComm, comm)
 		energies[comm] = energy
 
 		if energy > maxEnergy {
@@ -326,13 +582,11 @@ func selectNewCommunityImprovedWithTarget(ml *MLModel, node int, partition map[i
 		}
 	}
 
-	partition[node] = oldComm
-
 	probabilities := make(map[int]float64)
 	var sumProb float64
 
-	for comm, energy := range energies {
-		expVal := math.Exp(ml.Beta * (energy - maxEnergy))
+	for _, comm := range candidates {
+		expVal := math.Exp(ml.Beta * (energies[comm] - maxEnergy))
 		probabilities[comm] = expVal
 		sumProb += expVal
 	}
@@ -343,11 +597,11 @@ func selectNewCommunityImprovedWithTarget(ml *MLModel, node int, partition map[i
 		}
 	}
 
-	r := rand.Float64()
+	r := ml.Rng.Float64()
 	cumulative := 0.0
 
-	for comm, prob := range probabilities {
-		cumulative += prob
+	for _, comm := range candidates {
+		cumulative += probabilities[comm]
 		if r < cumulative {
 			return comm
 		}
@@ -356,75 +610,420 @@ func selectNewCommunityImprovedWithTarget(ml *MLModel, node int, partition map[i
 	return oldComm
 }
 
-func selectNewCommunityImproved(ml *MLModel, node int, partition map[int]int) int {
-	oldComm := partition[node]
-	commsToTry := make(map[int]bool)
-	commsToTry[oldComm] = true
+// ============================================================
+// АГРЕГАТЫ ПРОХОДА ГИББСА
+// ============================================================
+
+// samplerState - агрегаты разбиения, по которым сэмплер считает приращение
+// энергии перехода без полного пересчёта цели. Строятся в начале прохода
+// за O(n + m) и поддерживаются moveBlock
+type samplerState struct {
+	ml        *MLModel
+	partition *Partition
+	weightIn  float64 // вес рёбер внутри сообществ (потенциал)
+	edgesIn   float64 // число рёбер внутри сообществ (правдоподобие, без весов)
+	pairsIn   float64 // число пар узлов внутри сообществ
+	sumNk2    float64 // Σ n_k²
+
+	// Только для ObjectiveDCSBM (обозначения DCSBMLogLikelihood)
+	blockEdges map[[2]int]float64 // e_rs, хранятся обе пары (r, s) и (s, r)
+	kappa      map[int]float64    // κ_r
+}
 
-	for neighbor := range ml.G.Edges[node] {
-		commsToTry[partition[neighbor]] = true
+// blockLinks - связи блока узлов с сообществами (рёбра внутри блока не входят)
+type blockLinks struct {
+	weight   map[int]float64 // суммарный вес рёбер до сообщества
+	count    map[int]float64 // число рёбер до сообщества
+	internal float64         // e блока с самим собой (каждое ребро дважды, петля - удвоенным весом)
+	degree   float64         // сумма степеней узлов блока (петля - дважды)
+}
+
+func (ml *MLModel) newSamplerState(partition *Partition) *samplerState {
+	st := &samplerState{ml: ml, partition: partition}
+	for _, comm := range partition.Communities() {
+		nk := partition.Size(comm)
+		st.pairsIn += pairs(nk)
+		st.sumNk2 += float64(nk * nk)
+	}
+	if ml.Objective == ObjectiveDCSBM {
+		st.blockEdges = make(map[[2]int]float64)
+		st.kappa = make(map[int]float64)
 	}
+	for _, u := range ml.G.GetNodeList() {
+		cu := partition.Community(u)
+		for _, v := range ml.G.GetNeighbors(u) {
+			w, cv := ml.G.Weight(u, v), partition.Community(v)
+			if u < v && cu == cv {
+				st.weightIn += w
+				st.edgesIn++
+			}
+			if st.blockEdges != nil {
+				if u == v {
+					w *= 2 // петля входит в степень дважды
+				}
+				st.blockEdges[[2]int{cu, cv}] += w
+				st.kappa[cu] += w
+			}
+		}
+	}
+	return st
+}
 
-	if rand.Float64() < 0.15 {
-		newComm := rand.Intn(NumCommunities(partition) + 3)
-		commsToTry[newComm] = true
+// blockLinks считает связи блока с сообществами за O(deg) (соседи - по возрастанию,
+// чтобы суммы не зависели от порядка обхода map)
+func (st *samplerState) blockLinks(block []int) blockLinks {
+	links := blockLinks{weight: make(map[int]float64), count: make(map[int]float64)}
+	inBlock := func(v int) bool { return len(block) > 1 && slices.Contains(block, v) }
+	for _, u := range block {
+		for _, v := range st.ml.G.GetNeighbors(u) {
+			w := st.ml.G.Weight(u, v)
+			switch {
+			case u == v:
+				links.internal += 2 * w
+				links.degree += 2 * w
+				continue
+			case inBlock(v):
+				links.internal += w
+			default:
+				comm := st.partition.Community(v)
+				links.weight[comm] += w
+				links.count[comm]++
+			}
+			links.degree += w
+		}
 	}
+	return links
+}
 
-	energies := make(map[int]float64)
-	var maxEnergy float64 = math.Inf(-1)
+// energyChange - приращение энергии (ml.energy) при переходе блока из size узлов
+// из сообщества from в to
+func (st *samplerState) energyChange(size int, links blockLinks, from, to int) float64 {
+	if from == to {
+		return 0
+	}
+	if st.blockEdges != nil {
+		return st.dcsbmChange(links, from, to)
+	}
+	nFrom, nTo := st.partition.Size(from), st.partition.Size(to)
+	weightIn := st.weightIn + links.weight[to] - links.weight[from]
+	edgesIn := st.edgesIn + links.count[to] - links.count[from]
+	pairsIn := st.pairsIn + pairs(nFrom-size) + pairs(nTo+size) - pairs(nFrom) - pairs(nTo)
+	sumNk2 := st.sumNk2 + float64((nFrom-size)*(nFrom-size)+(nTo+size)*(nTo+size)-nFrom*nFrom-nTo*nTo)
+	return st.energyOf(weightIn, edgesIn, pairsIn, sumNk2) - st.energyOf(st.weightIn, st.edgesIn, st.pairsIn, st.sumNk2)
+}
 
-	for comm := range commsToTry {
-		partition[node] = comm
-		energy := ml.ComputeObjectiveFunction(partition)
-		energies[comm] = energy
+// energyOf - ml.energy по агрегатам (кроме DC-SBM)
+func (st *samplerState) energyOf(weightIn, edgesIn, pairsIn, sumNk2 float64) float64 {
+	switch st.ml.Objective {
+	case ObjectiveLikelihood:
+		return st.ml.profileLikelihood(edgesIn, pairsIn)
+	case ObjectiveEM:
+		return st.ml.fixedLikelihood(edgesIn, pairsIn)
+	}
+	return weightIn - 0.5*sumNk2*st.ml.Alpha
+}
 
-		if energy > maxEnergy {
-			maxEnergy = energy
+// dcsbmChange - приращение DCSBMLogLikelihood при переходе блока из a в c.
+// L = ½·Σ_rs h(e_rs) - Σ_r h(κ_r) + const, h(x) = x·log x; меняются только
+// строки a и c: e_ax -= l_x, e_cx += l_x, e_aa -= 2l_a + l_B, e_cc += 2l_c + l_B,
+// e_ac += l_a - l_c, κ_a -= d_B, κ_c += d_B
+func (st *samplerState) dcsbmChange(links blockLinks, a, c int) float64 {
+	e := func(r, s int) float64 { return st.blockEdges[[2]int{r, s}] }
+	la, lc := links.weight[a], links.weight[c]
+
+	delta := 0.0
+	for _, x := range SortedCommunityIDs(links.weight) {
+		if x == a || x == c {
+			continue
 		}
+		lx := links.weight[x]
+		delta += xlogx(e(a, x)-lx) - xlogx(e(a, x)) + xlogx(e(c, x)+lx) - xlogx(e(c, x))
 	}
+	delta += 0.5 * (xlogx(e(a, a)-2*la-links.internal) - xlogx(e(a, a)) +
+		xlogx(e(c, c)+2*lc+links.internal) - xlogx(e(c, c)))
+	delta += xlogx(e(a, c)+la-lc) - xlogx(e(a, c))
+	delta -= xlogx(st.kappa[a]-links.degree) - xlogx(st.kappa[a]) +
+		xlogx(st.kappa[c]+links.degree) - xlogx(st.kappa[c])
+	return delta
+}
 
-	partition[node] = oldComm
+// xlogx - x·log x (0 при x ≤ 0: остаток округления пустой строки)
+func xlogx(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return x * math.Log(x)
+}
 
-	probabilities := make(map[int]float64)
-	var sumProb float64
+// moveBlock переносит блок в сообщество to и обновляет агрегаты
+func (st *samplerState) moveBlock(block []int, links blockLinks, to int) {
+	from := st.partition.Community(block[0])
+	if from == to {
+		return
+	}
+	size := len(block)
+	nFrom, nTo := st.partition.Size(from), st.partition.Size(to)
+	st.weightIn += links.weight[to] - links.weight[from]
+	st.edgesIn += links.count[to] - links.count[from]
+	st.pairsIn += pairs(nFrom-size) + pairs(nTo+size) - pairs(nFrom) - pairs(nTo)
+	st.sumNk2 += float64((nFrom-size)*(nFrom-size) + (nTo+size)*(nTo+size) - nFrom*nFrom - nTo*nTo)
+
+	if st.blockEdges != nil {
+		add := func(r, s int, w float64) {
+			st.blockEdges[[2]int{r, s}] += w
+			if r != s {
+				st.blockEdges[[2]int{s, r}] += w
+			}
+		}
+		la, lc := links.weight[from], links.weight[to]
+		for x, lx := range links.weight {
+			if x != from && x != to {
+				add(from, x, -lx)
+				add(to, x, lx)
+			}
+		}
+		add(from, from, -2*la-links.internal)
+		add(to, to, 2*lc+links.internal)
+		add(from, to, la-lc)
+		st.kappa[from] -= links.degree
+		st.kappa[to] += links.degree
+	}
 
-	for comm, energy := range energies {
-		expVal := math.Exp(ml.Beta * (energy - maxEnergy))
-		probabilities[comm] = expVal
-		sumProb += expVal
+	for _, node := range block {
+		st.partition.Set(node, to)
 	}
+}
 
-	if sumProb > 0 {
-		for comm := range probabilities {
-			probabilities[comm] /= sumProb
+func initializeRandomPartition(g *Graph, numComms int, rng *rand.Rand) *Partition {
+	partition := NewPartition(g.NumNodes())
+	for _, node := range g.GetNodeList() {
+		partition.Set(node, rng.Intn(numComms))
+	}
+	return partition
+}
+
+// ============================================================
+// SBM С ПОПРАВКОЙ НА СТЕПЕНИ И ВЫБОР ЧИСЛА СООБЩЕСТВ
+// ============================================================
+
+// DCSBMLogLikelihood - логарифм правдоподобия пуассоновской DC-SBM
+// при оптимальных параметрах (Karrer, Newman 2011):
+// L = ½·Σ_rs e_rs·log(e_rs / (κ_r·κ_s)) + Σ_i k_i·log k_i - m,
+// e_rs - вес рёбер между сообществами (e_rr - удвоенный вес внутри), κ_r - сумма степеней.
+// Слагаемые, зависящие только от графа, оставлены, чтобы L годилась для BIC
+func (ml *MLModel) DCSBMLogLikelihood(partition *Partition) float64 {
+	if ml.adjacency == nil {
+		ml.adjacency = newWeightedAdjacency(ml.G)
+	}
+	adj := ml.adjacency
+
+	blockEdges := make(map[[2]int]float64)
+	kappa := make(map[int]float64)
+	for i, u := range adj.nodes {
+		cu := partition.Community(u)
+		for j, v := range adj.neighbors[i] {
+			blockEdges[[2]int{cu, partition.Community(v)}] += adj.weights[i][j]
 		}
+		kappa[cu] += adj.degree[i]
 	}
 
-	r := rand.Float64()
-	cumulative := 0.0
+	keys := make([][2]int, 0, len(blockEdges))
+	for key := range blockEdges {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	ll := adj.constant
+	for _, key := range keys {
+		e := blockEdges[key]
+		ll += 0.5 * e * math.Log(e/(kappa[key[0]]*kappa[key[1]]))
+	}
+	return ll
+}
 
-	for comm, prob := range probabilities {
-		cumulative += prob
-		if r < cumulative {
-			return comm
+// weightedAdjacency - снимок графа для многократного подсчёта DC-SBM
+type weightedAdjacency struct {
+	nodes     []int
+	neighbors [][]int
+	weights   [][]float64 // петля - с удвоенным весом
+	degree    []float64
+	constant  float64 // Σ_i k_i·log k_i - m
+}
+
+func newWeightedAdjacency(g *Graph) *weightedAdjacency {
+	adj := &weightedAdjacency{nodes: g.GetNodeList()}
+	total := 0.0
+	for _, u := range adj.nodes {
+		neighbors := g.GetNeighbors(u)
+		weights := make([]float64, len(neighbors))
+		degree := 0.0
+		for j, v := range neighbors {
+			weights[j] = g.Weight(u, v)
+			if v == u {
+				weights[j] *= 2 // петля входит в степень дважды
+			}
+			degree += weights[j]
 		}
+		adj.neighbors = append(adj.neighbors, neighbors)
+		adj.weights = append(adj.weights, weights)
+		adj.degree = append(adj.degree, degree)
+		if degree > 0 {
+			adj.constant += degree * math.Log(degree)
+		}
+		total += degree
 	}
+	adj.constant -= total / 2
+	return adj
+}
 
-	return oldComm
+// DCSBMParameters - число свободных параметров DC-SBM с K сообществами:
+// K(K+1)/2 блочных интенсивностей и n - K степеней (θ нормированы в каждом сообществе)
+func DCSBMParameters(n, k int) int {
+	return k*(k+1)/2 + n - k
 }
 
-func initializeRandomPartition(g *Graph, numComms int) map[int]int {
-	partition := make(map[int]int)
-	for node := range g.Nodes {
-		partition[node] = rand.Intn(numComms)
+// GreedySweep - жадный проход: каждый узел переходит в сообщество соседа
+// (или, если fixedK = false, в новое), дающее наибольший рост ml.objective.
+// При fixedK число сообществ не меняется. Прочие ограничения ml.Constraints
+// не учитываются. Возвращает число переходов
+func (ml *MLModel) GreedySweep(partition *Partition, fixedK bool) int {
+	moves := 0
+	for _, node := range ml.G.GetNodeList() {
+		from := partition.Community(node)
+		if fixedK && partition.Size(from) == 1 {
+			continue
+		}
+		commsToTry := map[int]bool{}
+		for neighbor := range ml.G.Edges[node] {
+			commsToTry[partition.Community(neighbor)] = true
+		}
+		if !fixedK {
+			commsToTry[partition.MaxCommunity()+1] = true
+		}
+		delete(commsToTry, from)
+
+		best, bestValue := from, ml.objective(partition)
+		for _, comm := range SortedCommunityIDs(commsToTry) {
+			partition.Set(node, comm)
+			if value := ml.objective(partition); value > bestValue+1e-12 {
+				best, bestValue = comm, value
+			}
+		}
+		partition.Set(node, best)
+		if best != from {
+			moves++
+		}
 	}
-	return partition
+	return moves
 }
 
-func copyPartition(partition map[int]int) map[int]int {
-	res := make(map[int]int, len(partition))
-	for k, v := range partition {
-		res[k] = v
+// GreedyOptimize повторяет GreedySweep до неподвижной точки (не больше maxSweeps раз)
+// Возвращает число выполненных проходов
+func (ml *MLModel) GreedyOptimize(partition *Partition, fixedK bool, maxSweeps int) int {
+	for sweep := 1; sweep <= maxSweeps && !isCancelled(ml.Cancel); sweep++ {
+		if ml.GreedySweep(partition, fixedK) == 0 {
+			return sweep
+		}
+	}
+	return maxSweeps
+}
+
+// ModelScore - качество лучшего найденного разбиения с K сообществами
+type ModelScore struct {
+	K             int
+	LogLikelihood float64
+	Parameters    int
+	BIC           float64 // k·ln(n(n-1)/2) - 2L
+	MDL           float64 // длина описания, наты
+	Partition     *Partition
+}
+
+// ModelSelection - результат перебора K
+type ModelSelection struct {
+	Criterion string // bic | mdl
+	Best      int    // индекс лучшего K в Scores
+	Scores    []ModelScore
+}
+
+// BestScore - выбранная модель
+func (sel *ModelSelection) BestScore() ModelScore {
+	return sel.Scores[sel.Best]
+}
+
+// dcsbmDescriptionLength - приближённая длина описания (Peixoto 2013):
+// -L + m·h(K(K+1)/(2m)) + n·ln K, h(x) = (1+x)ln(1+x) - x·ln x
+func dcsbmDescriptionLength(ll float64, n, k int, m float64) float64 {
+	dl := -ll + float64(n)*math.Log(float64(k))
+	if m > 0 {
+		x := float64(k*(k+1)) / (2 * m)
+		dl += m * ((1+x)*math.Log1p(x) - x*math.Log(x))
+	}
+	return dl
+}
+
+// SelectNumCommunities подбирает число сообществ для DC-SBM: для каждого K из
+// [kMin, kMax] отжиг по schedule с ограничением ровно K сообществ, затем жадная
+// доводка; выбирается K с наименьшим BIC или длиной описания (criterion = bic | mdl)
+func (ml *MLModel) SelectNumCommunities(kMin, kMax int, criterion string, schedule AnnealingSchedule) (*ModelSelection, error) {
+	if criterion != "bic" && criterion != "mdl" {
+		return nil, fmt.Errorf("неизвестный критерий выбора модели %q (bic | mdl)", criterion)
+	}
+	n := ml.G.NumNodes()
+	kMin, kMax = max(kMin, 1), min(kMax, n)
+	if kMin > kMax {
+		return nil, fmt.Errorf("пустой диапазон числа сообществ [%d, %d]", kMin, kMax)
+	}
+
+	objective, constraints := ml.Objective, ml.Constraints
+	defer func() { ml.Objective, ml.Constraints = objective, constraints }()
+	ml.Objective = ObjectiveDCSBM
+
+	sel := &ModelSelection{Criterion: criterion}
+	m := ml.G.TotalWeight()
+	pairCount := pairs(n)
+	for k := kMin; k <= kMax && !isCancelled(ml.Cancel); k++ {
+		c := Constraints{ExactK: k}
+		if constraints != nil {
+			c = *constraints
+			c.ExactK = k
+		}
+		ml.Constraints = &c
+
+		partition, err := FeasiblePartition(ml.G, ml.Constraints, ml.Rng)
+		if err != nil {
+			return nil, err
+		}
+		res, err := ml.Anneal(partition, schedule)
+		if err != nil {
+			return nil, err
+		}
+		partition = res.BestPartition
+		if constraints.IsZero() {
+			ml.GreedyOptimize(partition, true, 100)
+		}
+
+		ll := ml.DCSBMLogLikelihood(partition)
+		params := DCSBMParameters(n, k)
+		score := ModelScore{
+			K:             k,
+			LogLikelihood: ll,
+			Parameters:    params,
+			BIC:           float64(params)*math.Log(math.Max(pairCount, 1)) - 2*ll,
+			MDL:           dcsbmDescriptionLength(ll, n, k, m),
+			Partition:     partition,
+		}
+		sel.Scores = append(sel.Scores, score)
+		if criterion == "bic" && score.BIC < sel.BestScore().BIC ||
+			criterion == "mdl" && score.MDL < sel.BestScore().MDL {
+			sel.Best = len(sel.Scores) - 1
+		}
+	}
+	if isCancelled(ml.Cancel) {
+		return nil, ErrCancelled
 	}
-	return res
+	return sel, nil
 }
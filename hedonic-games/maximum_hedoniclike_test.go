@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSamplerEnergyChange - приращение энергии по агрегатам прохода Гиббса равно
+// разности полной ml.energy до и после переноса блока, агрегаты после переносов
+// совпадают с пересборкой
+func TestSamplerEnergyChange(t *testing.T) {
+	tests := []struct {
+		name      string
+		graph     *Graph
+		objective MLObjective
+	}{
+		{"potential", weightedKarate(), ObjectivePotential},
+		{"likelihood", LoadKarateClub(), ObjectiveLikelihood},
+		{"em", LoadKarateClub(), ObjectiveEM},
+		{"dcsbm", weightedKarate(), ObjectiveDCSBM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := NewRand(1)
+			ml := NewMLModel(tt.graph, 0.3, 1)
+			ml.Objective = tt.objective
+			partition := initializeRandomPartition(tt.graph, 4, rng)
+			if tt.objective == ObjectiveEM {
+				ml.FitAlpha(partition)
+			}
+			st := ml.newSamplerState(partition)
+
+			nodes := tt.graph.GetNodeList()
+			for move := 0; move < 300; move++ {
+				node := nodes[rng.Intn(len(nodes))]
+				from := partition.Community(node)
+				// блок - до трёх узлов одного сообщества
+				members := partition.SortedMembers(from)
+				block := members[:1+rng.Intn(min(3, len(members)))]
+				to := rng.Intn(7) // в том числе пустые сообщества
+
+				links := st.blockLinks(block)
+				before := ml.energy(partition)
+				change := st.energyChange(len(block), links, from, to)
+				st.moveBlock(block, links, to)
+				after := ml.energy(partition)
+
+				if math.Abs(after-before-change) > 1e-9*math.Max(1, math.Abs(before)) {
+					t.Fatalf("ход %d: блок %v из %d в %d: приращение %g, разность энергий %g",
+						move, block, from, to, change, after-before)
+				}
+			}
+
+			fresh := ml.newSamplerState(partition)
+			near := func(name string, got, want float64) {
+				if math.Abs(got-want) > 1e-9 {
+					t.Errorf("%s = %g, после пересборки %g", name, got, want)
+				}
+			}
+			near("weightIn", st.weightIn, fresh.weightIn)
+			near("edgesIn", st.edgesIn, fresh.edgesIn)
+			near("pairsIn", st.pairsIn, fresh.pairsIn)
+			near("sumNk2", st.sumNk2, fresh.sumNk2)
+			for key, e := range fresh.blockEdges {
+				near("e", st.blockEdges[key], e)
+			}
+			for comm, k := range fresh.kappa {
+				near("kappa", st.kappa[comm], k)
+			}
+		})
+	}
+}
+
+// pairwiseLikelihood - логарифм правдоподобия прямой суммой по всем парам узлов:
+// ребро внутри сообщества с вероятностью pIn, между сообществами - pOut
+func pairwiseLikelihood(g *Graph, partition *Partition, pIn, pOut float64) float64 {
+	nodes := g.GetNodeList()
+	ll := 0.0
+	for i, u := range nodes {
+		for _, v := range nodes[i+1:] {
+			p := pOut
+			if partition.Community(u) == partition.Community(v) {
+				p = pIn
+			}
+			if g.HasEdge(u, v) {
+				ll += math.Log(p)
+			} else {
+				ll += math.Log(1 - p)
+			}
+		}
+	}
+	return ll
+}
+
+// TestLikelihoodAgainstPairwiseSum - ProfileLikelihood равна прямой сумме по парам
+// при долях рёбер внутри и между сообществами, FixedLikelihood - при ml.Pin, ml.Pout;
+// заложенное разбиение правдоподобнее случайного
+func TestLikelihoodAgainstPairwiseSum(t *testing.T) {
+	g := LoadKarateClub()
+	ml := NewMLModel(g, 0.3, 1)
+	rng := NewRand(2)
+	for trial := 0; trial < 5; trial++ {
+		partition := initializeRandomPartition(g, 2+trial, rng)
+
+		edgesIn, pairsIn := 0.0, 0.0
+		nodes := g.GetNodeList()
+		for i, u := range nodes {
+			for _, v := range nodes[i+1:] {
+				if partition.Community(u) == partition.Community(v) {
+					pairsIn++
+					if g.HasEdge(u, v) {
+						edgesIn++
+					}
+				}
+			}
+		}
+		pairsOut := float64(len(nodes)*(len(nodes)-1)/2) - pairsIn
+		pIn, pOut := edgesIn/pairsIn, (float64(g.NumEdges())-edgesIn)/pairsOut
+
+		if got, want := ml.ProfileLikelihood(partition), pairwiseLikelihood(g, partition, pIn, pOut); math.Abs(got-want) > 1e-9 {
+			t.Errorf("K=%d: ProfileLikelihood = %g, по парам %g", 2+trial, got, want)
+		}
+		ml.Pin, ml.Pout = 0.4, 0.05
+		if got, want := ml.FixedLikelihood(partition), pairwiseLikelihood(g, partition, 0.4, 0.05); math.Abs(got-want) > 1e-9 {
+			t.Errorf("K=%d: FixedLikelihood = %g, по парам %g", 2+trial, got, want)
+		}
+	}
+
+	caveman := LoadCavemanGraph(4, 5)
+	cml := NewMLModel(caveman, 0.3, 1)
+	planted := cml.ProfileLikelihood(CavemanCliques(4, 5))
+	random := cml.ProfileLikelihood(initializeRandomPartition(caveman, 4, rng))
+	if planted <= random {
+		t.Errorf("правдоподобие клик %g не больше случайного разбиения %g", planted, random)
+	}
+}
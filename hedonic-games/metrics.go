@@ -4,41 +4,124 @@ package main
 import (
 	"fmt"
 	"math"
+	"slices"
 )
 
 // ComputeModularity вычисляет модулярность разбиения
 // Q = (1/2m) * Σ(a_ij - (k_i * k_j / 2m)) * δ(c_i, c_j)
-func ComputeModularity(g *Graph, partition map[int]int) float64 {
-	m := float64(g.NumEdges())
+// Для взвешенного графа a_ij - вес ребра, k_i - сила узла, m - суммарный вес.
+// Считается за O(m + n): Q = (1/2m) * Σ_c [2·w_in(c) - D_c²/(2m)], D_c - сумма степеней сообщества
+func ComputeModularity(g *Graph, partition *Partition) float64 {
+	return ComputeModularityResolution(g, partition, 1)
+}
+
+// ComputeModularityResolution - модулярность с разрешением γ (Райхардт–Борнхольдт):
+// Q_γ = (1/2m) * Σ_c [2·w_in(c) - γ·D_c²/(2m)]; γ = 1 - обычная модулярность.
+// Для ориентированного графа - модулярность Лейхта–Ньюмана (см. ComputeDirectedModularity)
+func ComputeModularityResolution(g *Graph, partition *Partition, gamma float64) float64 {
+	if g.Directed {
+		return ComputeDirectedModularity(g, partition, gamma)
+	}
+	return symmetricModularity(g, partition, gamma)
+}
+
+// symmetricModularity - Q_γ по рёбрам Edges/Weights: у ориентированного графа -
+// модулярность его симметризации
+func symmetricModularity(g *Graph, partition *Partition, gamma float64) float64 {
+	inner, total := 0.0, 0.0
+	commDegree := make(map[int]float64, partition.NumCommunities())
+	for _, u := range g.GetNodeList() {
+		cu := partition.Community(u)
+		for _, v := range g.GetNeighbors(u) {
+			w := g.Weight(u, v)
+			if v == u {
+				// петля входит в степень дважды (как в Graph.Degree), а ребро
+				// обходится с обоих концов - её A_uu = 2w учитывается так же
+				w *= 2
+			}
+			if partition.Community(v) == cu {
+				inner += w
+			}
+			commDegree[cu] += w
+			total += w
+		}
+	}
+	m := total / 2
 	if m == 0 {
 		return 0
 	}
 
-	Q := 0.0
-	for u := range g.Nodes {
-		for v := range g.Nodes {
-			if partition[u] == partition[v] {
-				ku := float64(len(g.Edges[u]))
-				kv := float64(len(g.Edges[v]))
-				aij := 0.0
-				if g.Edges[u][v] {
-					aij = 1.0
-				}
+	expected := 0.0
+	for _, comm := range SortedCommunityIDs(commDegree) {
+		expected += commDegree[comm] * commDegree[comm] / (2 * m)
+	}
+
+	return (inner - gamma*expected) / (2 * m)
+}
 
-				Q += aij - (ku*kv)/(2*m)
+// ComputeDirectedModularity - модулярность ориентированного графа (Лейхт, Ньюман):
+// Q = (1/m) * Σ_ij (A_ij - γ·k_i^out·k_j^in/m) δ(c_i, c_j), m - суммарный вес дуг.
+// Считается за O(m + n): Q = (1/m) * Σ_c [a_in(c) - γ·Out_c·In_c/m]
+func ComputeDirectedModularity(g *Graph, partition *Partition, gamma float64) float64 {
+	inner, m := 0.0, 0.0
+	out := make(map[int]float64, partition.NumCommunities())
+	in := make(map[int]float64, partition.NumCommunities())
+	for _, u := range g.GetNodeList() {
+		cu := partition.Community(u)
+		for _, v := range g.OutNeighbors(u) {
+			w := g.ArcWeight(u, v)
+			cv := partition.Community(v)
+			if cv == cu {
+				inner += w
 			}
+			out[cu] += w
+			in[cv] += w
+			m += w
 		}
 	}
+	if m == 0 {
+		return 0
+	}
 
-	return Q / (2 * m)
+	expected := 0.0
+	for _, comm := range SortedCommunityIDs(out) {
+		expected += out[comm] * in[comm] / m
+	}
+
+	return (inner - gamma*expected) / m
 }
 
-// SilhouetteCoefficient вычисляет коэффициент силуэта
-func SilhouetteCoefficient(g *Graph, partition map[int]int) float64 {
-	comms := make(map[int][]int)
-	for node, comm := range partition {
-		comms[comm] = append(comms[comm], node)
+// ComputeCPM - качество модели Поттса с константой (Traag и др.):
+// H = Σ_c [w_in(c) - γ·n_c(n_c-1)/2]. Нулевая модель не зависит от размера графа,
+// поэтому у CPM нет предела разрешения модулярности.
+// В ориентированном графе w_in - вес дуг, пары упорядочены: H = Σ_c [a_in(c) - γ·n_c(n_c-1)]
+func ComputeCPM(g *Graph, partition *Partition, gamma float64) float64 {
+	if g.Directed {
+		gamma *= 2
+	}
+	return symmetricCPM(g, partition, gamma)
+}
+
+// symmetricCPM - H_CPM по рёбрам Edges/Weights с неупорядоченными парами
+func symmetricCPM(g *Graph, partition *Partition, gamma float64) float64 {
+	quality := 0.0
+	for _, u := range g.GetNodeList() {
+		cu := partition.Community(u)
+		for _, v := range g.GetNeighbors(u) {
+			if u < v && partition.Community(v) == cu {
+				quality += g.Weight(u, v)
+			}
+		}
+	}
+	for _, comm := range partition.Communities() {
+		quality -= gamma * pairs(partition.Size(comm))
 	}
+	return quality
+}
+
+// SilhouetteCoefficient вычисляет коэффициент силуэта
+func SilhouetteCoefficient(g *Graph, partition *Partition) float64 {
+	comms := partition.Groups()
 
 	if len(comms) == 1 {
 		return 0
@@ -47,7 +130,8 @@ func SilhouetteCoefficient(g *Graph, partition map[int]int) float64 {
 	totalSilhouette := 0.0
 	count := 0
 
-	for node, nodeComm := range partition {
+	for _, node := range partition.Nodes() {
+		nodeComm := partition.Community(node)
 		nodes := comms[nodeComm]
 
 		a := 0.0
@@ -68,7 +152,8 @@ func SilhouetteCoefficient(g *Graph, partition map[int]int) float64 {
 
 		b := math.MaxFloat64
 
-		for otherComm, otherNodes := range comms {
+		for _, otherComm := range SortedCommunityIDs(comms) {
+			otherNodes := comms[otherComm]
 			if otherComm == nodeComm {
 				continue
 			}
@@ -113,13 +198,11 @@ func SilhouetteCoefficient(g *Graph, partition map[int]int) float64 {
 }
 
 // PrintCommunities печатает разбиение узлов по сообществам
-func PrintCommunities(partition map[int]int) {
-	comms := make(map[int][]int)
-	for node, comm := range partition {
-		comms[comm] = append(comms[comm], node)
-	}
+func PrintCommunities(partition *Partition) {
+	comms := partition.Groups()
 
-	for comm, nodes := range comms {
+	for _, comm := range SortedCommunityIDs(comms) {
+		nodes := comms[comm]
 		fmt.Printf("C%d: ", comm)
 		for i, v := range nodes {
 			if i > 0 {
@@ -131,3 +214,345 @@ func PrintCommunities(partition map[int]int) {
 	}
 	fmt.Println()
 }
+
+// ============================================================
+// СРАВНЕНИЕ С ЭТАЛОННЫМ РАЗБИЕНИЕМ
+// ============================================================
+
+// ComparisonScores - меры согласия найденного разбиения с эталонным
+type ComparisonScores struct {
+	NMI           float64 // нормированная взаимная информация, 2I/(H(X)+H(Y))
+	ARI           float64 // скорректированный индекс Рэнда
+	VI            float64 // вариация информации (в натах, 0 - совпадение)
+	F1            float64 // средняя F1 лучших соответствий сообществ
+	Purity        float64 // чистота: доля узлов в «правильном» эталонном сообществе
+	PairPrecision float64 // доля пар в одном найденном сообществе, которые вместе и в эталоне
+	PairRecall    float64 // доля пар одного эталонного сообщества, найденных вместе
+	Nodes         int     // число узлов, размеченных в обоих разбиениях
+}
+
+// contingencyTable - таблица сопряжённости двух разбиений по общим узлам
+type contingencyTable struct {
+	cells map[[2]int]int // (сообщество found, сообщество truth) -> число узлов
+	rows  map[int]int    // размеры сообществ found
+	cols  map[int]int    // размеры сообществ truth
+	n     int
+}
+
+func newContingencyTable(found, truth *Partition) *contingencyTable {
+	t := &contingencyTable{
+		cells: make(map[[2]int]int),
+		rows:  make(map[int]int),
+		cols:  make(map[int]int),
+	}
+	for _, node := range found.Nodes() {
+		ct := truth.Community(node)
+		if ct == Unassigned {
+			continue
+		}
+		cf := found.Community(node)
+		t.cells[[2]int{cf, ct}]++
+		t.rows[cf]++
+		t.cols[ct]++
+		t.n++
+	}
+	return t
+}
+
+// sortedCells - ключи таблицы в детерминированном порядке (суммы не зависят от обхода map)
+func (t *contingencyTable) sortedCells() [][2]int {
+	keys := make([][2]int, 0, len(t.cells))
+	for key := range t.cells {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	return keys
+}
+
+// entropy - энтропия распределения размеров сообществ
+func (t *contingencyTable) entropy(sizes map[int]int) float64 {
+	h := 0.0
+	for _, comm := range SortedCommunityIDs(sizes) {
+		p := float64(sizes[comm]) / float64(t.n)
+		h -= p * math.Log(p)
+	}
+	return h
+}
+
+// mutualInformation - взаимная информация I(X;Y)
+func (t *contingencyTable) mutualInformation() float64 {
+	n := float64(t.n)
+	mi := 0.0
+	for _, key := range t.sortedCells() {
+		nij := float64(t.cells[key])
+		mi += nij / n * math.Log(nij*n/(float64(t.rows[key[0]])*float64(t.cols[key[1]])))
+	}
+	return math.Max(mi, 0)
+}
+
+// pairs - C(x, 2)
+func pairs(x int) float64 {
+	return float64(x) * float64(x-1) / 2
+}
+
+// pairCounts - пары узлов: вместе в обоих разбиениях, вместе в found, вместе в truth
+func (t *contingencyTable) pairCounts() (both, inFound, inTruth float64) {
+	for _, key := range t.sortedCells() {
+		both += pairs(t.cells[key])
+	}
+	for _, comm := range SortedCommunityIDs(t.rows) {
+		inFound += pairs(t.rows[comm])
+	}
+	for _, comm := range SortedCommunityIDs(t.cols) {
+		inTruth += pairs(t.cols[comm])
+	}
+	return both, inFound, inTruth
+}
+
+// NMI - нормированная взаимная информация (1 - разбиения совпадают)
+// Считается по узлам, размеченным в обоих разбиениях.
+func NMI(found, truth *Partition) float64 {
+	t := newContingencyTable(found, truth)
+	if t.n == 0 {
+		return 0
+	}
+	hx, hy := t.entropy(t.rows), t.entropy(t.cols)
+	if hx+hy == 0 {
+		return 1 // оба разбиения из одного сообщества
+	}
+	return 2 * t.mutualInformation() / (hx + hy)
+}
+
+// AdjustedRandIndex - индекс Рэнда с поправкой на случайное совпадение
+// (1 - совпадение, около 0 - случайное разбиение, может быть отрицательным)
+func AdjustedRandIndex(found, truth *Partition) float64 {
+	t := newContingencyTable(found, truth)
+	if t.n < 2 {
+		return 0
+	}
+	both, inFound, inTruth := t.pairCounts()
+	expected := inFound * inTruth / pairs(t.n)
+	maxIndex := (inFound + inTruth) / 2
+	if maxIndex == expected {
+		return 1 // оба разбиения тривиальны и совпадают
+	}
+	return (both - expected) / (maxIndex - expected)
+}
+
+// VariationOfInformation - VI = H(X) + H(Y) - 2I(X;Y), метрика на разбиениях
+func VariationOfInformation(found, truth *Partition) float64 {
+	t := newContingencyTable(found, truth)
+	if t.n == 0 {
+		return 0
+	}
+	return math.Max(t.entropy(t.rows)+t.entropy(t.cols)-2*t.mutualInformation(), 0)
+}
+
+// Purity - доля узлов, попавших в преобладающее эталонное сообщество своего сообщества
+func Purity(found, truth *Partition) float64 {
+	t := newContingencyTable(found, truth)
+	if t.n == 0 {
+		return 0
+	}
+	best := make(map[int]int, len(t.rows))
+	for _, key := range t.sortedCells() {
+		best[key[0]] = max(best[key[0]], t.cells[key])
+	}
+	total := 0
+	for _, comm := range SortedCommunityIDs(best) {
+		total += best[comm]
+	}
+	return float64(total) / float64(t.n)
+}
+
+// F1Score - симметричная средняя F1: для каждого сообщества берётся лучшее
+// соответствие в другом разбиении, средние по обоим направлениям усредняются
+func F1Score(found, truth *Partition) float64 {
+	t := newContingencyTable(found, truth)
+	if t.n == 0 {
+		return 0
+	}
+	bestFound := make(map[int]float64, len(t.rows))
+	bestTruth := make(map[int]float64, len(t.cols))
+	for _, key := range t.sortedCells() {
+		nij := float64(t.cells[key])
+		f1 := 2 * nij / float64(t.rows[key[0]]+t.cols[key[1]])
+		bestFound[key[0]] = math.Max(bestFound[key[0]], f1)
+		bestTruth[key[1]] = math.Max(bestTruth[key[1]], f1)
+	}
+	mean := func(best map[int]float64) float64 {
+		sum := 0.0
+		for _, comm := range SortedCommunityIDs(best) {
+			sum += best[comm]
+		}
+		return sum / float64(len(best))
+	}
+	return (mean(bestFound) + mean(bestTruth)) / 2
+}
+
+// PairwisePrecisionRecall - точность и полнота на парах узлов:
+// пара «положительна», если узлы в одном сообществе
+func PairwisePrecisionRecall(found, truth *Partition) (precision, recall float64) {
+	t := newContingencyTable(found, truth)
+	both, inFound, inTruth := t.pairCounts()
+	if inFound > 0 {
+		precision = both / inFound
+	}
+	if inTruth > 0 {
+		recall = both / inTruth
+	}
+	return precision, recall
+}
+
+// ComparePartitions считает все меры согласия с эталоном
+func ComparePartitions(found, truth *Partition) ComparisonScores {
+	s := ComparisonScores{
+		NMI:    NMI(found, truth),
+		ARI:    AdjustedRandIndex(found, truth),
+		VI:     VariationOfInformation(found, truth),
+		F1:     F1Score(found, truth),
+		Purity: Purity(found, truth),
+		Nodes:  newContingencyTable(found, truth).n,
+	}
+	s.PairPrecision, s.PairRecall = PairwisePrecisionRecall(found, truth)
+	return s
+}
+
+func (s ComparisonScores) String() string {
+	return fmt.Sprintf("NMI=%.4f ARI=%.4f VI=%.4f F1=%.4f purity=%.4f pair P/R=%.4f/%.4f",
+		s.NMI, s.ARI, s.VI, s.F1, s.Purity, s.PairPrecision, s.PairRecall)
+}
+
+// ============================================================
+// СРАВНЕНИЕ ПЕРЕКРЫВАЮЩИХСЯ ПОКРЫТИЙ
+// ============================================================
+
+// commonCovers - ограничения покрытий на узлы, покрытые в обоих
+func commonCovers(found, truth *Cover) (*Cover, *Cover, []int) {
+	f, t := NewCover(), NewCover()
+	var nodes []int
+	for _, node := range found.Nodes() {
+		if len(truth.Memberships(node)) == 0 {
+			continue
+		}
+		nodes = append(nodes, node)
+		for _, comm := range found.Memberships(node) {
+			f.Add(node, comm)
+		}
+		for _, comm := range truth.Memberships(node) {
+			t.Add(node, comm)
+		}
+	}
+	return f, t, nodes
+}
+
+// binaryEntropy - h(a) + h(n-a) для индикатора сообщества размера a из n узлов
+func binaryEntropy(a, n float64) float64 {
+	return entropyTerm(a, n) + entropyTerm(n-a, n)
+}
+
+// entropyTerm - h(a) = -a/n·log(a/n)
+func entropyTerm(a, n float64) float64 {
+	if a <= 0 {
+		return 0
+	}
+	return -a / n * math.Log(a/n)
+}
+
+// coverConditionalEntropy - H(X|Y) = Σ_i min_j H(X_i|Y_j) (Lancichinetti–Fortunato–Kertész);
+// Y_j учитывается, только если он несёт информацию об X_i: h(a)+h(d) ≥ h(b)+h(c),
+// иначе H(X_i|Y_j) = H(X_i). Возвращает также H(X) = Σ_i H(X_i)
+func coverConditionalEntropy(x, y *Cover, n float64) (conditional, entropy float64) {
+	overlap := make(map[[2]int]float64) // (X_i, Y_j) -> |X_i ∩ Y_j|
+	for _, node := range x.Nodes() {
+		for _, i := range x.Memberships(node) {
+			for _, j := range y.Memberships(node) {
+				overlap[[2]int{i, j}]++
+			}
+		}
+	}
+	for _, i := range x.Communities() {
+		sizeX := float64(x.Size(i))
+		hx := binaryEntropy(sizeX, n)
+		best := hx
+		for _, j := range y.Communities() {
+			sizeY := float64(y.Size(j))
+			d := overlap[[2]int{i, j}]
+			b, c := sizeX-d, sizeY-d
+			a := n - b - c - d
+			if entropyTerm(a, n)+entropyTerm(d, n) < entropyTerm(b, n)+entropyTerm(c, n) {
+				continue
+			}
+			joint := entropyTerm(a, n) + entropyTerm(b, n) + entropyTerm(c, n) + entropyTerm(d, n)
+			best = math.Min(best, joint-binaryEntropy(sizeY, n))
+		}
+		conditional += best
+		entropy += hx
+	}
+	return conditional, entropy
+}
+
+// OverlappingNMI - NMI перекрывающихся покрытий в варианте McDaid, Greene и Hurley (2011):
+// I = [H(X) - H(X|Y) + H(Y) - H(Y|X)]/2, NMI = I/max(H(X), H(Y)).
+// Для разбиений близка к обычной NMI, но не равна ей. Считается по узлам, покрытым в обоих
+func OverlappingNMI(found, truth *Cover) float64 {
+	x, y, nodes := commonCovers(found, truth)
+	if len(nodes) == 0 {
+		return 0
+	}
+	n := float64(len(nodes))
+	hxy, hx := coverConditionalEntropy(x, y, n)
+	hyx, hy := coverConditionalEntropy(y, x, n)
+	if math.Max(hx, hy) == 0 {
+		return 1 // оба покрытия из одного сообщества
+	}
+	mi := (hx - hxy + hy - hyx) / 2
+	return math.Max(mi, 0) / math.Max(hx, hy)
+}
+
+// OmegaIndex - индекс омега (Collins, Dent, 1988): доля пар узлов, состоящих
+// в одинаковом числе общих сообществ в обоих покрытиях, с поправкой на случайное
+// совпадение (1 - совпадение; для разбиений равен ARI)
+func OmegaIndex(found, truth *Cover) float64 {
+	x, y, nodes := commonCovers(found, truth)
+	if len(nodes) < 2 {
+		return 0
+	}
+	shared := func(c *Cover, u, v int) int {
+		count := 0
+		for _, comm := range c.Memberships(u) {
+			if c.Has(v, comm) {
+				count++
+			}
+		}
+		return count
+	}
+
+	agree := 0.0
+	countX, countY := make(map[int]float64), make(map[int]float64) // общих сообществ -> число пар
+	for i, u := range nodes {
+		for _, v := range nodes[i+1:] {
+			tx, ty := shared(x, u, v), shared(y, u, v)
+			countX[tx]++
+			countY[ty]++
+			if tx == ty {
+				agree++
+			}
+		}
+	}
+	total := pairs(len(nodes))
+	observed := agree / total
+	expected := 0.0
+	for _, j := range SortedCommunityIDs(countX) {
+		expected += countX[j] * countY[j] / (total * total)
+	}
+	if expected == 1 {
+		return 1
+	}
+	return (observed - expected) / (1 - expected)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestComparisonScoresKnownValues - меры согласия на разбиениях со значениями, посчитанными вручную
+func TestComparisonScoresKnownValues(t *testing.T) {
+	ln2, ln3 := math.Log(2), math.Log(3)
+	tests := []struct {
+		name        string
+		found, want *Partition
+		scores      ComparisonScores
+	}{
+		{
+			// таблица сопряжённости [[2 1 0] [0 1 2]]: I = 2/3·ln2, H = ln2 и ln3
+			name:  "two vs three",
+			found: partitionOf(0, 0, 0, 1, 1, 1),
+			want:  partitionOf(0, 0, 1, 1, 2, 2),
+			scores: ComparisonScores{
+				NMI:           4.0 / 3 * ln2 / (ln2 + ln3),
+				ARI:           0.8 / 3.3,
+				VI:            ln3 - ln2/3,
+				F1:            (0.8 + 2.0/3) / 2,
+				Purity:        2.0 / 3,
+				PairPrecision: 1.0 / 3,
+				PairRecall:    2.0 / 3,
+				Nodes:         6,
+			},
+		},
+		{
+			name:   "relabelled copy",
+			found:  partitionOf(5, 5, 2, 2, 7),
+			want:   partitionOf(0, 0, 1, 1, 2),
+			scores: ComparisonScores{NMI: 1, ARI: 1, VI: 0, F1: 1, Purity: 1, PairPrecision: 1, PairRecall: 1, Nodes: 5},
+		},
+		{
+			// одно сообщество против синглетонов: нет общих пар, I = 0
+			name:   "grand coalition vs singletons",
+			found:  partitionOf(0, 0, 0, 0),
+			want:   partitionOf(0, 1, 2, 3),
+			scores: ComparisonScores{NMI: 0, ARI: 0, VI: math.Log(4), F1: 0.4, Purity: 0.25, PairPrecision: 0, PairRecall: 0, Nodes: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComparePartitions(tt.found, tt.want)
+			checks := []struct {
+				metric    string
+				got, want float64
+			}{
+				{"NMI", got.NMI, tt.scores.NMI},
+				{"ARI", got.ARI, tt.scores.ARI},
+				{"VI", got.VI, tt.scores.VI},
+				{"F1", got.F1, tt.scores.F1},
+				{"Purity", got.Purity, tt.scores.Purity},
+				{"PairPrecision", got.PairPrecision, tt.scores.PairPrecision},
+				{"PairRecall", got.PairRecall, tt.scores.PairRecall},
+			}
+			for _, c := range checks {
+				if math.Abs(c.got-c.want) > 1e-12 {
+					t.Errorf("%s = %.12f, ожидалось %.12f", c.metric, c.got, c.want)
+				}
+			}
+			if got.Nodes != tt.scores.Nodes {
+				t.Errorf("Nodes = %d, ожидалось %d", got.Nodes, tt.scores.Nodes)
+			}
+		})
+	}
+}
+
+// TestComparisonIgnoresUnlabelledNodes - узлы без эталонной метки не влияют на меры
+func TestComparisonIgnoresUnlabelledNodes(t *testing.T) {
+	found := partitionOf(0, 0, 1, 1, 1, 0)
+	truth := PartitionFromMap(map[int]int{0: 3, 1: 3, 2: 4, 3: 4}) // узлы 4 и 5 не размечены
+	got := ComparePartitions(found, truth)
+	if got.Nodes != 4 || got.NMI != 1 || got.ARI != 1 || got.VI != 0 {
+		t.Errorf("ожидалось полное совпадение на 4 узлах, получено %v (узлов %d)", got, got.Nodes)
+	}
+}
+
+// TestComparisonSymmetry - NMI, ARI и VI симметричны, а VI удовлетворяет неравенству треугольника
+func TestComparisonSymmetry(t *testing.T) {
+	rng := NewRand(3)
+	random := func() *Partition {
+		p := NewPartition(12)
+		for node := 0; node < 12; node++ {
+			p.Set(node, rng.Intn(4))
+		}
+		return p
+	}
+	for trial := 0; trial < 50; trial++ {
+		a, b, c := random(), random(), random()
+		if d := math.Abs(NMI(a, b) - NMI(b, a)); d > 1e-12 {
+			t.Errorf("NMI несимметрична: разница %g", d)
+		}
+		if d := math.Abs(AdjustedRandIndex(a, b) - AdjustedRandIndex(b, a)); d > 1e-12 {
+			t.Errorf("ARI несимметричен: разница %g", d)
+		}
+		ab, bc, ac := VariationOfInformation(a, b), VariationOfInformation(b, c), VariationOfInformation(a, c)
+		if math.Abs(ab-VariationOfInformation(b, a)) > 1e-12 || ac > ab+bc+1e-12 {
+			t.Errorf("VI не метрика: d(a,b)=%g d(b,c)=%g d(a,c)=%g", ab, bc, ac)
+		}
+		if nmi := NMI(a, b); nmi < 0 || nmi > 1+1e-12 {
+			t.Errorf("NMI = %g вне [0, 1]", nmi)
+		}
+	}
+}
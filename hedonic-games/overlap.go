@@ -0,0 +1,446 @@
+// overlap.go - перекрывающиеся коалиции: агент может состоять в нескольких сообществах
+package main
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// ============================================================
+// ПОКРЫТИЕ
+// ============================================================
+
+// Cover - покрытие узлов сообществами: в отличие от Partition узел
+// может входить в несколько сообществ (номера ≥ 0)
+type Cover struct {
+	of      map[int][]int        // узел -> его сообщества по возрастанию
+	members map[int]map[int]bool // сообщество -> узлы
+}
+
+// NewCover создаёт пустое покрытие
+func NewCover() *Cover {
+	return &Cover{
+		of:      make(map[int][]int),
+		members: make(map[int]map[int]bool),
+	}
+}
+
+// CoverFromPartition - покрытие, в котором каждый узел ровно в одном сообществе разбиения
+func CoverFromPartition(p *Partition) *Cover {
+	c := NewCover()
+	for _, node := range p.Nodes() {
+		c.Add(node, p.Community(node))
+	}
+	return c
+}
+
+// Add включает узел в сообщество
+func (c *Cover) Add(node, comm int) {
+	if c.Has(node, comm) {
+		return
+	}
+	if c.members[comm] == nil {
+		c.members[comm] = make(map[int]bool)
+	}
+	c.members[comm][node] = true
+	comms := c.of[node]
+	i, _ := slices.BinarySearch(comms, comm)
+	c.of[node] = slices.Insert(comms, i, comm)
+}
+
+// Remove исключает узел из сообщества; узел без сообществ выпадает из покрытия
+func (c *Cover) Remove(node, comm int) {
+	if !c.Has(node, comm) {
+		return
+	}
+	delete(c.members[comm], node)
+	if len(c.members[comm]) == 0 {
+		delete(c.members, comm)
+	}
+	c.of[node] = slices.DeleteFunc(c.of[node], func(x int) bool { return x == comm })
+	if len(c.of[node]) == 0 {
+		delete(c.of, node)
+	}
+}
+
+// Has - входит ли узел в сообщество
+func (c *Cover) Has(node, comm int) bool {
+	return c.members[comm][node]
+}
+
+// Memberships - сообщества узла по возрастанию
+func (c *Cover) Memberships(node int) []int {
+	return c.of[node]
+}
+
+// Members - узлы сообщества по возрастанию
+func (c *Cover) Members(comm int) []int {
+	return SortedCommunityIDs(c.members[comm])
+}
+
+// Size - число узлов сообщества
+func (c *Cover) Size(comm int) int {
+	return len(c.members[comm])
+}
+
+// Communities - номера непустых сообществ по возрастанию
+func (c *Cover) Communities() []int {
+	return SortedCommunityIDs(c.members)
+}
+
+// Nodes - покрытые узлы по возрастанию
+func (c *Cover) Nodes() []int {
+	return SortedCommunityIDs(c.of)
+}
+
+// NumCommunities - число непустых сообществ
+func (c *Cover) NumCommunities() int {
+	return len(c.members)
+}
+
+// Len - число покрытых узлов
+func (c *Cover) Len() int {
+	return len(c.of)
+}
+
+// NumMemberships - суммарное число членств (Σ_v |M(v)|)
+func (c *Cover) NumMemberships() int {
+	total := 0
+	for _, comms := range c.of {
+		total += len(comms)
+	}
+	return total
+}
+
+// OverlappingNodes - узлы, входящие больше чем в одно сообщество
+func (c *Cover) OverlappingNodes() []int {
+	var nodes []int
+	for _, node := range c.Nodes() {
+		if len(c.of[node]) > 1 {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// MaxCommunity - наибольший номер непустого сообщества (-1 для пустого покрытия)
+func (c *Cover) MaxCommunity() int {
+	maxComm := -1
+	for comm := range c.members {
+		maxComm = max(maxComm, comm)
+	}
+	return maxComm
+}
+
+// Clone - независимая копия покрытия
+func (c *Cover) Clone() *Cover {
+	q := NewCover()
+	for node, comms := range c.of {
+		for _, comm := range comms {
+			q.Add(node, comm)
+		}
+	}
+	return q
+}
+
+// Relabel перенумеровывает сообщества в 0..K-1 в порядке наименьшего узла
+// (при равном наименьшем узле - в порядке старых номеров)
+func (c *Cover) Relabel() {
+	label := make(map[int]int, len(c.members))
+	q := NewCover()
+	for _, node := range c.Nodes() {
+		for _, comm := range c.of[node] {
+			l, ok := label[comm]
+			if !ok {
+				l = len(label)
+				label[comm] = l
+			}
+			q.Add(node, l)
+		}
+	}
+	*c = *q
+}
+
+// MembershipStrengths - сила членства узла в каждом его сообществе (в порядке
+// Memberships): доля веса связей узла с остальными членами сообщества от суммы
+// по всем его сообществам. Если связей с сообществами нет - поровну
+func MembershipStrengths(g *Graph, c *Cover, node int) []float64 {
+	comms := c.Memberships(node)
+	strengths := make([]float64, len(comms))
+	total := 0.0
+	for i, comm := range comms {
+		for _, v := range g.GetNeighbors(node) {
+			if v != node && c.Has(v, comm) {
+				strengths[i] += g.Weight(node, v)
+			}
+		}
+		total += strengths[i]
+	}
+	for i := range strengths {
+		if total > 0 {
+			strengths[i] /= total
+		} else {
+			strengths[i] = 1 / float64(len(comms))
+		}
+	}
+	return strengths
+}
+
+// PrimaryPartition - разбиение по основному сообществу узла (с наибольшей силой
+// членства, при равенстве - с меньшим номером)
+func PrimaryPartition(g *Graph, c *Cover) *Partition {
+	nodes := c.Nodes()
+	p := NewPartition(nodeCapacity(nodes))
+	for _, node := range nodes {
+		comms := c.Memberships(node)
+		strengths := MembershipStrengths(g, c, node)
+		best := 0
+		for i := range comms {
+			if strengths[i] > strengths[best] {
+				best = i
+			}
+		}
+		p.Set(node, comms[best])
+	}
+	return p
+}
+
+// ============================================================
+// ИГРА С ПЕРЕКРЫВАЮЩИМИСЯ КОАЛИЦИЯМИ
+// ============================================================
+
+// OverlappingGame - гедоническая игра, в которой агент состоит не более чем
+// в MaxMemberships коалициях и платит Cost за каждое членство.
+// Друг приносит w один раз, сколько бы общих коалиций с ним ни было,
+// а штраф α берётся за каждого соседа по каждой коалиции:
+//
+//	u_v = Σ_{u: есть общая коалиция} w_vu - α·Σ_{S ∋ v} (|S|-1) - Cost·|M(v)|
+//
+// Потенциал Φ = Σ_{u<v: есть общая коалиция} w_uv - α·Σ_S n(S)(n(S)-1)/2 - Cost·Σ_v |M(v)|:
+// при изменении членств одного агента приращение его полезности равно приращению Φ,
+// поэтому динамика лучших ответов сходится. При r = 1 это игра (7.1).
+// Дублировать коалицию невыгодно: друзья в ней уже учтены, а штраф и плата растут
+type OverlappingGame struct {
+	G              *Graph
+	Cover          *Cover
+	Alpha          float64    // штраф за соседа по коалиции
+	Cost           float64    // плата за одно членство
+	MaxMemberships int        // r - наибольшее число коалиций агента (0 - 2)
+	Rng            *rand.Rand // порядок обхода агентов
+	Iterations     int        // раундов последней динамики
+}
+
+// NewOverlappingGame создаёт игру с начальным покрытием из разбиения
+// (nil - каждый агент в своей коалиции); rng == nil - генератор со случайным зерном
+func NewOverlappingGame(g *Graph, initial *Partition, alpha, cost float64, r int, rng *rand.Rand) *OverlappingGame {
+	if initial == nil {
+		initial = levelPartition(g.GetNodeList(), identity(g.NumNodes()))
+	}
+	if rng == nil {
+		rng = NewRand(ResolveSeed(0))
+	}
+	return &OverlappingGame{
+		G:              g,
+		Cover:          CoverFromPartition(initial),
+		Alpha:          alpha,
+		Cost:           cost,
+		MaxMemberships: r,
+		Rng:            rng,
+	}
+}
+
+// maxMemberships - r (0 - 2)
+func (og *OverlappingGame) maxMemberships() int {
+	if og.MaxMemberships <= 0 {
+		return 2
+	}
+	return og.MaxMemberships
+}
+
+// sharedCount - число общих коалиций узлов u и v
+func (og *OverlappingGame) sharedCount(u, v int) int {
+	count := 0
+	for _, comm := range og.Cover.Memberships(u) {
+		if og.Cover.Has(v, comm) {
+			count++
+		}
+	}
+	return count
+}
+
+// penalty - штраф за соседей по коалициям: α·Σ_S n(S)(n(S)-1)/2
+func (og *OverlappingGame) penalty() float64 {
+	total := 0.0
+	for _, comm := range og.Cover.Communities() {
+		total += pairs(og.Cover.Size(comm))
+	}
+	return og.Alpha * total
+}
+
+// ComputePotential - потенциал Φ покрытия
+func (og *OverlappingGame) ComputePotential() float64 {
+	P := 0.0
+	for _, u := range og.G.GetNodeList() {
+		for _, v := range og.G.GetNeighbors(u) {
+			if u < v && og.sharedCount(u, v) > 0 {
+				P += og.G.Weight(u, v)
+			}
+		}
+	}
+	return P - og.penalty() - og.Cost*float64(og.Cover.NumMemberships())
+}
+
+// Utility - полезность агента u_v при текущем покрытии
+func (og *OverlappingGame) Utility(node int) float64 {
+	u := 0.0
+	for _, v := range og.G.GetNeighbors(node) {
+		if v != node && og.sharedCount(node, v) > 0 {
+			u += og.G.Weight(node, v)
+		}
+	}
+	for _, comm := range og.Cover.Memberships(node) {
+		u -= og.Alpha * float64(og.Cover.Size(comm)-1)
+	}
+	return u - og.Cost*float64(len(og.Cover.Memberships(node)))
+}
+
+// friend - сосед агента: вес связи и число общих коалиций
+type friend struct {
+	node   int
+	weight float64
+	shared int
+}
+
+// friends - соседи агента (без петли) по возрастанию
+func (og *OverlappingGame) friends(node int) []friend {
+	var fs []friend
+	for _, v := range og.G.GetNeighbors(node) {
+		if v != node {
+			fs = append(fs, friend{node: v, weight: og.G.Weight(node, v), shared: og.sharedCount(node, v)})
+		}
+	}
+	return fs
+}
+
+// changeGain - приращение полезности агента при выходе из leave и вступлении
+// в join (Unassigned - нет): друзья, с которыми появилась или пропала первая
+// общая коалиция, штраф за соседей и плата за членство
+func (og *OverlappingGame) changeGain(node int, fs []friend, leave, join int) float64 {
+	gain := 0.0
+	for _, f := range fs {
+		shared := f.shared
+		if leave != Unassigned && og.Cover.Has(f.node, leave) {
+			shared--
+		}
+		if join != Unassigned && og.Cover.Has(f.node, join) {
+			shared++
+		}
+		switch {
+		case f.shared == 0 && shared > 0:
+			gain += f.weight
+		case f.shared > 0 && shared == 0:
+			gain -= f.weight
+		}
+	}
+	if leave != Unassigned {
+		gain += og.Alpha*float64(og.Cover.Size(leave)-1) + og.Cost
+	}
+	if join != Unassigned {
+		gain -= og.Alpha*float64(og.Cover.Size(join)) + og.Cost
+	}
+	return gain
+}
+
+// OverlapMove - изменение членств агента: выход из Leave и/или вступление в Join
+// (Unassigned - нет); Join может быть новой пустой коалицией
+type OverlapMove struct {
+	Node  int
+	Leave int
+	Join  int
+	Gain  float64
+}
+
+// BestResponse - лучшее изменение членств агента: вступить (если коалиций меньше r),
+// выйти (если коалиций больше одной) или перейти из одной коалиции в другую
+// (в том числе в новую, если коалиция одна). ok = false - улучшающего хода нет
+func (og *OverlappingGame) BestResponse(node int) (OverlapMove, bool) {
+	fs := og.friends(node)
+	mine := og.Cover.Memberships(node)
+	best := OverlapMove{Node: node, Leave: Unassigned, Join: Unassigned}
+	consider := func(leave, join int) {
+		if gain := og.changeGain(node, fs, leave, join); gain > best.Gain+1e-12 {
+			best = OverlapMove{Node: node, Leave: leave, Join: join, Gain: gain}
+		}
+	}
+
+	targetSet := make(map[int]bool)
+	for _, f := range fs {
+		for _, comm := range og.Cover.Memberships(f.node) {
+			if !og.Cover.Has(node, comm) {
+				targetSet[comm] = true
+			}
+		}
+	}
+	targets := SortedCommunityIDs(targetSet)
+
+	if len(mine) < og.maxMemberships() {
+		for _, join := range targets {
+			consider(Unassigned, join)
+		}
+	}
+	for _, leave := range mine {
+		if len(mine) > 1 {
+			consider(leave, Unassigned)
+		}
+		for _, join := range targets {
+			consider(leave, join)
+		}
+		if len(mine) == 1 && og.Cover.Size(leave) > 1 {
+			consider(leave, og.Cover.MaxCommunity()+1) // в одиночку
+		}
+	}
+	return best, best.Leave != Unassigned || best.Join != Unassigned
+}
+
+// Apply выполняет изменение членств
+func (og *OverlappingGame) Apply(move OverlapMove) {
+	if move.Leave != Unassigned {
+		og.Cover.Remove(move.Node, move.Leave)
+	}
+	if move.Join != Unassigned {
+		og.Cover.Add(move.Node, move.Join)
+	}
+}
+
+// FindStableCover - динамика лучших ответов: раунды обхода агентов в случайном
+// порядке, пока кто-то улучшает полезность (maxRounds ≤ 0 - без ограничения).
+// Возвращает итоговое покрытие с номерами 0..K-1
+func (og *OverlappingGame) FindStableCover(maxRounds int) *Cover {
+	nodes := og.G.GetNodeList()
+	og.Iterations = 0
+	for maxRounds <= 0 || og.Iterations < maxRounds {
+		og.Iterations++
+		moved := false
+		for _, i := range og.Rng.Perm(len(nodes)) {
+			if move, ok := og.BestResponse(nodes[i]); ok {
+				og.Apply(move)
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	og.Cover.Relabel()
+	return og.Cover
+}
+
+// IsStable - ни одному агенту не выгодно изменить свои членства
+func (og *OverlappingGame) IsStable() bool {
+	for _, node := range og.G.GetNodeList() {
+		if _, ok := og.BestResponse(node); ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// coverOf - покрытие из списков сообществ узлов (узел i - memberships[i])
+func coverOf(memberships ...[]int) *Cover {
+	c := NewCover()
+	for node, comms := range memberships {
+		for _, comm := range comms {
+			c.Add(node, comm)
+		}
+	}
+	return c
+}
+
+// TestOverlappingNMISelf - покрытие совпадает само с собой: NMI = 1
+func TestOverlappingNMISelf(t *testing.T) {
+	tests := []struct {
+		name  string
+		cover *Cover
+	}{
+		{"partition", CoverFromPartition(partitionOf(0, 0, 0, 1, 1, 1, 2, 2))},
+		{"overlap", coverOf([]int{0}, []int{0}, []int{0, 1}, []int{1}, []int{1, 2}, []int{2}, []int{2})},
+		{"nested", coverOf([]int{0, 1}, []int{0, 1}, []int{0}, []int{0}, []int{2}, []int{2, 0})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if nmi := OverlappingNMI(tt.cover, tt.cover.Clone()); math.Abs(nmi-1) > 1e-12 {
+				t.Errorf("OverlappingNMI(c, c) = %g, want 1", nmi)
+			}
+		})
+	}
+}
+
+// TestOmegaIndexEqualsARI - на непересекающихся разбиениях индекс омега равен ARI
+func TestOmegaIndexEqualsARI(t *testing.T) {
+	tests := []struct {
+		name        string
+		found, want *Partition
+	}{
+		{"same", partitionOf(0, 0, 1, 1, 2, 2), partitionOf(0, 0, 1, 1, 2, 2)},
+		{"relabeled", partitionOf(0, 0, 1, 1, 2, 2), partitionOf(5, 5, 3, 3, 0, 0)},
+		{"merged", partitionOf(0, 0, 0, 0, 1, 1, 1), partitionOf(0, 0, 1, 1, 2, 2, 2)},
+		{"crossed", partitionOf(0, 1, 0, 1, 0, 1, 0, 1), partitionOf(0, 0, 0, 0, 1, 1, 1, 1)},
+		{"singletons", partitionOf(0, 1, 2, 3, 4), partitionOf(0, 0, 1, 1, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			omega := OmegaIndex(CoverFromPartition(tt.found), CoverFromPartition(tt.want))
+			ari := AdjustedRandIndex(tt.found, tt.want)
+			if math.Abs(omega-ari) > 1e-12 {
+				t.Errorf("OmegaIndex = %g, ARI = %g", omega, ari)
+			}
+		})
+	}
+}
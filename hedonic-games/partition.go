@@ -0,0 +1,280 @@
+// partition.go - разбиение узлов на сообщества в плотных массивах
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"slices"
+)
+
+// Unassigned - сообщество узла, не входящего в разбиение
+const Unassigned = -1
+
+// Partition - разбиение узлов на сообщества с номерами ≥ 0.
+// Хранит узел -> сообщество и списки членов каждого сообщества, поэтому
+// размер сообщества, число сообществ и перенос узла - O(1).
+// Номера узлов и сообществ - индексы массивов, массивы растут по мере надобности.
+type Partition struct {
+	comm    []int   // узел -> сообщество (Unassigned - узла нет)
+	pos     []int   // узел -> позиция в members[comm]
+	members [][]int // сообщество -> узлы (порядок не задан)
+	count   int     // число непустых сообществ
+	nodes   int     // число распределённых узлов
+}
+
+// NewPartition создаёт пустое разбиение с местом под узлы 0..n-1
+func NewPartition(n int) *Partition {
+	p := &Partition{
+		comm: make([]int, n),
+		pos:  make([]int, n),
+	}
+	for i := range p.comm {
+		p.comm[i] = Unassigned
+	}
+	return p
+}
+
+// NewSingletonPartition - каждый из узлов 0..n-1 в своём сообществе с тем же номером
+func NewSingletonPartition(n int) *Partition {
+	p := NewPartition(n)
+	p.members = make([][]int, n)
+	for i := 0; i < n; i++ {
+		p.Set(i, i)
+	}
+	return p
+}
+
+// PartitionFromMap строит разбиение из отображения узел -> сообщество.
+// Номера сообществ могут быть любыми неотрицательными (например, из файла):
+// они сжимаются в 0..K-1 в порядке наименьшего узла, поэтому память
+// не зависит от их величины
+func PartitionFromMap(m map[int]int) *Partition {
+	p := NewPartition(len(m))
+	label := make(map[int]int)
+	for _, node := range SortedCommunityIDs(m) {
+		l, ok := label[m[node]]
+		if !ok {
+			l = len(label)
+			label[m[node]] = l
+		}
+		p.Set(node, l)
+	}
+	return p
+}
+
+// ToMap возвращает разбиение как отображение узел -> сообщество
+func (p *Partition) ToMap() map[int]int {
+	m := make(map[int]int, p.nodes)
+	for node, comm := range p.comm {
+		if comm != Unassigned {
+			m[node] = comm
+		}
+	}
+	return m
+}
+
+// Len - число распределённых узлов
+func (p *Partition) Len() int {
+	return p.nodes
+}
+
+// NumCommunities - число непустых сообществ
+func (p *Partition) NumCommunities() int {
+	return p.count
+}
+
+// Community - сообщество узла (Unassigned, если узла нет)
+func (p *Partition) Community(node int) int {
+	if node < 0 || node >= len(p.comm) {
+		return Unassigned
+	}
+	return p.comm[node]
+}
+
+// Size - число узлов в сообществе
+func (p *Partition) Size(comm int) int {
+	if comm < 0 || comm >= len(p.members) {
+		return 0
+	}
+	return len(p.members[comm])
+}
+
+// Members - узлы сообщества в порядке хранения; срез нельзя изменять
+// и он меняется при следующем Set
+func (p *Partition) Members(comm int) []int {
+	if comm < 0 || comm >= len(p.members) {
+		return nil
+	}
+	return p.members[comm]
+}
+
+// SortedMembers - копия состава сообщества по возрастанию номера узла
+func (p *Partition) SortedMembers(comm int) []int {
+	members := slices.Clone(p.Members(comm))
+	slices.Sort(members)
+	return members
+}
+
+// Set переносит узел в сообщество comm (или добавляет узел в разбиение)
+func (p *Partition) Set(node, comm int) {
+	if comm < 0 {
+		p.Remove(node)
+		return
+	}
+	for node >= len(p.comm) {
+		p.comm = append(p.comm, Unassigned)
+		p.pos = append(p.pos, 0)
+	}
+	old := p.comm[node]
+	if old == comm {
+		return
+	}
+	if old != Unassigned {
+		p.detach(node, old)
+	} else {
+		p.nodes++
+	}
+
+	if comm >= len(p.members) {
+		p.members = append(p.members, make([][]int, comm+1-len(p.members))...)
+	}
+	if len(p.members[comm]) == 0 {
+		p.count++
+	}
+	p.pos[node] = len(p.members[comm])
+	p.members[comm] = append(p.members[comm], node)
+	p.comm[node] = comm
+}
+
+// Remove исключает узел из разбиения
+func (p *Partition) Remove(node int) {
+	old := p.Community(node)
+	if old == Unassigned {
+		return
+	}
+	p.detach(node, old)
+	p.comm[node] = Unassigned
+	p.nodes--
+}
+
+// detach удаляет узел из списка членов сообщества перестановкой с последним
+func (p *Partition) detach(node, comm int) {
+	members := p.members[comm]
+	i, last := p.pos[node], len(members)-1
+	members[i] = members[last]
+	p.pos[members[i]] = i
+	p.members[comm] = members[:last]
+	if last == 0 {
+		p.count--
+	}
+}
+
+// Communities - номера непустых сообществ по возрастанию
+func (p *Partition) Communities() []int {
+	comms := make([]int, 0, p.count)
+	for comm, members := range p.members {
+		if len(members) > 0 {
+			comms = append(comms, comm)
+		}
+	}
+	return comms
+}
+
+// Nodes - распределённые узлы по возрастанию
+func (p *Partition) Nodes() []int {
+	nodes := make([]int, 0, p.nodes)
+	for node, comm := range p.comm {
+		if comm != Unassigned {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Groups - состав непустых сообществ (узлы по возрастанию)
+func (p *Partition) Groups() map[int][]int {
+	groups := make(map[int][]int, p.count)
+	for _, comm := range p.Communities() {
+		groups[comm] = p.SortedMembers(comm)
+	}
+	return groups
+}
+
+// MaxCommunity - наибольший номер непустого сообщества (-1 для пустого разбиения)
+func (p *Partition) MaxCommunity() int {
+	for comm := len(p.members) - 1; comm >= 0; comm-- {
+		if len(p.members[comm]) > 0 {
+			return comm
+		}
+	}
+	return -1
+}
+
+// Clone - независимая копия разбиения
+func (p *Partition) Clone() *Partition {
+	q := &Partition{
+		comm:    slices.Clone(p.comm),
+		pos:     slices.Clone(p.pos),
+		members: make([][]int, len(p.members)),
+		count:   p.count,
+		nodes:   p.nodes,
+	}
+	for comm, members := range p.members {
+		if len(members) > 0 {
+			q.members[comm] = slices.Clone(members)
+		}
+	}
+	return q
+}
+
+// Relabel перенумеровывает сообщества в 0..K-1 в порядке наименьшего узла
+// (каноническая форма: равные разбиения получают равные номера)
+func (p *Partition) Relabel() {
+	label := make(map[int]int, p.count)
+	q := NewPartition(len(p.comm))
+	q.members = make([][]int, p.count)
+	for node, comm := range p.comm {
+		if comm == Unassigned {
+			continue
+		}
+		l, ok := label[comm]
+		if !ok {
+			l = len(label)
+			label[comm] = l
+		}
+		q.Set(node, l)
+	}
+	*p = *q
+}
+
+// partitionGob - разбиение для gob: списки членов в их внутреннем порядке
+type partitionGob struct {
+	N       int     // место под узлы 0..N-1
+	Members [][]int // сообщество -> узлы
+}
+
+// GobEncode сохраняет разбиение вместе с порядком членов сообществ: от него
+// зависит порядок суммирования в цели ML, поэтому восстановленное разбиение
+// даёт те же значения до бита (см. checkpoint.go)
+func (p *Partition) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(partitionGob{N: len(p.comm), Members: p.members})
+	return buf.Bytes(), err
+}
+
+// GobDecode восстанавливает разбиение, сохранённое GobEncode
+func (p *Partition) GobDecode(data []byte) error {
+	var pg partitionGob
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&pg); err != nil {
+		return err
+	}
+	q := NewPartition(pg.N)
+	q.members = make([][]int, len(pg.Members))
+	for comm, members := range pg.Members {
+		for _, node := range members {
+			q.Set(node, comm)
+		}
+	}
+	*p = *q
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// checkPartition сравнивает разбиение с эталонным отображением узел -> сообщество
+func checkPartition(t *testing.T, p *Partition, want map[int]int) {
+	t.Helper()
+	if !maps.Equal(p.ToMap(), want) {
+		t.Fatalf("ToMap = %v, ожидалось %v", p.ToMap(), want)
+	}
+	sizes := make(map[int]int)
+	for _, comm := range want {
+		sizes[comm]++
+	}
+	if p.Len() != len(want) || p.NumCommunities() != len(sizes) {
+		t.Fatalf("Len = %d, NumCommunities = %d; ожидалось %d, %d", p.Len(), p.NumCommunities(), len(want), len(sizes))
+	}
+	if comms := SortedCommunityIDs(sizes); !slices.Equal(p.Communities(), comms) {
+		t.Fatalf("Communities = %v, ожидалось %v", p.Communities(), comms)
+	}
+	if nodes := SortedCommunityIDs(want); !slices.Equal(p.Nodes(), nodes) {
+		t.Fatalf("Nodes = %v, ожидалось %v", p.Nodes(), nodes)
+	}
+	maxComm := -1
+	for comm, size := range sizes {
+		maxComm = max(maxComm, comm)
+		if p.Size(comm) != size {
+			t.Fatalf("Size(%d) = %d, ожидалось %d", comm, p.Size(comm), size)
+		}
+		for _, node := range p.Members(comm) {
+			if want[node] != comm {
+				t.Fatalf("узел %d в составе сообщества %d, а должен быть в %d", node, comm, want[node])
+			}
+		}
+	}
+	if p.MaxCommunity() != maxComm {
+		t.Fatalf("MaxCommunity = %d, ожидалось %d", p.MaxCommunity(), maxComm)
+	}
+}
+
+// TestPartitionAgainstMap - случайные Set и Remove согласованы с отображением
+func TestPartitionAgainstMap(t *testing.T) {
+	rng := NewRand(4)
+	p := NewPartition(5) // узлы за пределами начального места добавляются по ходу
+	want := make(map[int]int)
+	for step := 0; step < 2000; step++ {
+		node, comm := rng.Intn(30), rng.Intn(8)
+		switch rng.Intn(5) {
+		case 0:
+			p.Remove(node)
+			delete(want, node)
+		case 1:
+			p.Set(node, Unassigned)
+			delete(want, node)
+		default:
+			p.Set(node, comm)
+			want[node] = comm
+		}
+		checkPartition(t, p, want)
+	}
+	if p.Community(-1) != Unassigned || p.Community(1000) != Unassigned || p.Size(1000) != 0 || p.Members(-1) != nil {
+		t.Error("узлы и сообщества вне диапазона должны быть пустыми")
+	}
+}
+
+// TestPartitionCloneAndRelabel - копия независима, перенумерация канонична
+func TestPartitionCloneAndRelabel(t *testing.T) {
+	p := PartitionFromMap(map[int]int{0: 70, 1: 70, 2: 9, 4: 1000000, 5: 9})
+	checkPartition(t, p, map[int]int{0: 0, 1: 0, 2: 1, 4: 2, 5: 1})
+
+	q := p.Clone()
+	q.Set(0, 5)
+	q.Remove(4)
+	checkPartition(t, p, map[int]int{0: 0, 1: 0, 2: 1, 4: 2, 5: 1})
+	checkPartition(t, q, map[int]int{0: 5, 1: 0, 2: 1, 5: 1})
+
+	q.Relabel()
+	checkPartition(t, q, map[int]int{0: 0, 1: 1, 2: 2, 5: 2})
+}
+
+// TestPartitionGobKeepsMemberOrder - после gob совпадают и разбиение, и порядок членов
+func TestPartitionGobKeepsMemberOrder(t *testing.T) {
+	p := NewSingletonPartition(10)
+	rng := NewRand(2)
+	for step := 0; step < 50; step++ {
+		p.Set(rng.Intn(10), rng.Intn(4))
+	}
+	p.Remove(3)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	var q Partition
+	if err := gob.NewDecoder(&buf).Decode(&q); err != nil {
+		t.Fatal(err)
+	}
+	checkPartition(t, &q, p.ToMap())
+	for _, comm := range p.Communities() {
+		if !slices.Equal(p.Members(comm), q.Members(comm)) {
+			t.Errorf("сообщество %d: порядок членов %v, после gob %v", comm, p.Members(comm), q.Members(comm))
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// preferences.go - модели предпочтений агентов гедонической игры
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Preference - модель предпочтений: полезность агента в коалиции
+type Preference interface {
+	// Name - короткое имя модели
+	Name() string
+	// Value - полезность агента node в коалиции members (node входит в members)
+	Value(g *Graph, node int, members []int) float64
+	// HasPotential сообщает, гарантирует ли модель точный потенциал
+	// (тогда динамика улучшающих ответов сходится к Нэш-стабильному разбиению)
+	HasPotential() bool
+}
+
+// boundedPreference - модель, умеющая оценить полезность агента сверху
+// (сужает перебор коалиций при проверке ядра)
+type boundedPreference interface {
+	UpperBound(g *Graph, node int) float64
+}
+
+// PreferenceNames - модели, которые выбираются по имени (-preference)
+const PreferenceNames = "friends-strangers | fractional | friends-appreciation | enemies-aversion | additively-separable | anonymous"
+
+// PreferenceValues - таблицы ценностей для моделей, которым мало графа
+// (загружаются LoadPreferenceValues)
+type PreferenceValues struct {
+	Matrix [][]float64 // Matrix[i][j] - ценность агента j для агента i (additively-separable, fractional)
+	Sizes  []float64   // Sizes[k] - полезность коалиции из k агентов (anonymous)
+}
+
+// ParsePreference создаёт модель предпочтений по имени для CLI
+// "" и "friends-strangers" - модель по умолчанию (nil). additively-separable
+// требует values.Matrix, anonymous - values.Sizes; fractional без матрицы
+// берёт ценности из весов рёбер. Матрица должна покрывать все узлы g
+func ParsePreference(name string, values PreferenceValues, g *Graph) (Preference, error) {
+	if values.Matrix != nil {
+		if err := checkValueMatrix(values.Matrix, nodeCapacity(g.GetNodeList())); err != nil {
+			return nil, fmt.Errorf("модель %s: %w", name, err)
+		}
+	}
+	switch name {
+	case "", "friends-strangers":
+		return nil, nil
+	case "fractional":
+		return &FractionalPreference{Values: values.Matrix}, nil
+	case "friends-appreciation":
+		return &FriendsAppreciationPreference{}, nil
+	case "enemies-aversion":
+		return &EnemiesAversionPreference{}, nil
+	case "additively-separable":
+		if values.Matrix == nil {
+			return nil, fmt.Errorf("модели %s нужны ценности пар агентов (-values, строки \"i j v\")", name)
+		}
+		return &AdditivelySeparablePreference{Values: values.Matrix}, nil
+	case "anonymous":
+		if len(values.Sizes) < 2 {
+			return nil, fmt.Errorf("модели %s нужны полезности размеров коалиций (-values, строки \"k v\")", name)
+		}
+		return &AnonymousPreference{SizeValues: values.Sizes}, nil
+	}
+	return nil, fmt.Errorf("неизвестная модель предпочтений %q", name)
+}
+
+// checkValueMatrix проверяет, что матрица ценностей - не меньше n×n
+func checkValueMatrix(matrix [][]float64, n int) error {
+	if len(matrix) < n {
+		return fmt.Errorf("матрица ценностей на %d агентов, в графе узлы 0..%d", len(matrix), n-1)
+	}
+	for i, row := range matrix[:n] {
+		if len(row) < n {
+			return fmt.Errorf("в строке %d матрицы ценностей %d значений, нужно %d", i, len(row), n)
+		}
+	}
+	return nil
+}
+
+// matrixValue - Values[i][j]; пары за пределами матрицы (узлы, добавленные
+// после загрузки ценностей) стоят 0, как непомеченные пары в LoadPreferenceValues
+func matrixValue(values [][]float64, i, j int) float64 {
+	if i < 0 || i >= len(values) || j < 0 || j >= len(values[i]) {
+		return 0
+	}
+	return values[i][j]
+}
+
+// ============================================================
+// АДДИТИВНО-СЕПАРАБЕЛЬНЫЕ ПРЕДПОЧТЕНИЯ
+// ============================================================
+
+// AdditivelySeparablePreference - v_i(S) = Σ_{j∈S, j≠i} v_ij по матрице ценностей
+type AdditivelySeparablePreference struct {
+	Values [][]float64 // Values[i][j] - ценность агента j для агента i
+}
+
+func (p *AdditivelySeparablePreference) Name() string { return "additively-separable" }
+
+func (p *AdditivelySeparablePreference) Value(g *Graph, node int, members []int) float64 {
+	value := 0.0
+	for _, other := range members {
+		if other != node {
+			value += matrixValue(p.Values, node, other)
+		}
+	}
+	return value
+}
+
+// HasPotential - симметричная матрица (v_ij = v_ji) даёт потенциал Σ_S Σ_{i<j∈S} v_ij
+func (p *AdditivelySeparablePreference) HasPotential() bool {
+	for i := range p.Values {
+		for j := range p.Values[i] {
+			if j >= len(p.Values) || i >= len(p.Values[j]) || p.Values[i][j] != p.Values[j][i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (p *AdditivelySeparablePreference) UpperBound(g *Graph, node int) float64 {
+	if node >= len(p.Values) {
+		return 0
+	}
+	return positiveSum(p.Values[node], node)
+}
+
+// ============================================================
+// ДРОБНЫЕ ГЕДОНИЧЕСКИЕ ИГРЫ
+// ============================================================
+
+// FractionalPreference - v_i(S) = Σ_{j∈S} v_ij / |S| (средняя ценность коалиции)
+// Если Values не задана, v_ij - вес ребра графа (0 для несоседей).
+type FractionalPreference struct {
+	Values [][]float64
+}
+
+func (p *FractionalPreference) Name() string { return "fractional" }
+
+func (p *FractionalPreference) Value(g *Graph, node int, members []int) float64 {
+	if len(members) == 0 {
+		return 0
+	}
+	value := 0.0
+	for _, other := range members {
+		if other != node {
+			value += p.value(g, node, other)
+		}
+	}
+	return value / float64(len(members))
+}
+
+// HasPotential - у дробных игр Нэш-стабильного разбиения может не существовать
+// даже при симметричных ценностях, потенциала нет
+func (p *FractionalPreference) HasPotential() bool { return false }
+
+// UpperBound - в коалиции хотя бы из двух агентов сумма делится минимум на 2
+func (p *FractionalPreference) UpperBound(g *Graph, node int) float64 {
+	if p.Values != nil {
+		if node >= len(p.Values) {
+			return 0
+		}
+		return positiveSum(p.Values[node], node) / 2
+	}
+	bound := 0.0
+	for _, nghbr := range g.GetNeighbors(node) {
+		if w := g.Weight(node, nghbr); w > 0 && nghbr != node {
+			bound += w
+		}
+	}
+	return bound / 2
+}
+
+func (p *FractionalPreference) value(g *Graph, i, j int) float64 {
+	if p.Values != nil {
+		return matrixValue(p.Values, i, j)
+	}
+	return g.Weight(i, j)
+}
+
+// ============================================================
+// ДРУЗЬЯ И ВРАГИ (Dimitrov et al.)
+// ============================================================
+
+// FriendsAppreciationPreference - друг стоит n, враг (несосед) -1:
+// агент предпочитает коалицию с большим числом друзей, при равенстве - с меньшим числом врагов
+type FriendsAppreciationPreference struct{}
+
+func (p *FriendsAppreciationPreference) Name() string { return "friends-appreciation" }
+
+func (p *FriendsAppreciationPreference) Value(g *Graph, node int, members []int) float64 {
+	n := float64(g.NumNodes())
+	friends, enemies := countFriendsEnemies(g, node, members)
+	return n*friends - enemies
+}
+
+// HasPotential - симметричная аддитивно-сепарабельная игра
+func (p *FriendsAppreciationPreference) HasPotential() bool { return true }
+
+func (p *FriendsAppreciationPreference) UpperBound(g *Graph, node int) float64 {
+	return float64(g.NumNodes()) * float64(len(g.Edges[node]))
+}
+
+// EnemiesAversionPreference - друг стоит 1, враг -n:
+// агент избегает врагов, а при их равенстве предпочитает больше друзей
+type EnemiesAversionPreference struct{}
+
+func (p *EnemiesAversionPreference) Name() string { return "enemies-aversion" }
+
+func (p *EnemiesAversionPreference) Value(g *Graph, node int, members []int) float64 {
+	n := float64(g.NumNodes())
+	friends, enemies := countFriendsEnemies(g, node, members)
+	return friends - n*enemies
+}
+
+// HasPotential - симметричная аддитивно-сепарабельная игра
+func (p *EnemiesAversionPreference) HasPotential() bool { return true }
+
+func (p *EnemiesAversionPreference) UpperBound(g *Graph, node int) float64 {
+	return float64(len(g.Edges[node]))
+}
+
+func countFriendsEnemies(g *Graph, node int, members []int) (float64, float64) {
+	friends, enemies := 0.0, 0.0
+	for _, other := range members {
+		if other == node {
+			continue
+		}
+		if g.HasEdge(node, other) {
+			friends++
+		} else {
+			enemies++
+		}
+	}
+	return friends, enemies
+}
+
+// ============================================================
+// АНОНИМНЫЕ ПРЕДПОЧТЕНИЯ
+// ============================================================
+
+// AnonymousPreference - полезность зависит только от размера коалиции:
+// v_i(S) = SizeValues[|S|] (для размеров за концом таблицы - последнее значение)
+type AnonymousPreference struct {
+	SizeValues []float64 // SizeValues[0] не используется; таблица короче 2 - полезность всегда 0
+}
+
+func (p *AnonymousPreference) Name() string { return "anonymous" }
+
+func (p *AnonymousPreference) Value(g *Graph, node int, members []int) float64 {
+	if len(p.SizeValues) < 2 {
+		return 0
+	}
+	size := min(len(members), len(p.SizeValues)-1)
+	return p.SizeValues[size]
+}
+
+// HasPotential - при общей для всех агентов таблице Φ = Σ_S Σ_{k=1}^{|S|} f(k)
+// является точным потенциалом
+func (p *AnonymousPreference) HasPotential() bool { return true }
+
+func (p *AnonymousPreference) UpperBound(g *Graph, node int) float64 {
+	if len(p.SizeValues) < 2 {
+		return 0
+	}
+	bound := math.Inf(-1)
+	for size := 1; size < len(p.SizeValues); size++ {
+		bound = math.Max(bound, p.SizeValues[size])
+	}
+	return bound
+}
+
+func positiveSum(values []float64, skip int) float64 {
+	sum := 0.0
+	for j, v := range values {
+		if j != skip && v > 0 {
+			sum += v
+		}
+	}
+	return sum
+}
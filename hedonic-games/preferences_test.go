@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+// TestPreferenceMovesWithoutEdges - при Preference выгодный переход в коалицию без
+// соседей видят и динамика потенциала, и IsNashStable, и лучший ответ агента
+func TestPreferenceMovesWithoutEdges(t *testing.T) {
+	// узел 4 изолирован, 0-1 и 2-3 - пары
+	g := edgeGraph(5, [2]int{0, 1}, [2]int{2, 3})
+	symmetric := [][]float64{
+		{0, 1, 0, 0, 2},
+		{1, 0, 0, 0, 2},
+		{0, 0, 0, 1, 0},
+		{0, 0, 1, 0, 0},
+		{2, 2, 0, 0, 0},
+	}
+	tests := []struct {
+		name       string
+		preference Preference
+	}{
+		{"anonymous", &AnonymousPreference{SizeValues: []float64{0, 0, 1, 5, 5, 5}}},
+		{"additively-separable", &AdditivelySeparablePreference{Values: symmetric}},
+		{"fractional", &FractionalPreference{Values: symmetric}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newGame := func() *HedonicGame {
+				hg := NewHedonicGame(*g.Clone(), 0.5)
+				hg.SetSeed(1)
+				hg.Preference = tt.preference
+				hg.Partition = partitionOf(0, 0, 1, 1, 2)
+				hg.RebuildAggregates()
+				return hg
+			}
+
+			hg := newGame()
+			if _, gain := hg.bestPreferenceResponse(4); gain <= 0 {
+				t.Fatalf("bestPreferenceResponse(4): выигрыш %g, ожидался положительный", gain)
+			}
+			if nashStable(t, hg, false) {
+				t.Errorf("IsNashStable = true, хотя узлу 4 выгодно уйти в коалицию без соседей")
+			}
+
+			if !tt.preference.HasPotential() {
+				return
+			}
+			hg = newGame()
+			equilibrate(t, hg, false)
+			if hg.Partition.Size(hg.Partition.Community(4)) == 1 {
+				t.Errorf("динамика оставила узел 4 одного")
+			}
+			if !nashStable(t, hg, false) {
+				t.Errorf("разбиение после динамики не Нэш-стабильно")
+			}
+			for _, node := range hg.G.GetNodeList() {
+				if _, gain := hg.bestPreferenceResponse(node); gain > 1e-9 {
+					t.Errorf("узлу %d выгоден переход (+%g), динамика его не сделала", node, gain)
+				}
+			}
+		})
+	}
+}
+
+// TestParsePreferenceChecksMatrix - матрица ценностей меньше графа отвергается
+// при разборе, а не падает на первом обращении
+func TestParsePreferenceChecksMatrix(t *testing.T) {
+	g := edgeGraph(3, [2]int{0, 1})
+	tests := []struct {
+		name    string
+		matrix  [][]float64
+		wantErr bool
+	}{
+		{"square", [][]float64{{0, 1, 0}, {1, 0, 0}, {0, 0, 0}}, false},
+		{"larger", [][]float64{{0, 1, 0, 0}, {1, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}, false},
+		{"too few rows", [][]float64{{0, 1}, {1, 0}}, true},
+		{"short row", [][]float64{{0, 1, 0}, {1, 0}, {0, 0, 0}}, true},
+	}
+	for _, tt := range tests {
+		for _, name := range []string{"additively-separable", "fractional"} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				p, err := ParsePreference(name, PreferenceValues{Matrix: tt.matrix}, g)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ошибка %v, ожидалась: %v", err, tt.wantErr)
+				}
+				if err == nil {
+					// узел 3 добавлен после загрузки ценностей: его пары стоят 0
+					if v := p.Value(g, 3, []int{0, 1, 3}); v != 0 {
+						t.Errorf("Value узла вне матрицы = %g, ожидалось 0", v)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestAnonymousPreferenceTable - SizeValues[0] не используется, размеры за концом
+// таблицы берут последнее значение, таблица короче 2 отвергается при разборе
+func TestAnonymousPreferenceTable(t *testing.T) {
+	g := edgeGraph(6)
+	p := &AnonymousPreference{SizeValues: []float64{100, 1, 2, 4}}
+	for i, want := range []float64{1, 2, 4, 4, 4} {
+		members := g.GetNodeList()[:i+1]
+		if got := p.Value(g, 0, members); got != want {
+			t.Errorf("коалиция из %d: полезность %g, ожидалась %g", len(members), got, want)
+		}
+	}
+	if got := (&AnonymousPreference{SizeValues: []float64{7}}).Value(g, 0, []int{0, 1}); got != 0 {
+		t.Errorf("таблица из одного значения: полезность %g, ожидалась 0", got)
+	}
+	for _, sizes := range [][]float64{nil, {7}} {
+		if _, err := ParsePreference("anonymous", PreferenceValues{Sizes: sizes}, g); err == nil {
+			t.Errorf("таблица %v принята", sizes)
+		}
+	}
+}
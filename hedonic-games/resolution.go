@@ -0,0 +1,177 @@
+// resolution.go - разрешение модулярности и сканирование по γ
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// NullModel - нулевая модель потенциала (7.2)
+type NullModel string
+
+const (
+	NullModelNewmanGirvan NullModel = "ng"  // конфигурационная модель: ожидаемый вес пары γ·d_i·d_j/(2m)
+	NullModelCPM          NullModel = "cpm" // модель Поттса с константой: ожидаемый вес пары γ
+)
+
+// ParseNullModel разбирает имя нулевой модели ("" - Ньюман–Гирван)
+func ParseNullModel(name string) (NullModel, error) {
+	switch model := NullModel(name); model {
+	case "":
+		return NullModelNewmanGirvan, nil
+	case NullModelNewmanGirvan, NullModelCPM:
+		return model, nil
+	}
+	return "", fmt.Errorf("неизвестная нулевая модель %q", name)
+}
+
+// ResolutionQuality - качество разбиения при разрешении γ: Q_γ для ng, H_CPM для cpm
+func ResolutionQuality(g *Graph, partition *Partition, gamma float64, model NullModel) float64 {
+	if model == NullModelCPM {
+		return ComputeCPM(g, partition, gamma)
+	}
+	return ComputeModularityResolution(g, partition, gamma)
+}
+
+// SymmetricResolutionQuality - ResolutionQuality симметризации графа (вес ребра -
+// сумма дуг в обе стороны): её оптимизируют Louvain и Leiden. У неориентированного
+// графа совпадает с ResolutionQuality
+func SymmetricResolutionQuality(g *Graph, partition *Partition, gamma float64, model NullModel) float64 {
+	if model == NullModelCPM {
+		return symmetricCPM(g, partition, gamma)
+	}
+	return symmetricModularity(g, partition, gamma)
+}
+
+// DefaultResolutionRange - диапазон сканирования по умолчанию: для модулярности
+// γ около 1, для CPM γ сравнима с плотностью рёбер внутри сообществ
+func DefaultResolutionRange(model NullModel) (float64, float64) {
+	if model == NullModelCPM {
+		return 0.01, 1
+	}
+	return 0.1, 10
+}
+
+// ResolutionGrid - steps значений γ от from до to с равным шагом по логарифму
+func ResolutionGrid(from, to float64, steps int) ([]float64, error) {
+	if from <= 0 || to < from {
+		return nil, fmt.Errorf("диапазон γ: нужно 0 < от ≤ до, получено [%g, %g]", from, to)
+	}
+	if steps < 1 {
+		return nil, fmt.Errorf("диапазон γ: нужен хотя бы один шаг, получено %d", steps)
+	}
+	if steps == 1 || from == to {
+		return []float64{from}, nil
+	}
+	gammas := make([]float64, steps)
+	ratio := math.Log(to / from)
+	for i := range gammas {
+		gammas[i] = from * math.Exp(ratio*float64(i)/float64(steps-1))
+	}
+	gammas[steps-1] = to
+	return gammas, nil
+}
+
+// ============================================================
+// СКАНИРОВАНИЕ ПО РАЗРЕШЕНИЮ
+// ============================================================
+
+// ScanPoint - разбиение, найденное при одном значении γ
+type ScanPoint struct {
+	Gamma       float64
+	Partition   *Partition
+	Result      ExperimentResult
+	Quality     float64 // Q_γ или H_CPM (см. ResolutionQuality)
+	VIPrev      float64 // вариация информации с разбиением предыдущей γ (у первой точки 0)
+	NMIPrev     float64 // NMI с разбиением предыдущей γ (у первой точки 1)
+	SameAsPrev  bool    // разбиение совпадает с предыдущим с точностью до допуска
+	Communities int
+}
+
+// ResolutionPlateau - диапазон γ, на котором разбиение не меняется.
+// Широкие плато соответствуют устойчивым уровням иерархии сообществ
+type ResolutionPlateau struct {
+	Start, End  int     // индексы первой и последней точки скана
+	GammaFrom   float64 // γ первой точки
+	GammaTo     float64 // γ последней точки
+	Communities int
+	Partition   *Partition // разбиение первой точки плато
+}
+
+// Points - число точек скана на плато
+func (p ResolutionPlateau) Points() int {
+	return p.End - p.Start + 1
+}
+
+// Width - ширина плато в декадах γ, log10(γ_to/γ_from)
+func (p ResolutionPlateau) Width() float64 {
+	return math.Log10(p.GammaTo / p.GammaFrom)
+}
+
+// ResolutionScan запускает динамику потенциала (7.2) при каждом γ из gammas
+// на workers воркерах (≤ 0 - по числу процессоров, см. RunSweep) и сравнивает
+// соседние разбиения.
+// Остальные параметры запуска берутся из base; base.Seed = 0 - случайное зерно,
+// точка i получает seed+i
+func ResolutionScan(g *Graph, base RunConfig, gammas []float64, tolerance float64, workers int) ([]ScanPoint, error) {
+	model, err := ParseNullModel(base.NullModel)
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]RunConfig, len(gammas))
+	for i, gamma := range gammas {
+		cells[i] = base
+		cells[i].Algorithm = "hedonic"
+		cells[i].UseModularity = true
+		cells[i].Resolution = gamma
+	}
+	ResolveSweepSeeds(cells, base.Seed)
+
+	points := make([]ScanPoint, 0, len(gammas))
+	_, err = RunSweep(g, cells, workers, func(cell *SweepCell) {
+		gamma := cell.Config.Resolution
+		point := ScanPoint{
+			Gamma:       gamma,
+			Partition:   cell.Partition,
+			Result:      cell.Result,
+			Quality:     ResolutionQuality(g, cell.Partition, gamma, model),
+			NMIPrev:     1,
+			Communities: cell.Partition.NumCommunities(),
+		}
+		if n := len(points); n > 0 {
+			prev := points[n-1].Partition
+			point.VIPrev = VariationOfInformation(cell.Partition, prev)
+			point.NMIPrev = NMI(cell.Partition, prev)
+			point.SameAsPrev = point.VIPrev <= tolerance+1e-12
+		}
+		points = append(points, point)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// FindPlateaus выделяет в скане участки подряд совпадающих разбиений
+// не короче minPoints точек (в порядке возрастания γ)
+func FindPlateaus(points []ScanPoint, minPoints int) []ResolutionPlateau {
+	var plateaus []ResolutionPlateau
+	for start := 0; start < len(points); {
+		end := start
+		for end+1 < len(points) && points[end+1].SameAsPrev {
+			end++
+		}
+		if end-start+1 >= minPoints {
+			plateaus = append(plateaus, ResolutionPlateau{
+				Start:       start,
+				End:         end,
+				GammaFrom:   points[start].Gamma,
+				GammaTo:     points[end].Gamma,
+				Communities: points[start].Communities,
+				Partition:   points[start].Partition,
+			})
+		}
+		start = end + 1
+	}
+	return plateaus
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestResolutionQualityKnownValues - две тройки с мостом (m = 7, степени троек 7 и 7):
+// Q_γ = 6/7 - γ/2, H_CPM = 6 - 6γ для разбиения на тройки
+func TestResolutionQualityKnownValues(t *testing.T) {
+	g := edgeGraph(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{0, 2}, [2]int{3, 4}, [2]int{4, 5}, [2]int{3, 5}, [2]int{2, 3})
+	triangles := partitionOf(0, 0, 0, 1, 1, 1)
+	whole := partitionOf(0, 0, 0, 0, 0, 0)
+	for _, gamma := range []float64{0.5, 1, 2} {
+		tests := []struct {
+			name      string
+			got, want float64
+		}{
+			{"Q_γ тройки", ResolutionQuality(g, triangles, gamma, NullModelNewmanGirvan), 6.0/7 - gamma/2},
+			{"Q_γ целиком", ResolutionQuality(g, whole, gamma, NullModelNewmanGirvan), 1 - gamma},
+			{"H_CPM тройки", ResolutionQuality(g, triangles, gamma, NullModelCPM), 6 - 6*gamma},
+			{"H_CPM целиком", ResolutionQuality(g, whole, gamma, NullModelCPM), 7 - 15*gamma},
+		}
+		for _, tt := range tests {
+			if math.Abs(tt.got-tt.want) > 1e-12 {
+				t.Errorf("γ = %g, %s = %g, ожидалось %g", gamma, tt.name, tt.got, tt.want)
+			}
+		}
+	}
+	if q := ResolutionQuality(g, triangles, 1, NullModelNewmanGirvan); q != ComputeModularity(g, triangles) {
+		t.Errorf("Q_1 = %g не совпадает с модулярностью %g", q, ComputeModularity(g, triangles))
+	}
+}
+
+// TestResolutionGrid - концы, равный шаг по логарифму и ошибки диапазона
+func TestResolutionGrid(t *testing.T) {
+	gammas, err := ResolutionGrid(0.1, 10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.1, math.Sqrt(0.1), 1, math.Sqrt(10), 10}
+	for i := range want {
+		if math.Abs(gammas[i]-want[i]) > 1e-12 {
+			t.Errorf("γ[%d] = %g, ожидалось %g", i, gammas[i], want[i])
+		}
+	}
+	if gammas, err := ResolutionGrid(2, 2, 7); err != nil || len(gammas) != 1 {
+		t.Errorf("вырожденный диапазон: %v, %v", gammas, err)
+	}
+	for _, bad := range [][3]float64{{0, 1, 3}, {2, 1, 3}, {0.1, 1, 0}} {
+		if _, err := ResolutionGrid(bad[0], bad[1], int(bad[2])); err == nil {
+			t.Errorf("диапазон %v должен быть отклонён", bad)
+		}
+	}
+}
+
+// TestFindPlateaus - плато из подряд совпадающих точек не короче minPoints
+func TestFindPlateaus(t *testing.T) {
+	same := []bool{false, true, true, false, false, true, false, true, true, true}
+	points := make([]ScanPoint, len(same))
+	for i := range points {
+		points[i] = ScanPoint{Gamma: float64(i + 1), SameAsPrev: same[i], Communities: i}
+	}
+	plateaus := FindPlateaus(points, 2)
+	want := [][2]int{{0, 2}, {4, 5}, {6, 9}}
+	if len(plateaus) != len(want) {
+		t.Fatalf("плато %v, ожидались %v", plateaus, want)
+	}
+	for i, p := range plateaus {
+		if p.Start != want[i][0] || p.End != want[i][1] || p.Communities != want[i][0] {
+			t.Errorf("плато %d: [%d, %d], ожидалось %v", i, p.Start, p.End, want[i])
+		}
+	}
+	if w := plateaus[2].Width(); math.Abs(w-math.Log10(10.0/7)) > 1e-12 || plateaus[2].Points() != 4 {
+		t.Errorf("плато γ 7..10: ширина %g декад и %d точек", w, plateaus[2].Points())
+	}
+	if got := len(FindPlateaus(points, 4)); got != 1 {
+		t.Errorf("плато из ≥ 4 точек: %d, ожидалось 1", got)
+	}
+}
+
+// TestResolutionScanRingOfCliques - на кольце клик клики - широкое плато вокруг γ = 1,
+// при большой γ выгодны только одиночки
+func TestResolutionScanRingOfCliques(t *testing.T) {
+	g := ringOfCliques(6, 5)
+	gammas, err := ResolutionGrid(0.05, 50, 13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := DefaultRunConfig()
+	base.Seed = 1
+	points, err := ResolutionScan(g, base, gammas, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != len(gammas) {
+		t.Fatalf("точек %d, ожидалось %d", len(points), len(gammas))
+	}
+	for i, p := range points {
+		if math.Abs(p.Quality-ResolutionQuality(g, p.Partition, p.Gamma, NullModelNewmanGirvan)) > 1e-12 {
+			t.Errorf("точка %d: Quality не равно Q_γ разбиения", i)
+		}
+		if i > 0 && p.SameAsPrev != (VariationOfInformation(p.Partition, points[i-1].Partition) <= 1e-12) {
+			t.Errorf("точка %d: SameAsPrev = %v при VI = %g", i, p.SameAsPrev, p.VIPrev)
+		}
+	}
+	if last := points[len(points)-1]; last.Communities != g.NumNodes() {
+		t.Errorf("при γ = %g сообществ %d, ожидались одиночки", last.Gamma, last.Communities)
+	}
+
+	plateaus := FindPlateaus(points, 3)
+	if len(plateaus) == 0 {
+		t.Fatal("плато не найдено")
+	}
+	widest := plateaus[0]
+	for _, p := range plateaus[1:] {
+		if p.Width() > widest.Width() {
+			widest = p
+		}
+	}
+	if widest.GammaFrom > 1 || widest.GammaTo < 1 || NMI(widest.Partition, CavemanCliques(6, 5)) < 1-1e-12 {
+		t.Errorf("самое широкое плато γ ∈ [%g, %g] с %d сообществами, ожидались клики около γ = 1",
+			widest.GammaFrom, widest.GammaTo, widest.Communities)
+	}
+}
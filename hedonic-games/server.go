@@ -0,0 +1,651 @@
+// server.go - локальный HTTP-сервис: загрузка графов, очередь запусков и их результаты
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// ОЧЕРЕДЬ ЗАПУСКОВ
+// ============================================================
+
+// maxGraphBytes - наибольший размер загружаемого графа
+const maxGraphBytes = 64 << 20
+
+// ServerOptions - параметры сервиса
+type ServerOptions struct {
+	QueueSize int // мест в очереди ожидающих запусков (0 - 16); сверх них запуск отклоняется
+	Workers   int // одновременно выполняемых запусков (0 - 1)
+	MaxGraphs int // хранимых графов (0 - 64); сверх них загрузка отклоняется до DELETE /graphs/{id}
+	MaxJobs   int // хранимых запусков (0 - 1024, не меньше QueueSize+Workers); сверх них забываются самые старые завершённые
+}
+
+// JobStatus - состояние запуска
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"    // ждёт в очереди
+	JobRunning   JobStatus = "running"   // выполняется
+	JobDone      JobStatus = "done"      // готовы разбиение и строка результатов
+	JobFailed    JobStatus = "failed"    // ошибка запуска (см. Error)
+	JobCancelled JobStatus = "cancelled" // отменён в очереди или во время выполнения
+)
+
+// finished - запуск больше не изменится
+func (s JobStatus) finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// storedGraph - загруженный граф с именами узлов
+type storedGraph struct {
+	ID       string
+	Name     string
+	Graph    *Graph
+	IDToName map[int]string
+}
+
+// Job - запуск RunExperiment на загруженном графе
+type Job struct {
+	ID        string
+	GraphID   string
+	Config    RunConfig
+	Status    JobStatus
+	Error     string
+	Submitted time.Time
+	Started   time.Time
+	Finished  time.Time
+	Partition *Partition        // итоговое разбиение (только при JobDone)
+	Result    *ExperimentResult // строка результатов (только при JobDone)
+
+	cancel    chan struct{} // закрывается при отмене (передаётся в RunConfig.Cancel)
+	cancelled bool          // канал cancel уже закрыт
+}
+
+// Server - графы, запуски и очередь с пулом исполнителей.
+// Все поля под mu; исполнители берут запуски из queue
+type Server struct {
+	mu        sync.Mutex
+	graphs    map[string]*storedGraph
+	jobs      map[string]*Job
+	queue     chan *Job
+	maxGraphs int
+	maxJobs   int
+	nextGraph int
+	nextJob   int
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer создаёт сервис и запускает исполнителей
+func NewServer(opts ServerOptions) *Server {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 16
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxGraphs <= 0 {
+		opts.MaxGraphs = 64
+	}
+	if opts.MaxJobs <= 0 {
+		opts.MaxJobs = 1024
+	}
+	// незавершённых запусков не больше QueueSize+Workers, поэтому при таком
+	// пределе место для нового запуска всегда освобождается вытеснением
+	opts.MaxJobs = max(opts.MaxJobs, opts.QueueSize+opts.Workers)
+	s := &Server{
+		graphs:    make(map[string]*storedGraph),
+		jobs:      make(map[string]*Job),
+		queue:     make(chan *Job, opts.QueueSize),
+		maxGraphs: opts.MaxGraphs,
+		maxJobs:   opts.MaxJobs,
+	}
+	for i := 0; i < opts.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Close отменяет ожидающие и выполняемые запуски и дожидается исполнителей
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, job := range s.jobs {
+		if !job.Status.finished() {
+			s.cancelJob(job)
+		}
+	}
+	close(s.queue)
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// AddGraph сохраняет граф и возвращает его номер ("g1", "g2", ...).
+// Сверх MaxGraphs - errGraphLimit
+func (s *Server) AddGraph(name string, g *Graph, idToName map[int]string) (*storedGraph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.graphs) >= s.maxGraphs {
+		return nil, errGraphLimit
+	}
+	s.nextGraph++
+	id := fmt.Sprintf("g%d", s.nextGraph)
+	if name == "" {
+		name = id
+	}
+	sg := &storedGraph{ID: id, Name: name, Graph: g, IDToName: idToName}
+	s.graphs[id] = sg
+	return sg, nil
+}
+
+// RemoveGraph удаляет граф вместе с его завершёнными запусками.
+// Пока у графа есть ожидающие или выполняемые запуски - errGraphBusy
+func (s *Server) RemoveGraph(graphID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.graphs[graphID] == nil {
+		return fmt.Errorf("граф %q не загружен", graphID)
+	}
+	for _, job := range s.jobs {
+		if job.GraphID == graphID && !job.Status.finished() {
+			return fmt.Errorf("%w: запуск %s (%s)", errGraphBusy, job.ID, job.Status)
+		}
+	}
+	for id, job := range s.jobs {
+		if job.GraphID == graphID {
+			delete(s.jobs, id)
+		}
+	}
+	delete(s.graphs, graphID)
+	return nil
+}
+
+var (
+	errQueueFull    = errors.New("очередь запусков заполнена")
+	errServerClosed = errors.New("сервис останавливается")
+	errGraphLimit   = errors.New("загружено предельное число графов (удалите ненужные: DELETE /graphs/{id})")
+	errGraphBusy    = errors.New("у графа есть незавершённые запуски")
+)
+
+// Submit ставит запуск в очередь. Зерно фиксируется сразу (0 - случайное),
+// чтобы статус показывал то, с которым будет получен результат
+func (s *Server) Submit(graphID string, cfg RunConfig) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errServerClosed
+	}
+	if s.graphs[graphID] == nil {
+		return nil, fmt.Errorf("граф %q не загружен", graphID)
+	}
+	cfg.Seed = ResolveSeed(cfg.Seed)
+	job := &Job{
+		ID:        fmt.Sprintf("j%d", s.nextJob+1),
+		GraphID:   graphID,
+		Config:    cfg,
+		Status:    JobQueued,
+		Submitted: time.Now(),
+		cancel:    make(chan struct{}),
+	}
+	job.Config.Cancel = job.cancel
+
+	select {
+	case s.queue <- job:
+	default:
+		return nil, errQueueFull
+	}
+	s.nextJob++
+	s.evictJobs()
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+// evictJobs забывает самые старые завершённые запуски, чтобы новый уместился
+// в MaxJobs (под s.mu)
+func (s *Server) evictJobs() {
+	if len(s.jobs) < s.maxJobs {
+		return
+	}
+	for _, id := range sortedIDs(s.jobs) {
+		if s.jobs[id].Status.finished() {
+			delete(s.jobs, id)
+			if len(s.jobs) < s.maxJobs {
+				return
+			}
+		}
+	}
+}
+
+// Cancel отменяет запуск: ожидающий снимается сразу, выполняемый прерывается
+// после текущего прохода динамики. Возвращает false, если запуск уже завершён
+func (s *Server) Cancel(job *Job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.Status.finished() {
+		return false
+	}
+	s.cancelJob(job)
+	return true
+}
+
+// cancelJob - отмена под s.mu
+func (s *Server) cancelJob(job *Job) {
+	if !job.cancelled {
+		close(job.cancel)
+		job.cancelled = true
+	}
+	if job.Status == JobQueued {
+		job.Status = JobCancelled
+		job.Finished = time.Now()
+	}
+}
+
+// worker выполняет запуски из очереди, пропуская отменённые
+func (s *Server) worker() {
+	defer s.wg.Done()
+	for job := range s.queue {
+		s.mu.Lock()
+		if job.Status != JobQueued {
+			s.mu.Unlock()
+			continue
+		}
+		job.Status = JobRunning
+		job.Started = time.Now()
+		g := s.graphs[job.GraphID].Graph
+		s.mu.Unlock()
+
+		partition, result, err := RunExperiment(g, job.Config)
+
+		s.mu.Lock()
+		job.Finished = time.Now()
+		switch {
+		case errors.Is(err, ErrCancelled):
+			job.Status = JobCancelled
+		case err != nil:
+			job.Status = JobFailed
+			job.Error = err.Error()
+		default:
+			job.Status = JobDone
+			job.Partition = partition
+			job.Result = &result
+		}
+		s.mu.Unlock()
+	}
+}
+
+// ============================================================
+// HTTP
+// ============================================================
+
+// GraphInfoJSON - описание загруженного графа
+type GraphInfoJSON struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Nodes    int    `json:"nodes"`
+	Edges    int    `json:"edges"`
+	Arcs     int    `json:"arcs,omitempty"` // только у ориентированного графа
+	Directed bool   `json:"directed"`
+	Weighted bool   `json:"weighted"`
+}
+
+// JobJSON - статус запуска
+type JobJSON struct {
+	ID          string    `json:"id"`
+	Graph       string    `json:"graph"`
+	Algorithm   string    `json:"algorithm"`
+	Seed        int64     `json:"seed"`
+	Status      JobStatus `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Submitted   string    `json:"submitted"`
+	Started     string    `json:"started,omitempty"`
+	Finished    string    `json:"finished,omitempty"`
+	Communities int       `json:"communities,omitempty"` // только при done
+}
+
+// JobRequest - тело POST /jobs: граф и параметры запуска под именами флагов
+// run без дефиса ({"algo": "ml", "beta": 2, "k": 3, "seed": 7}); остальные -
+// как в DefaultRunConfig
+type JobRequest struct {
+	Graph  string                     `json:"graph"`
+	Params map[string]json.RawMessage `json:"params"`
+}
+
+// Handler - маршруты сервиса:
+//
+//	POST   /graphs[?name=имя]      загрузить node-link JSON (как AMteachers)
+//	GET    /graphs                 список графов
+//	GET    /graphs/{id}            описание графа
+//	DELETE /graphs/{id}            удалить граф и его завершённые запуски (409, пока есть незавершённые)
+//	POST   /jobs                   поставить запуск в очередь (JobRequest)
+//	GET    /jobs                   список запусков
+//	GET    /jobs/{id}              статус запуска
+//	DELETE /jobs/{id}              отменить запуск
+//	GET    /jobs/{id}/partition    разбиение в формате PartitionJSON
+//	GET    /jobs/{id}/result       строка ExperimentResult (?format=csv - как в SaveResultsToCSV)
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphs", s.handleUploadGraph)
+	mux.HandleFunc("GET /graphs", s.handleListGraphs)
+	mux.HandleFunc("GET /graphs/{id}", s.handleGetGraph)
+	mux.HandleFunc("DELETE /graphs/{id}", s.handleDeleteGraph)
+	mux.HandleFunc("POST /jobs", s.handleSubmitJob)
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleCancelJob)
+	mux.HandleFunc("GET /jobs/{id}/partition", s.handleJobPartition)
+	mux.HandleFunc("GET /jobs/{id}/result", s.handleJobResult)
+	return mux
+}
+
+func (s *Server) handleUploadGraph(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGraphBytes))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return
+	}
+	var teachers AMteachers
+	if err := json.Unmarshal(data, &teachers); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Ошибка парсинга JSON: %w", err))
+		return
+	}
+	if len(teachers.Nodes) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("в графе нет узлов"))
+		return
+	}
+	g, _, idToName, missing := teachers.toGraph()
+	if len(missing) > 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("узел %s не найден", missing[0]))
+		return
+	}
+	sg, err := s.AddGraph(r.URL.Query().Get("name"), g, idToName)
+	if err != nil {
+		writeError(w, http.StatusInsufficientStorage, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, graphInfo(sg))
+}
+
+func (s *Server) handleListGraphs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]GraphInfoJSON, 0, len(s.graphs))
+	for _, id := range sortedIDs(s.graphs) {
+		infos = append(infos, graphInfo(s.graphs[id]))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleGetGraph(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sg := s.graphs[r.PathValue("id")]
+	if sg == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("граф %q не загружен", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, graphInfo(sg))
+}
+
+func (s *Server) handleDeleteGraph(w http.ResponseWriter, r *http.Request) {
+	err := s.RemoveGraph(r.PathValue("id"))
+	switch {
+	case errors.Is(err, errGraphBusy):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusNotFound, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var req JobRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Ошибка парсинга JSON: %w", err))
+		return
+	}
+	cfg, err := runConfigFromParams(req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := s.Submit(req.Graph, cfg)
+	switch {
+	case errors.Is(err, errQueueFull), errors.Is(err, errServerClosed):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, jobInfo(job))
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]JobJSON, 0, len(s.jobs))
+	for _, id := range sortedIDs(s.jobs) {
+		infos = append(infos, jobInfo(s.jobs[id]))
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job := s.lookupJob(w, r); job != nil {
+		writeJSON(w, http.StatusOK, jobInfo(job))
+	}
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job := s.lookupJob(w, r)
+	s.mu.Unlock()
+	if job == nil {
+		return
+	}
+	ok := s.Cancel(job)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusConflict, fmt.Errorf("запуск %s уже завершён (%s)", job.ID, job.Status))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, jobInfo(job))
+}
+
+func (s *Server) handleJobPartition(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.lookupDoneJob(w, r)
+	if job == nil {
+		return
+	}
+	sg := s.graphs[job.GraphID]
+	writeJSON(w, http.StatusOK, partitionJSON(sg.Graph, job.Partition, sg.IDToName))
+}
+
+func (s *Server) handleJobResult(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.lookupDoneJob(w, r)
+	if job == nil {
+		return
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		writeJSON(w, http.StatusOK, job.Result)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writeResultsCSV(w, []ExperimentResult{*job.Result})
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("неизвестный формат %q (json, csv)", format))
+	}
+}
+
+// lookupJob - запуск из пути запроса (под s.mu); при ошибке ответ уже записан
+func (s *Server) lookupJob(w http.ResponseWriter, r *http.Request) *Job {
+	job := s.jobs[r.PathValue("id")]
+	if job == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("запуск %q не найден", r.PathValue("id")))
+	}
+	return job
+}
+
+// lookupDoneJob - завершённый успешно запуск из пути запроса (под s.mu)
+func (s *Server) lookupDoneJob(w http.ResponseWriter, r *http.Request) *Job {
+	job := s.lookupJob(w, r)
+	if job != nil && job.Status != JobDone {
+		writeError(w, http.StatusConflict, fmt.Errorf("запуск %s не готов (%s)", job.ID, job.Status))
+		return nil
+	}
+	return job
+}
+
+// runConfigFromParams - конфигурация запуска из параметров JobRequest:
+// значения разбираются теми же флагами, что у run (addRunFlags)
+func runConfigFromParams(params map[string]json.RawMessage) (RunConfig, error) {
+	cfg := DefaultRunConfig()
+	fs := flag.NewFlagSet("job", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addRunFlags(fs, &cfg)
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return cfg, fmt.Errorf("неизвестный параметр %q", name)
+		}
+		value := strings.TrimSpace(string(params[name]))
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		if err := fs.Set(name, value); err != nil {
+			return cfg, fmt.Errorf("параметр %q: %w", name, err)
+		}
+	}
+	switch cfg.Algorithm {
+	case "hedonic", "ml", "louvain", "leiden":
+	default:
+		return cfg, fmt.Errorf("неизвестный алгоритм %q", cfg.Algorithm)
+	}
+	return cfg, nil
+}
+
+// graphInfo - описание графа для ответа
+func graphInfo(sg *storedGraph) GraphInfoJSON {
+	info := GraphInfoJSON{
+		ID:       sg.ID,
+		Name:     sg.Name,
+		Nodes:    sg.Graph.NumNodes(),
+		Edges:    sg.Graph.NumEdges(),
+		Directed: sg.Graph.Directed,
+		Weighted: sg.Graph.IsWeighted(),
+	}
+	if sg.Graph.Directed {
+		info.Arcs = sg.Graph.NumArcs()
+	}
+	return info
+}
+
+// jobInfo - статус запуска для ответа (под s.mu)
+func jobInfo(job *Job) JobJSON {
+	info := JobJSON{
+		ID:        job.ID,
+		Graph:     job.GraphID,
+		Algorithm: job.Config.Algorithm,
+		Seed:      job.Config.Seed,
+		Status:    job.Status,
+		Error:     job.Error,
+		Submitted: job.Submitted.Format(time.RFC3339),
+	}
+	if !job.Started.IsZero() {
+		info.Started = job.Started.Format(time.RFC3339)
+	}
+	if !job.Finished.IsZero() {
+		info.Finished = job.Finished.Format(time.RFC3339)
+	}
+	if job.Result != nil {
+		info.Communities = job.Result.Communities
+	}
+	return info
+}
+
+// sortedIDs - номера графов или запусков по порядку выдачи ("g2" раньше "g10")
+func sortedIDs[V any](m map[string]V) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// writeJSON пишет ответ; если значение не кодируется (NaN в истории отжига), - 500
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("JSON error: %v", err)})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// checkLoopback - сервис слушает только локальный адрес (localhost, 127.0.0.1, ::1)
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("некорректный адрес %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("сервис слушает только localhost, а не %q", host)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// twoTrianglesJSON - две тройки a-b-c и d-e-f, соединённые ребром c-d (node-link JSON)
+const twoTrianglesJSON = `{"directed": false, "nodes": [{"id": "a"}, {"id": "b"}, {"id": "c"}, {"id": "d"}, {"id": "e"}, {"id": "f"}],
+"edges": [{"source": "a", "target": "b"}, {"source": "b", "target": "c"}, {"source": "a", "target": "c"},
+{"source": "d", "target": "e"}, {"source": "e", "target": "f"}, {"source": "d", "target": "f"}, {"source": "c", "target": "d"}]}`
+
+// call выполняет запрос к сервису и разбирает JSON-ответ в out (nil - не разбирать)
+func call(t *testing.T, srv *httptest.Server, method, path, body string, wantStatus int, out any) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		var e map[string]string
+		json.NewDecoder(resp.Body).Decode(&e)
+		t.Fatalf("%s %s: статус %d, ожидался %d (%s)", method, path, resp.StatusCode, wantStatus, e["error"])
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+// waitJob опрашивает запуск, пока условие не выполнится
+func waitJob(t *testing.T, srv *httptest.Server, id string, done func(JobJSON) bool) JobJSON {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job JobJSON
+		call(t, srv, "GET", "/jobs/"+id, "", http.StatusOK, &job)
+		if done(job) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("запуск %s так и остался %s", id, job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestServerJobLifecycle - загрузка графа, запуск, разбиение и строка результатов
+func TestServerJobLifecycle(t *testing.T) {
+	s := NewServer(ServerOptions{})
+	defer s.Close()
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	var info GraphInfoJSON
+	call(t, srv, "POST", "/graphs?name=triangles", twoTrianglesJSON, http.StatusCreated, &info)
+	if info.ID != "g1" || info.Name != "triangles" || info.Nodes != 6 || info.Edges != 7 {
+		t.Fatalf("описание графа %+v", info)
+	}
+
+	var job JobJSON
+	resp := call(t, srv, "POST", "/jobs", `{"graph": "g1", "params": {"algo": "louvain", "seed": 3}}`, http.StatusAccepted, &job)
+	if resp.Header.Get("Location") != "/jobs/"+job.ID || job.Seed != 3 {
+		t.Errorf("запуск %+v, Location %q", job, resp.Header.Get("Location"))
+	}
+	job = waitJob(t, srv, job.ID, func(j JobJSON) bool { return j.Status.finished() })
+	if job.Status != JobDone || job.Communities != 2 {
+		t.Fatalf("запуск завершился %+v", job)
+	}
+
+	var partition PartitionJSON
+	call(t, srv, "GET", "/jobs/"+job.ID+"/partition", "", http.StatusOK, &partition)
+	community := make(map[string]int)
+	for _, node := range partition.Nodes {
+		community[node.ID] = node.Community
+	}
+	if len(community) != 6 || community["a"] != community["c"] || community["d"] != community["f"] || community["a"] == community["d"] {
+		t.Errorf("разбиение по именам %v", community)
+	}
+
+	var result ExperimentResult
+	call(t, srv, "GET", "/jobs/"+job.ID+"/result", "", http.StatusOK, &result)
+	if result.Seed != 3 || result.Communities != 2 {
+		t.Errorf("строка результатов %+v", result)
+	}
+	resp, err := srv.Client().Get(srv.URL + "/jobs/" + job.ID + "/result?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	resp.Body.Close()
+	if err != nil || len(records) != 2 || records[0][0] != "TestName" {
+		t.Errorf("CSV результатов: %v, %v", records, err)
+	}
+
+	call(t, srv, "DELETE", "/jobs/"+job.ID, "", http.StatusConflict, nil) // уже завершён
+	call(t, srv, "DELETE", "/graphs/g1", "", http.StatusNoContent, nil)
+	call(t, srv, "GET", "/jobs/"+job.ID, "", http.StatusNotFound, nil) // удалён вместе с графом
+}
+
+// TestServerRejectsBadRequests - ошибки запроса получают свой статус
+func TestServerRejectsBadRequests(t *testing.T) {
+	s := NewServer(ServerOptions{MaxGraphs: 1})
+	defer s.Close()
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	call(t, srv, "POST", "/graphs", `{"nodes": []}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/graphs", `{"nodes": [{"id": "a"}], "edges": [{"source": "a", "target": "z"}]}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/graphs", twoTrianglesJSON, http.StatusCreated, nil)
+	call(t, srv, "POST", "/graphs", twoTrianglesJSON, http.StatusInsufficientStorage, nil) // MaxGraphs
+	call(t, srv, "GET", "/graphs/g7", "", http.StatusNotFound, nil)
+
+	call(t, srv, "POST", "/jobs", `{"graph": "g7", "params": {}}`, http.StatusNotFound, nil)
+	call(t, srv, "POST", "/jobs", `{"graph": "g1", "params": {"algo": "kmeans"}}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/jobs", `{"graph": "g1", "params": {"colour": 1}}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/jobs", `{"graph": "g1", "params": {"iter": "many"}}`, http.StatusBadRequest, nil)
+	call(t, srv, "POST", "/jobs", `{"graph": "g1", "extra": true}`, http.StatusBadRequest, nil)
+	call(t, srv, "GET", "/jobs/j9", "", http.StatusNotFound, nil)
+}
+
+// TestServerQueueAndCancel - полная очередь отклоняет запуск, ожидающий запуск
+// отменяется сразу, выполняемый - после текущего прохода; граф с незавершёнными
+// запусками не удаляется
+func TestServerQueueAndCancel(t *testing.T) {
+	s := NewServer(ServerOptions{QueueSize: 1, Workers: 1})
+	defer s.Close()
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	call(t, srv, "POST", "/graphs", twoTrianglesJSON, http.StatusCreated, nil)
+
+	// ML на миллиард проходов занимает исполнителя, пока его не отменят
+	long := `{"graph": "g1", "params": {"algo": "ml", "iter": 1000000000}}`
+	var running, queued JobJSON
+	call(t, srv, "POST", "/jobs", long, http.StatusAccepted, &running)
+	waitJob(t, srv, running.ID, func(j JobJSON) bool { return j.Status == JobRunning })
+	call(t, srv, "POST", "/jobs", long, http.StatusAccepted, &queued)
+	call(t, srv, "POST", "/jobs", long, http.StatusServiceUnavailable, nil)
+	call(t, srv, "DELETE", "/graphs/g1", "", http.StatusConflict, nil)
+
+	var cancelled JobJSON
+	call(t, srv, "DELETE", "/jobs/"+queued.ID, "", http.StatusAccepted, &cancelled)
+	if cancelled.Status != JobCancelled {
+		t.Errorf("ожидающий запуск после отмены %s", cancelled.Status)
+	}
+	call(t, srv, "DELETE", "/jobs/"+running.ID, "", http.StatusAccepted, nil)
+	waitJob(t, srv, running.ID, func(j JobJSON) bool { return j.Status == JobCancelled })
+	call(t, srv, "GET", "/jobs/"+running.ID+"/result", "", http.StatusConflict, nil)
+
+	// исполнитель освободился и берёт следующий запуск
+	var next JobJSON
+	call(t, srv, "POST", "/jobs", `{"graph": "g1", "params": {"algo": "leiden"}}`, http.StatusAccepted, &next)
+	waitJob(t, srv, next.ID, func(j JobJSON) bool { return j.Status == JobDone })
+	call(t, srv, "DELETE", "/graphs/g1", "", http.StatusNoContent, nil)
+}
+
+// TestServerEvictsFinishedJobs - сверх MaxJobs забываются самые старые завершённые запуски
+func TestServerEvictsFinishedJobs(t *testing.T) {
+	s := NewServer(ServerOptions{QueueSize: 1, Workers: 1, MaxJobs: 2})
+	defer s.Close()
+	g := LoadKarateClub()
+	if _, err := s.AddGraph("karate", g, nil); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 4; i++ {
+		job, err := s.Submit("g1", RunConfig{Algorithm: "louvain", Seed: int64(i + 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+		for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(time.Millisecond) {
+			s.mu.Lock()
+			status := job.Status
+			s.mu.Unlock()
+			if status.finished() {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("запуск %s не завершился", job.ID)
+			}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if got := fmt.Sprint(sortedIDs(s.jobs)); got != fmt.Sprint(ids[2:]) {
+		t.Errorf("хранятся запуски %s, ожидались %v", got, ids[2:])
+	}
+}
@@ -0,0 +1,380 @@
+// stability.go - индивидуальная, контрактно-индивидуальная устойчивость и ядро
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+)
+
+// EmptyCoalition - цель отклонения "уйти в новую одиночную коалицию"
+const EmptyCoalition = -1
+
+// DefaultCoreExactLimit - до скольких агентов-кандидатов ядро проверяется полным перебором
+const DefaultCoreExactLimit = 20
+
+const stabilityEps = 1e-9
+
+// Deviation - отклонение, нарушающее устойчивость (свидетель)
+type Deviation struct {
+	Concept   string  // "IS", "CIS" или "core"
+	Node      int     // отклоняющийся агент
+	From      int     // его текущее сообщество
+	To        int     // сообщество, куда он уходит (EmptyCoalition - в одиночку)
+	Coalition []int   // блокирующая коалиция (для ядра)
+	Gain      float64 // выигрыш агента; для ядра - минимальный выигрыш по коалиции
+}
+
+func (d *Deviation) String() string {
+	if d.Concept == "core" {
+		return fmt.Sprintf("core: коалиция %v блокирует разбиение (мин. выигрыш %.4f)", d.Coalition, d.Gain)
+	}
+	to := fmt.Sprintf("C%d", d.To)
+	if d.To == EmptyCoalition {
+		to = "новую коалицию"
+	}
+	return fmt.Sprintf("%s: узел %d уходит из C%d в %s (выигрыш %.4f)", d.Concept, d.Node, d.From, to, d.Gain)
+}
+
+// currentUtilities - полезность каждого агента в его текущей коалиции
+func (hg *HedonicGame) currentUtilities(comms map[int][]int) map[int]float64 {
+	utilities := make(map[int]float64, hg.Partition.Len())
+	for _, members := range comms {
+		for _, node := range members {
+			utilities[node] = hg.coalitionUtility(node, members)
+		}
+	}
+	return utilities
+}
+
+// ============================================================
+// ИНДИВИДУАЛЬНАЯ УСТОЙЧИВОСТЬ (IS, CIS)
+// ============================================================
+
+// IsIndividuallyStable проверяет индивидуальную устойчивость:
+// ни один агент не может перейти в коалицию T (или уйти в одиночку) с выгодой для себя так,
+// чтобы ни один член T не проиграл от его прихода
+func (hg *HedonicGame) IsIndividuallyStable() (bool, *Deviation) {
+	return hg.checkIndividualDeviations(false)
+}
+
+// IsContractuallyIndividuallyStable проверяет контрактно-индивидуальную устойчивость:
+// как IS, но уход агента дополнительно не должен ухудшать положение оставшихся в его коалиции
+func (hg *HedonicGame) IsContractuallyIndividuallyStable() (bool, *Deviation) {
+	return hg.checkIndividualDeviations(true)
+}
+
+func (hg *HedonicGame) checkIndividualDeviations(contractual bool) (bool, *Deviation) {
+	comms := hg.Partition.Groups()
+	commIDs := hg.Partition.Communities()
+	current := hg.currentUtilities(comms)
+
+	concept := "IS"
+	if contractual {
+		concept = "CIS"
+	}
+
+	for _, node := range hg.Partition.Nodes() {
+		from := hg.Partition.Community(node)
+		rest := slices.DeleteFunc(slices.Clone(comms[from]), func(j int) bool { return j == node })
+
+		// CIS: оставшиеся должны согласиться отпустить агента
+		if contractual && !hg.allWeaklyPrefer(rest, current) {
+			continue
+		}
+
+		targets := make([]int, 0, len(commIDs)+1)
+		for _, comm := range commIDs {
+			if comm != from {
+				targets = append(targets, comm)
+			}
+		}
+		if len(rest) > 0 {
+			targets = append(targets, EmptyCoalition)
+		}
+
+		for _, to := range targets {
+			var members []int
+			if to != EmptyCoalition {
+				members = comms[to]
+			}
+			joined := append(slices.Clone(members), node)
+
+			gain := hg.coalitionUtility(node, joined) - current[node]
+			if gain <= stabilityEps {
+				continue
+			}
+
+			// Члены T должны принять агента (никто не проигрывает)
+			if hg.allWeaklyPrefer(members, current, node) {
+				return false, &Deviation{
+					Concept: concept,
+					Node:    node,
+					From:    from,
+					To:      to,
+					Gain:    gain,
+				}
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// allWeaklyPrefer проверяет, что каждый из members не хуже в коалиции members ∪ extra,
+// чем в своей текущей
+func (hg *HedonicGame) allWeaklyPrefer(members []int, current map[int]float64, extra ...int) bool {
+	coalition := append(slices.Clone(members), extra...)
+	for _, j := range members {
+		if hg.coalitionUtility(j, coalition) < current[j]-stabilityEps {
+			return false
+		}
+	}
+	return true
+}
+
+// ============================================================
+// ЯДРО (CORE STABILITY)
+// ============================================================
+
+// IsCoreStable ищет блокирующую коалицию: такую C, что каждый её член строго
+// предпочитает C своей текущей коалиции.
+// Если агентов, способных что-то выиграть, не больше exactLimit - полный перебор
+// связных коалиций, иначе - эвристический поиск (отсечение и жадный рост от каждого агента),
+// который может пропустить блокирующую коалицию.
+func (hg *HedonicGame) IsCoreStable(exactLimit int) (bool, *Deviation) {
+	comms := hg.Partition.Groups()
+	current := hg.currentUtilities(comms)
+
+	// Кандидаты - агенты, которые в принципе могут улучшить свою полезность
+	var candidates []int
+	for _, node := range hg.Partition.Nodes() {
+		if hg.utilityUpperBound(node) > current[node]+stabilityEps {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(candidates) == 0 {
+		return true, nil
+	}
+
+	var coalition []int
+	if len(candidates) <= exactLimit {
+		coalition = hg.exactBlockingCoalition(candidates, current)
+	} else {
+		coalition = hg.heuristicBlockingCoalition(candidates, current)
+	}
+	if coalition == nil {
+		return true, nil
+	}
+
+	slices.Sort(coalition)
+	_, minGain := hg.blockingMargin(coalition, current)
+	return false, &Deviation{
+		Concept:   "core",
+		Node:      coalition[0],
+		From:      hg.Partition.Community(coalition[0]),
+		To:        EmptyCoalition,
+		Coalition: coalition,
+		Gain:      minGain,
+	}
+}
+
+// utilityUpperBound - верхняя оценка полезности агента в любой коалиции
+// (для Preference без собственной оценки - +Inf, кандидатами становятся все)
+func (hg *HedonicGame) utilityUpperBound(node int) float64 {
+	if hg.Preference != nil {
+		if bounded, ok := hg.Preference.(boundedPreference); ok {
+			return bounded.UpperBound(&hg.G, node)
+		}
+		return math.Inf(1)
+	}
+
+	bound := 0.0
+	for _, neighbor := range hg.G.GetNeighbors(node) {
+		if w := hg.G.Weight(node, neighbor); w > 0 && neighbor != node {
+			bound += w
+		}
+	}
+	if hg.Alpha < 0 {
+		// незнакомцы приносят выгоду - оценка через всех агентов
+		bound -= hg.Alpha * float64(hg.G.NumNodes()-1)
+	}
+	return bound
+}
+
+// blockingMargin возвращает, блокирует ли коалиция, и минимальный выигрыш её членов
+func (hg *HedonicGame) blockingMargin(coalition []int, current map[int]float64) (bool, float64) {
+	minGain := 0.0
+	for i, node := range coalition {
+		gain := hg.coalitionUtility(node, coalition) - current[node]
+		if i == 0 || gain < minGain {
+			minGain = gain
+		}
+	}
+	return len(coalition) > 0 && minGain > stabilityEps, minGain
+}
+
+// exactBlockingCoalition перебирает коалиции из кандидатов.
+// При α ≥ 0 и положительных весах достаточно связных коалиций: отбрасывание
+// других компонент убирает только незнакомцев. Иначе (и для произвольной Preference)
+// перебираются все подмножества.
+func (hg *HedonicGame) exactBlockingCoalition(candidates []int, current map[int]float64) []int {
+	if hg.Preference != nil || hg.Alpha < 0 || hg.hasNonPositiveWeights() {
+		n := len(candidates)
+		for mask := 1; mask < 1<<n; mask++ {
+			var coalition []int
+			for i := 0; i < n; i++ {
+				if mask&(1<<i) != 0 {
+					coalition = append(coalition, candidates[i])
+				}
+			}
+			if ok, _ := hg.blockingMargin(coalition, current); ok {
+				return coalition
+			}
+		}
+		return nil
+	}
+
+	// Перечисление связных подмножеств (ESU): каждое подмножество ровно один раз
+	inCandidates := make(map[int]bool, len(candidates))
+	for _, node := range candidates {
+		inCandidates[node] = true
+	}
+	neighbors := func(node int) []int {
+		var result []int
+		for _, nghbr := range hg.G.GetNeighbors(node) {
+			if inCandidates[nghbr] && nghbr != node {
+				result = append(result, nghbr)
+			}
+		}
+		return result
+	}
+
+	var found []int
+	var extend func(sub []int, inSub, nearSub map[int]bool, ext []int, root int) bool
+	extend = func(sub []int, inSub, nearSub map[int]bool, ext []int, root int) bool {
+		if ok, _ := hg.blockingMargin(sub, current); ok {
+			found = slices.Clone(sub)
+			return true
+		}
+		for len(ext) > 0 {
+			w := ext[0]
+			ext = ext[1:]
+
+			nextExt := slices.Clone(ext)
+			var added []int
+			for _, u := range neighbors(w) {
+				if u > root && !inSub[u] && !nearSub[u] {
+					nextExt = append(nextExt, u)
+					added = append(added, u)
+				}
+			}
+
+			inSub[w] = true
+			for _, u := range added {
+				nearSub[u] = true
+			}
+			if extend(append(sub, w), inSub, nearSub, nextExt, root) {
+				return true
+			}
+			inSub[w] = false
+			for _, u := range added {
+				nearSub[u] = false
+			}
+		}
+		return false
+	}
+
+	for _, root := range candidates {
+		inSub := map[int]bool{root: true}
+		nearSub := map[int]bool{root: true}
+		var ext []int
+		for _, u := range neighbors(root) {
+			nearSub[u] = true
+			if u > root {
+				ext = append(ext, u)
+			}
+		}
+		if extend([]int{root}, inSub, nearSub, ext, root) {
+			return found
+		}
+	}
+	return nil
+}
+
+func (hg *HedonicGame) hasNonPositiveWeights() bool {
+	for u := range hg.G.Weights {
+		for _, w := range hg.G.Weights[u] {
+			if w <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// heuristicBlockingCoalition - поиск блокирующей коалиции от каждого агента:
+// отсечение худших из замкнутой окрестности и жадный рост по соседям
+func (hg *HedonicGame) heuristicBlockingCoalition(candidates []int, current map[int]float64) []int {
+	const maxGrowth = 64
+
+	inCandidates := make(map[int]bool, len(candidates))
+	for _, node := range candidates {
+		inCandidates[node] = true
+	}
+
+	for _, seed := range candidates {
+		// 1. Отсечение: начинаем с агента и его соседей-кандидатов и убираем
+		// члена с наименьшим выигрышем, пока коалиция не станет блокирующей
+		coalition := []int{seed}
+		for _, nghbr := range hg.G.GetNeighbors(seed) {
+			if inCandidates[nghbr] && nghbr != seed {
+				coalition = append(coalition, nghbr)
+			}
+		}
+		for len(coalition) > 0 {
+			if ok, _ := hg.blockingMargin(coalition, current); ok {
+				return coalition
+			}
+			worst, worstGain := 0, 0.0
+			for i, node := range coalition {
+				gain := hg.coalitionUtility(node, coalition) - current[node]
+				if i == 0 || gain < worstGain {
+					worst, worstGain = i, gain
+				}
+			}
+			coalition = slices.Delete(coalition, worst, worst+1)
+		}
+
+		// 2. Рост: добавляем соседа, максимизирующего минимальный выигрыш
+		coalition = []int{seed}
+		inCoalition := map[int]bool{seed: true}
+		for len(coalition) < maxGrowth {
+			best, bestGain := -1, 0.0
+			frontier := make(map[int]bool)
+			for _, member := range coalition {
+				for _, nghbr := range hg.G.GetNeighbors(member) {
+					if inCandidates[nghbr] && !inCoalition[nghbr] {
+						frontier[nghbr] = true
+					}
+				}
+			}
+			for _, w := range slices.Sorted(maps.Keys(frontier)) {
+				_, gain := hg.blockingMargin(append(slices.Clone(coalition), w), current)
+				if best < 0 || gain > bestGain {
+					best, bestGain = w, gain
+				}
+			}
+			if best < 0 {
+				break
+			}
+			coalition = append(coalition, best)
+			inCoalition[best] = true
+			if bestGain > stabilityEps {
+				return coalition
+			}
+		}
+	}
+	return nil
+}
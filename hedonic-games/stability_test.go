@@ -0,0 +1,157 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// stabilityGame - игра друзья/незнакомцы с заданным разбиением
+func stabilityGame(g *Graph, alpha float64, comms ...int) *HedonicGame {
+	hg := NewHedonicGame(*g, alpha)
+	hg.Partition = partitionOf(comms...)
+	return hg
+}
+
+// TestStabilityConcepts - IS, CIS и ядро на разбиениях с известным ответом
+func TestStabilityConcepts(t *testing.T) {
+	// две тройки, соединённые мостом 2-3
+	bridged := edgeGraph(6, [2]int{0, 1}, [2]int{1, 2}, [2]int{0, 2}, [2]int{3, 4}, [2]int{4, 5}, [2]int{3, 5}, [2]int{2, 3})
+	// 1 дружит с 0 и с кликой 3,4,5: к клике уйти выгодно, но 0 его не отпустит
+	possessive := edgeGraph(6, [2]int{0, 1}, [2]int{1, 3}, [2]int{1, 4}, [2]int{1, 5}, [2]int{3, 4}, [2]int{4, 5}, [2]int{3, 5})
+	// 0 и 1 сильно дружат, но сидят с 2 и 3: по одному к другому не пустят, вдвоём уйдут
+	pairs := edgeGraph(4, [2]int{0, 2}, [2]int{1, 3})
+	pairs.AddWeightedEdge(0, 1, 3)
+
+	tests := []struct {
+		name          string
+		game          *HedonicGame
+		is, cis, core bool
+		coalition     []int // ожидаемая блокирующая коалиция
+	}{
+		{"bridged triangles", stabilityGame(bridged, 0.5, 0, 0, 0, 1, 1, 1), true, true, true, nil},
+		{"path singletons", stabilityGame(edgeGraph(3, [2]int{0, 1}, [2]int{1, 2}), 0.5, 0, 1, 2), false, false, false, nil},
+		{"possessive friend", stabilityGame(possessive, 0.5, 0, 0, 1, 2, 2, 2), false, true, false, nil},
+		{"pair deviation", stabilityGame(pairs, 0.5, 0, 1, 0, 1), true, true, false, []int{0, 1}},
+		{"grand coalition of strangers", stabilityGame(edgeGraph(3), 0.5, 0, 0, 0), false, false, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is, dev := tt.game.IsIndividuallyStable()
+			if is != tt.is {
+				t.Errorf("IS = %v, ожидалось %v (%v)", is, tt.is, dev)
+			}
+			checkIndividualWitness(t, tt.game, dev, false)
+
+			cis, dev := tt.game.IsContractuallyIndividuallyStable()
+			if cis != tt.cis {
+				t.Errorf("CIS = %v, ожидалось %v (%v)", cis, tt.cis, dev)
+			}
+			checkIndividualWitness(t, tt.game, dev, true)
+
+			core, dev := tt.game.IsCoreStable(DefaultCoreExactLimit)
+			if core != tt.core {
+				t.Errorf("core = %v, ожидалось %v (%v)", core, tt.core, dev)
+			}
+			checkCoreWitness(t, tt.game, dev)
+			if tt.coalition != nil && (dev == nil || !slices.Equal(dev.Coalition, tt.coalition)) {
+				t.Errorf("блокирующая коалиция %v, ожидалась %v", dev, tt.coalition)
+			}
+		})
+	}
+}
+
+// checkIndividualWitness проверяет, что свидетель IS/CIS - настоящее отклонение
+func checkIndividualWitness(t *testing.T, hg *HedonicGame, dev *Deviation, contractual bool) {
+	t.Helper()
+	if dev == nil {
+		return
+	}
+	groups := hg.Partition.Groups()
+	current := hg.currentUtilities(groups)
+	var target []int
+	if dev.To != EmptyCoalition {
+		target = groups[dev.To]
+	}
+	if gain := hg.coalitionUtility(dev.Node, append(slices.Clone(target), dev.Node)) - current[dev.Node]; gain <= stabilityEps {
+		t.Errorf("%v: агент не выигрывает (%g)", dev, gain)
+	}
+	if !hg.allWeaklyPrefer(target, current, dev.Node) {
+		t.Errorf("%v: кто-то в принимающей коалиции проигрывает", dev)
+	}
+	rest := slices.DeleteFunc(slices.Clone(groups[dev.From]), func(j int) bool { return j == dev.Node })
+	if contractual && !hg.allWeaklyPrefer(rest, current) {
+		t.Errorf("%v: оставшиеся проигрывают от ухода агента", dev)
+	}
+}
+
+// checkCoreWitness проверяет, что каждый член коалиции-свидетеля строго выигрывает
+func checkCoreWitness(t *testing.T, hg *HedonicGame, dev *Deviation) {
+	t.Helper()
+	if dev == nil {
+		return
+	}
+	current := hg.currentUtilities(hg.Partition.Groups())
+	if ok, gain := hg.blockingMargin(dev.Coalition, current); !ok {
+		t.Errorf("%v: коалиция не блокирует (мин. выигрыш %g)", dev, gain)
+	}
+}
+
+// TestCoreAgainstBruteForce - на малых случайных играх проверка ядра совпадает
+// с перебором всех подмножеств, эвристика не находит ложных блокирующих коалиций,
+// а равновесие динамики улучшающих ответов IS и CIS-устойчиво
+func TestCoreAgainstBruteForce(t *testing.T) {
+	rng := NewRand(11)
+	for trial := 0; trial < 60; trial++ {
+		n := 4 + rng.Intn(4)
+		g := NewGraph()
+		for u := 0; u < n; u++ {
+			g.AddNode(u)
+		}
+		for u := 0; u < n; u++ {
+			for v := u + 1; v < n; v++ {
+				if rng.Float64() < 0.4 {
+					g.AddWeightedEdge(u, v, float64(1+rng.Intn(3)))
+				}
+			}
+		}
+		comms := make([]int, n)
+		for u := range comms {
+			comms[u] = rng.Intn(3)
+		}
+		alpha := []float64{0.3, 1, -0.2}[trial%3]
+		hg := stabilityGame(g, alpha, comms...)
+
+		current := hg.currentUtilities(hg.Partition.Groups())
+		blocked := false
+		for mask := 1; mask < 1<<n && !blocked; mask++ {
+			var coalition []int
+			for u := 0; u < n; u++ {
+				if mask&(1<<u) != 0 {
+					coalition = append(coalition, u)
+				}
+			}
+			blocked, _ = hg.blockingMargin(coalition, current)
+		}
+
+		exact, dev := hg.IsCoreStable(DefaultCoreExactLimit)
+		if exact == blocked {
+			t.Errorf("испытание %d (n=%d, α=%g): core = %v, перебор: блокирующая коалиция есть = %v", trial, n, alpha, exact, blocked)
+		}
+		checkCoreWitness(t, hg, dev)
+		_, dev = hg.IsCoreStable(0) // только эвристика
+		checkCoreWitness(t, hg, dev)
+
+		if alpha >= 0 {
+			hg.SetSeed(int64(trial))
+			if _, err := hg.FindStablePartition_BetterResponse(200, SchedulerRoundRobin); err != nil {
+				t.Fatal(err)
+			}
+			if ok, dev := hg.IsIndividuallyStable(); !ok {
+				t.Errorf("испытание %d: равновесие улучшающих ответов не IS: %v", trial, dev)
+			}
+			if ok, dev := hg.IsContractuallyIndividuallyStable(); !ok {
+				t.Errorf("испытание %d: равновесие улучшающих ответов не CIS: %v", trial, dev)
+			}
+		}
+	}
+}
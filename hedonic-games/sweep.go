@@ -0,0 +1,152 @@
+// sweep.go - перебор параметров по сетке на пуле воркеров
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// SweepGrid - декларативная сетка: алгоритм × K × α × β × зерно.
+// β перебирается только у ML, у остальных алгоритмов берётся из Base;
+// у Louvain и Leiden нет ни α, ни K - для них берутся значения из Base.
+// Остальные поля запуска (итерации, динамика, ограничения...) берутся из Base
+type SweepGrid struct {
+	Algorithms []string
+	Alphas     []float64
+	Betas      []float64
+	Ks         []int
+	Seeds      []int64 // пусто - одно зерно на ячейку (см. ResolveSweepSeeds)
+	Base       RunConfig
+}
+
+// Cells разворачивает сетку в список запусков в фиксированном порядке
+func (grid SweepGrid) Cells() []RunConfig {
+	seeds := grid.Seeds
+	if len(seeds) == 0 {
+		seeds = []int64{grid.Base.Seed}
+	}
+	ks := grid.Ks
+	if len(ks) == 0 {
+		ks = []int{grid.Base.TargetK}
+	}
+
+	var cells []RunConfig
+	for _, algo := range grid.Algorithms {
+		betas := grid.Betas
+		if algo != "ml" || len(betas) == 0 {
+			betas = []float64{grid.Base.Beta}
+		}
+		algoKs, alphas := ks, grid.Alphas
+		if algo == "louvain" || algo == "leiden" {
+			algoKs, alphas = []int{grid.Base.TargetK}, []float64{grid.Base.Alpha}
+		}
+		for _, k := range algoKs {
+			for _, alpha := range alphas {
+				for _, beta := range betas {
+					for _, seed := range seeds {
+						cfg := grid.Base
+						cfg.Algorithm = algo
+						cfg.Alpha = alpha
+						cfg.Beta = beta
+						cfg.TargetK = k
+						cfg.Seed = seed
+						cells = append(cells, cfg)
+					}
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// ResolveSweepSeeds назначает зёрна ячейкам без явного зерна: ячейка i получает base+i
+// (base = 0 - случайное). Зёрна назначаются до запуска, поэтому результат
+// не зависит от числа воркеров и порядка их завершения
+func ResolveSweepSeeds(cells []RunConfig, base int64) {
+	base = ResolveSeed(base)
+	for i := range cells {
+		if cells[i].Seed == 0 {
+			cells[i].Seed = base + int64(i)
+		}
+	}
+}
+
+// SweepCell - результат одной ячейки сетки
+type SweepCell struct {
+	Index     int
+	Config    RunConfig
+	Partition *Partition
+	Result    ExperimentResult
+	Err       error
+}
+
+// RunSweep запускает ячейки на пуле из workers воркеров (≤ 0 - по числу процессоров).
+// Каждая ячейка строит своё состояние игры и свой генератор из cfg.Seed
+// (граф общий и только читается), поэтому ячейки независимы.
+// visit вызывается из вызывающей горутины строго в порядке ячеек и может
+// дополнить cell.Result (он попадает в возвращаемый список после visit);
+// первая ошибка останавливает выдачу, возвращаются результаты до неё
+func RunSweep(g *Graph, cells []RunConfig, workers int, visit func(*SweepCell)) ([]ExperimentResult, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(cells))
+
+	jobs := make(chan int)
+	done := make(chan SweepCell, workers)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				partition, result, err := RunExperiment(g, cells[i])
+				select {
+				case done <- SweepCell{Index: i, Config: cells[i], Partition: partition, Result: result, Err: err}:
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range cells {
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	// ячейки завершаются в произвольном порядке - копим их до очереди
+	results := make([]ExperimentResult, 0, len(cells))
+	pending := make(map[int]SweepCell)
+	for next := 0; next < len(cells); {
+		cell := <-done
+		pending[cell.Index] = cell
+		for {
+			cell, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if cell.Err != nil {
+				return results, fmt.Errorf("ячейка %d (%s, α=%g): %w", cell.Index, cell.Config.Algorithm, cell.Config.Alpha, cell.Err)
+			}
+			if visit != nil {
+				visit(&cell)
+			}
+			results = append(results, cell.Result)
+			next++
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+// TestSweepGridCells - β перебирается только у ML, у Louvain нет α и K
+func TestSweepGridCells(t *testing.T) {
+	grid := SweepGrid{
+		Algorithms: []string{"hedonic", "ml", "louvain"},
+		Alphas:     []float64{0.1, 0.5},
+		Betas:      []float64{1, 2},
+		Ks:         []int{-1, 2},
+		Seeds:      []int64{1, 2},
+		Base:       RunConfig{Alpha: 0.3, Beta: 5, TargetK: -1, MaxIterations: 7},
+	}
+	cells := grid.Cells()
+	count := make(map[string]int)
+	for _, cfg := range cells {
+		count[cfg.Algorithm]++
+		if cfg.MaxIterations != 7 {
+			t.Errorf("%s: поле из Base потеряно", cfg.Algorithm)
+		}
+		if cfg.Algorithm != "ml" && cfg.Beta != 5 {
+			t.Errorf("%s: β = %g, ожидалась β из Base", cfg.Algorithm, cfg.Beta)
+		}
+		if cfg.Algorithm == "louvain" && (cfg.Alpha != 0.3 || cfg.TargetK != -1) {
+			t.Errorf("louvain: α = %g, K = %d, ожидались значения из Base", cfg.Alpha, cfg.TargetK)
+		}
+	}
+	if want := map[string]int{"hedonic": 8, "ml": 16, "louvain": 2}; !maps.Equal(count, want) {
+		t.Errorf("ячеек %v, ожидалось %v", count, want)
+	}
+	// порядок: алгоритм, K, α, β, зерно
+	if c := cells[1]; c.Algorithm != "hedonic" || c.TargetK != -1 || c.Alpha != 0.1 || c.Seed != 2 {
+		t.Errorf("вторая ячейка %+v", c)
+	}
+	if c := cells[8]; c.Algorithm != "ml" || c.Beta != 1 || c.Seed != 1 {
+		t.Errorf("первая ячейка ML %+v", c)
+	}
+
+	noSeeds := SweepGrid{Algorithms: []string{"hedonic"}, Alphas: []float64{0.1, 0.2, 0.3}, Base: RunConfig{TargetK: -1}}.Cells()
+	noSeeds[1].Seed = 99
+	ResolveSweepSeeds(noSeeds, 40)
+	if noSeeds[0].Seed != 40 || noSeeds[1].Seed != 99 || noSeeds[2].Seed != 42 {
+		t.Errorf("зёрна %d %d %d, ожидались 40 99 42", noSeeds[0].Seed, noSeeds[1].Seed, noSeeds[2].Seed)
+	}
+}
+
+// TestRunSweepIndependentOfWorkers - результаты и порядок выдачи не зависят от числа воркеров
+func TestRunSweepIndependentOfWorkers(t *testing.T) {
+	g := LoadKarateClub()
+	base := DefaultRunConfig()
+	base.MaxIterations = 30
+	cells := SweepGrid{
+		Algorithms: []string{"hedonic", "ml", "leiden"},
+		Alphas:     []float64{0.2, 0.6},
+		Base:       base,
+	}.Cells()
+	ResolveSweepSeeds(cells, 1)
+
+	run := func(workers int) ([]ExperimentResult, []map[int]int) {
+		var partitions []map[int]int
+		next := 0
+		results, err := RunSweep(g, cells, workers, func(cell *SweepCell) {
+			if cell.Index != next {
+				t.Errorf("visit получил ячейку %d вместо %d", cell.Index, next)
+			}
+			next++
+			partitions = append(partitions, cell.Partition.ToMap())
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results, partitions
+	}
+	serial, serialParts := run(1)
+	parallel, parallelParts := run(4)
+	if len(serial) != len(cells) || len(parallel) != len(cells) {
+		t.Fatalf("результатов %d и %d, ячеек %d", len(serial), len(parallel), len(cells))
+	}
+	for i := range cells {
+		a, b := serial[i], parallel[i]
+		if a.Algorithm != b.Algorithm || a.Parameter != b.Parameter || a.Seed != b.Seed ||
+			a.Potential != b.Potential || a.Modularity != b.Modularity || !maps.Equal(serialParts[i], parallelParts[i]) {
+			t.Errorf("ячейка %d: 1 воркер %+v, 4 воркера %+v", i, a, b)
+		}
+	}
+}
+
+// TestRunSweepStopsOnError - ошибка ячейки возвращается вместе с результатами до неё
+func TestRunSweepStopsOnError(t *testing.T) {
+	cells := []RunConfig{DefaultRunConfig(), DefaultRunConfig(), DefaultRunConfig()}
+	cells[1].Dynamics = "chaos"
+	ResolveSweepSeeds(cells, 1)
+	results, err := RunSweep(LoadKarateClub(), cells, 3, nil)
+	if err == nil || !strings.Contains(err.Error(), "ячейка 1") {
+		t.Fatalf("ошибка %v, ожидалась ошибка ячейки 1", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("результатов до ошибки %d, ожидался 1", len(results))
+	}
+}
@@ -0,0 +1,373 @@
+// temporal.go - сообщества во времени: разбиение каждого снимка графа,
+// сопоставление сообществ соседних снимков и события их жизненного цикла
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Snapshot - снимок графа; узлы разных снимков сопоставляются по именам
+type Snapshot struct {
+	Name     string
+	Graph    *Graph
+	IDToName map[int]string
+}
+
+// TemporalOptions - параметры отслеживания сообществ
+type TemporalOptions struct {
+	WarmStart bool    // начинать динамику снимка с разбиения предыдущего (только hedonic)
+	Threshold float64 // наименьшее сходство Жаккара для связи сообществ (0 - 0.3)
+	Tolerance float64 // относительное изменение размера, до которого сообщество не растёт и не сжимается
+}
+
+func (o TemporalOptions) threshold() float64 {
+	if o.Threshold <= 0 {
+		return 0.3
+	}
+	return o.Threshold
+}
+
+// EventKind - событие жизненного цикла сообщества
+type EventKind string
+
+const (
+	EventBirth       EventKind = "birth"       // у сообщества нет предшественника
+	EventDeath       EventKind = "death"       // у сообщества нет преемника
+	EventGrowth      EventKind = "growth"      // один предшественник, размер вырос
+	EventContraction EventKind = "contraction" // один предшественник, размер уменьшился
+	EventContinue    EventKind = "continue"    // один предшественник, размер в пределах Tolerance
+	EventMerge       EventKind = "merge"       // несколько предшественников
+	EventSplit       EventKind = "split"       // несколько преемников
+)
+
+// TemporalEvent - событие между снимками Step-1 и Step (рождение в первом снимке - Step 0).
+// From - сообщества снимка Step-1, To - снимка Step
+type TemporalEvent struct {
+	Step    int       `json:"step"`
+	Kind    EventKind `json:"event"`
+	From    []int     `json:"from,omitempty"`
+	To      []int     `json:"to,omitempty"`
+	Sizes   []int     `json:"sizes"`   // размеры From, затем To
+	Jaccard float64   `json:"jaccard"` // наибольшее сходство среди связей события (0 у рождения и смерти)
+	Track   int       `json:"track"`   // динамическое сообщество (для merge/split - продолжающее)
+}
+
+// SnapshotResult - разбиение одного снимка
+type SnapshotResult struct {
+	Name      string
+	Graph     *Graph // при тёплом старте - граф живой игры (номера узлов общие для всех снимков)
+	IDToName  map[int]string
+	Partition *Partition
+	Result    ExperimentResult
+	Changed   []int       // тёплый старт: узлы, сменившие сообщество
+	Tracks    map[int]int // сообщество -> динамическое сообщество
+}
+
+// TemporalResult - разбиения снимков и события между ними
+type TemporalResult struct {
+	Snapshots []SnapshotResult
+	Events    []TemporalEvent
+	NumTracks int // число динамических сообществ
+}
+
+// TrackCommunities разбивает снимки по порядку выбранным алгоритмом и связывает
+// сообщества соседних снимков. При WarmStart живая игра переходит к следующему снимку
+// через ApplyUpdates (разница графов по именам узлов) и восстанавливает равновесие
+// от затронутых узлов; иначе каждый снимок решается с нуля (RunExperiment)
+func TrackCommunities(snapshots []Snapshot, cfg RunConfig, opts TemporalOptions) (*TemporalResult, error) {
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("нет снимков")
+	}
+	cfg.Seed = ResolveSeed(cfg.Seed)
+
+	result := &TemporalResult{}
+	var err error
+	if opts.WarmStart {
+		result.Snapshots, err = warmSnapshots(snapshots, cfg)
+	} else {
+		result.Snapshots, err = coldSnapshots(snapshots, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for step := range result.Snapshots {
+		cur := &result.Snapshots[step]
+		if step == 0 {
+			cur.Tracks = make(map[int]int)
+			for _, comm := range cur.Partition.Communities() {
+				cur.Tracks[comm] = result.NumTracks
+				result.Events = append(result.Events, TemporalEvent{
+					Step: 0, Kind: EventBirth, To: []int{comm},
+					Sizes: []int{cur.Partition.Size(comm)}, Track: result.NumTracks,
+				})
+				result.NumTracks++
+			}
+			continue
+		}
+		prev := &result.Snapshots[step-1]
+		events, tracks, numTracks := matchSnapshots(step, prev, cur, result.NumTracks, opts)
+		result.Events = append(result.Events, events...)
+		cur.Tracks, result.NumTracks = tracks, numTracks
+	}
+	return result, nil
+}
+
+// matchSnapshots связывает сообщества снимков prev и cur, если сходство Жаккара
+// их множеств имён узлов не меньше порога, и возвращает события шага, динамические
+// сообщества cur и новое число динамических сообществ. Сообщество cur продолжает
+// динамическое сообщество предшественника, если они лучшие пары друг для друга
+func matchSnapshots(step int, prev, cur *SnapshotResult, numTracks int, opts TemporalOptions) ([]TemporalEvent, map[int]int, int) {
+	curOf := make(map[string]int, cur.Partition.Len())
+	for _, node := range cur.Partition.Nodes() {
+		curOf[cur.IDToName[node]] = cur.Partition.Community(node)
+	}
+	inter := make(map[[2]int]int)
+	for _, node := range prev.Partition.Nodes() {
+		if b, ok := curOf[prev.IDToName[node]]; ok {
+			inter[[2]int{prev.Partition.Community(node), b}]++
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(inter), func(x, y [2]int) int {
+		if x[0] != y[0] {
+			return x[0] - y[0]
+		}
+		return x[1] - y[1]
+	})
+	succ, pred := make(map[int][]int), make(map[int][]int)
+	jaccard := make(map[[2]int]float64)
+	bestSucc, bestPred := make(map[int]int), make(map[int]int)
+	for _, key := range keys {
+		a, b, n := key[0], key[1], inter[key]
+		j := float64(n) / float64(prev.Partition.Size(a)+cur.Partition.Size(b)-n)
+		if j < opts.threshold() {
+			continue
+		}
+		jaccard[key] = j
+		succ[a] = append(succ[a], b)
+		pred[b] = append(pred[b], a)
+		if len(succ[a]) == 1 || j > jaccard[[2]int{a, bestSucc[a]}] {
+			bestSucc[a] = b
+		}
+		if len(pred[b]) == 1 || j > jaccard[[2]int{bestPred[b], b}] {
+			bestPred[b] = a
+		}
+	}
+	maxJaccard := func(from, to []int) float64 {
+		best := 0.0
+		for _, a := range from {
+			for _, b := range to {
+				best = max(best, jaccard[[2]int{a, b}])
+			}
+		}
+		return best
+	}
+	sizes := func(from, to []int) []int {
+		var s []int
+		for _, a := range from {
+			s = append(s, prev.Partition.Size(a))
+		}
+		for _, b := range to {
+			s = append(s, cur.Partition.Size(b))
+		}
+		return s
+	}
+
+	tracks := make(map[int]int)
+	for _, b := range cur.Partition.Communities() {
+		if a, ok := bestPred[b]; ok && bestSucc[a] == b {
+			tracks[b] = prev.Tracks[a]
+		} else {
+			tracks[b] = numTracks
+			numTracks++
+		}
+	}
+
+	var events []TemporalEvent
+	for _, a := range prev.Partition.Communities() {
+		switch from := []int{a}; len(succ[a]) {
+		case 0:
+			events = append(events, TemporalEvent{Step: step, Kind: EventDeath, From: from, Sizes: sizes(from, nil), Track: prev.Tracks[a]})
+		case 1:
+		default:
+			events = append(events, TemporalEvent{Step: step, Kind: EventSplit, From: from, To: succ[a],
+				Sizes: sizes(from, succ[a]), Jaccard: maxJaccard(from, succ[a]), Track: prev.Tracks[a]})
+		}
+	}
+	for _, b := range cur.Partition.Communities() {
+		to := []int{b}
+		event := TemporalEvent{Step: step, To: to, Track: tracks[b]}
+		switch len(pred[b]) {
+		case 0:
+			event.Kind = EventBirth
+		case 1:
+			a := pred[b][0]
+			if len(succ[a]) > 1 {
+				continue // часть разделения
+			}
+			event.From = pred[b]
+			change := float64(cur.Partition.Size(b)-prev.Partition.Size(a)) / float64(prev.Partition.Size(a))
+			switch {
+			case change > opts.Tolerance:
+				event.Kind = EventGrowth
+			case change < -opts.Tolerance:
+				event.Kind = EventContraction
+			default:
+				event.Kind = EventContinue
+			}
+		default:
+			event.Kind = EventMerge
+			event.From = pred[b]
+		}
+		event.Sizes = sizes(event.From, to)
+		event.Jaccard = maxJaccard(event.From, to)
+		events = append(events, event)
+	}
+	return events, tracks, numTracks
+}
+
+// coldSnapshots - каждый снимок с нуля
+func coldSnapshots(snapshots []Snapshot, cfg RunConfig) ([]SnapshotResult, error) {
+	results := make([]SnapshotResult, len(snapshots))
+	for i, s := range snapshots {
+		partition, res, err := RunExperiment(s.Graph, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		results[i] = SnapshotResult{Name: s.Name, Graph: s.Graph, IDToName: s.IDToName, Partition: partition, Result: res}
+	}
+	return results, nil
+}
+
+// warmSnapshots - одна живая игра на все снимки; номера узлов - по первому снимку,
+// новые имена получают следующие номера
+func warmSnapshots(snapshots []Snapshot, cfg RunConfig) ([]SnapshotResult, error) {
+	if cfg.Algorithm != "hedonic" || (cfg.Dynamics != "" && cfg.Dynamics != "potential") {
+		return nil, fmt.Errorf("тёплый старт - только для гедонической игры с динамикой potential")
+	}
+
+	idToName := make(map[int]string, len(snapshots[0].IDToName))
+	nameToID := make(map[string]int, len(snapshots[0].IDToName))
+	for id, name := range snapshots[0].IDToName {
+		idToName[id] = name
+		nameToID[name] = id
+	}
+
+	results := make([]SnapshotResult, len(snapshots))
+	var hg *HedonicGame
+	for i, s := range snapshots {
+		start := time.Now()
+		var changed []int
+		if i == 0 {
+			var err error
+			if hg, err = newHedonicGameFromConfig(s.Graph.Clone(), cfg); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.Name, err)
+			}
+			if _, err = hg.FindNashStablePartition_WithPotential(cfg.MaxIterations, cfg.UseModularity); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.Name, err)
+			}
+		} else {
+			if s.Graph.Directed != hg.G.Directed {
+				return nil, fmt.Errorf("%s: снимки должны быть все ориентированными или все неориентированными", s.Name)
+			}
+			updates := snapshotDiff(&hg.G, s, nameToID, idToName)
+			var err error
+			if changed, err = hg.ApplyUpdates(updates, cfg.MaxIterations, cfg.UseModularity); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.Name, err)
+			}
+		}
+
+		g := hg.G.Clone()
+		partition := hg.Partition.Clone()
+		res := NewExperimentResult(
+			"Hedonic_WarmStart",
+			"Hedonic",
+			cfg.Alpha,
+			g,
+			partition,
+			hg.ComputePotentialCurrent(cfg.UseModularity),
+			ComputeModularity(g, partition),
+			hg.Iterations,
+			hg.Iterations,
+			time.Since(start).Seconds(),
+		)
+		res.Seed = cfg.Seed
+		names := make(map[int]string, g.NumNodes())
+		for _, node := range g.GetNodeList() {
+			names[node] = idToName[node]
+		}
+		results[i] = SnapshotResult{Name: s.Name, Graph: g, IDToName: names, Partition: partition, Result: res, Changed: changed}
+	}
+	return results, nil
+}
+
+// snapshotDiff - изменения, превращающие граф живой игры live в снимок s
+// (узлы сопоставляются по именам; новым именам выдаются новые номера)
+func snapshotDiff(live *Graph, s Snapshot, nameToID map[string]int, idToName map[int]string) []GraphUpdate {
+	nextID := 0
+	for id := range idToName {
+		nextID = max(nextID, id+1)
+	}
+	toLive := make(map[int]int, s.Graph.NumNodes()) // узел снимка -> узел живой игры
+	present := make(map[int]bool, s.Graph.NumNodes())
+	var updates []GraphUpdate
+	for _, node := range s.Graph.GetNodeList() {
+		name := s.IDToName[node]
+		id, ok := nameToID[name]
+		if !ok {
+			id = nextID
+			nextID++
+			nameToID[name] = id
+			idToName[id] = name
+		}
+		toLive[node] = id
+		present[id] = true
+		if !live.Nodes[id] {
+			updates = append(updates, GraphUpdate{Kind: UpdateAddNode, U: id})
+		}
+	}
+	for _, node := range live.GetNodeList() {
+		if !present[node] {
+			updates = append(updates, GraphUpdate{Kind: UpdateRemoveNode, U: node})
+		}
+	}
+
+	// рёбра (в ориентированном графе - дуги) снимка в номерах живой игры
+	arcs := make(map[[2]int]float64)
+	for _, u := range s.Graph.GetNodeList() {
+		for _, v := range s.Graph.OutNeighbors(u) {
+			lu, lv := toLive[u], toLive[v]
+			if !s.Graph.Directed && lu > lv {
+				continue
+			}
+			arcs[[2]int{lu, lv}] = s.Graph.ArcWeight(u, v)
+		}
+	}
+	for _, u := range live.GetNodeList() {
+		for _, v := range live.OutNeighbors(u) {
+			if !present[u] || !present[v] || (!live.Directed && u > v) {
+				continue // рёбра удаляемых узлов уйдут вместе с ними
+			}
+			if _, ok := arcs[[2]int{u, v}]; !ok {
+				updates = append(updates, GraphUpdate{Kind: UpdateRemoveEdge, U: u, V: v})
+			}
+		}
+	}
+	keys := slices.SortedFunc(maps.Keys(arcs), func(a, b [2]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[1] - b[1]
+	})
+	for _, key := range keys {
+		u, v := key[0], key[1]
+		if w := arcs[key]; !live.Nodes[u] || !live.Nodes[v] || !live.HasEdge(u, v) || live.ArcWeight(u, v) != w {
+			updates = append(updates, GraphUpdate{Kind: UpdateAddEdge, U: u, V: v, Weight: w})
+		}
+	}
+	return updates
+}
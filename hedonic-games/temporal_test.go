@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// snapshotOf - результат снимка с сообществами groups (узлы нумеруются подряд)
+func snapshotOf(groups ...[]string) *SnapshotResult {
+	s := &SnapshotResult{IDToName: make(map[int]string), Partition: NewPartition(0)}
+	node := 0
+	for comm, group := range groups {
+		for _, name := range group {
+			s.IDToName[node] = name
+			s.Partition.Set(node, comm)
+			node++
+		}
+	}
+	return s
+}
+
+// namedSnapshot - снимок графа с именами узлов n0, n1, ...
+func namedSnapshot(name string, g *Graph) Snapshot {
+	names := make(map[int]string, g.NumNodes())
+	for _, node := range g.GetNodeList() {
+		names[node] = fmt.Sprintf("n%d", node)
+	}
+	return Snapshot{Name: name, Graph: g, IDToName: names}
+}
+
+// eventKinds - события шага по видам
+func eventKinds(events []TemporalEvent) map[EventKind][]TemporalEvent {
+	kinds := make(map[EventKind][]TemporalEvent)
+	for _, e := range events {
+		kinds[e.Kind] = append(kinds[e.Kind], e)
+	}
+	return kinds
+}
+
+// TestMatchSnapshotsEvents - все виды событий на снимках с известным ответом
+func TestMatchSnapshotsEvents(t *testing.T) {
+	prev := snapshotOf(
+		[]string{"a", "b", "c", "d", "e", "f"}, // 0: разделится пополам
+		[]string{"g", "h", "i"},                // 1: сольётся с 2
+		[]string{"j", "k", "l", "m"},           // 2
+		[]string{"n", "o"},                     // 3: исчезнет
+		[]string{"r", "s", "t", "u"},           // 4: вырастет
+		[]string{"w", "x", "y", "z"},           // 5: сожмётся
+		[]string{"A", "B", "C", "D", "E"},      // 6: не изменится
+	)
+	prev.Tracks = map[int]int{0: 10, 1: 11, 2: 12, 3: 13, 4: 14, 5: 15, 6: 16}
+	cur := snapshotOf(
+		[]string{"a", "b", "c"},                     // 0
+		[]string{"d", "e", "f"},                     // 1
+		[]string{"g", "h", "i", "j", "k", "l", "m"}, // 2
+		[]string{"p", "q"},                          // 3: новое
+		[]string{"r", "s", "t", "u", "v"},           // 4
+		[]string{"w", "x", "y"},                     // 5
+		[]string{"A", "B", "C", "D", "E"},           // 6
+	)
+
+	events, tracks, numTracks := matchSnapshots(1, prev, cur, 17, TemporalOptions{Tolerance: 0.1})
+	kinds := eventKinds(events)
+	check := func(kind EventKind, from, to []int, track int) {
+		t.Helper()
+		for _, e := range kinds[kind] {
+			if slices.Equal(e.From, from) && slices.Equal(e.To, to) {
+				if e.Track != track {
+					t.Errorf("%s %v -> %v: track %d, ожидался %d", kind, from, to, e.Track, track)
+				}
+				return
+			}
+		}
+		t.Errorf("нет события %s %v -> %v среди %v", kind, from, to, events)
+	}
+	check(EventSplit, []int{0}, []int{0, 1}, 10)
+	check(EventMerge, []int{1, 2}, []int{2}, 12) // продолжает лучший предшественник (4/7 > 3/7)
+	check(EventDeath, []int{3}, nil, 13)
+	check(EventBirth, nil, []int{3}, tracks[3])
+	check(EventGrowth, []int{4}, []int{4}, 14)
+	check(EventContraction, []int{5}, []int{5}, 15)
+	check(EventContinue, []int{6}, []int{6}, 16)
+	if len(events) != 7 {
+		t.Errorf("событий %d, ожидалось 7: %v", len(events), events)
+	}
+
+	// при равном сходстве половин разделения трек продолжает первая
+	want := map[int]int{0: 10, 2: 12, 4: 14, 5: 15, 6: 16}
+	for comm, track := range want {
+		if tracks[comm] != track {
+			t.Errorf("сообщество %d: track %d, ожидался %d", comm, tracks[comm], track)
+		}
+	}
+	if tracks[1] < 17 || tracks[3] < 17 || tracks[1] == tracks[3] || numTracks != 19 {
+		t.Errorf("новые треки %d и %d, всего %d; ожидались два новых из 17, 18", tracks[1], tracks[3], numTracks)
+	}
+
+	merge := kinds[EventMerge][0]
+	if !slices.Equal(merge.Sizes, []int{3, 4, 7}) || merge.Jaccard != 4.0/7 {
+		t.Errorf("слияние: размеры %v и сходство %g", merge.Sizes, merge.Jaccard)
+	}
+}
+
+// TestMatchSnapshotsThreshold - связь ниже порога Жаккара - это смерть и рождение
+func TestMatchSnapshotsThreshold(t *testing.T) {
+	prev := snapshotOf([]string{"a", "b", "c", "d"})
+	prev.Tracks = map[int]int{0: 0}
+	cur := snapshotOf([]string{"a", "x", "y", "z"}) // сходство 1/7
+	for _, tt := range []struct {
+		threshold float64
+		want      []EventKind
+	}{
+		{0, []EventKind{EventDeath, EventBirth}}, // порог по умолчанию 0.3
+		{0.1, []EventKind{EventContinue}},
+	} {
+		events, _, _ := matchSnapshots(1, prev, cur, 1, TemporalOptions{Threshold: tt.threshold})
+		var got []EventKind
+		for _, e := range events {
+			got = append(got, e.Kind)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("порог %g: события %v, ожидались %v", tt.threshold, got, tt.want)
+		}
+	}
+}
+
+// TestTrackCommunities - сквозной прогон по снимкам: две клики сливаются, одна исчезает
+func TestTrackCommunities(t *testing.T) {
+	first := ringOfCliques(4, 5)
+	second := ringOfCliques(4, 5)
+	for u := 0; u < 5; u++ {
+		for v := 5; v < 10; v++ {
+			second.AddEdge(u, v)
+		}
+	}
+	for u := 15; u < 20; u++ {
+		second.RemoveNode(u)
+	}
+	snapshots := []Snapshot{namedSnapshot("t0", first), namedSnapshot("t1", second)}
+
+	cfg := RunConfig{Algorithm: "louvain", Seed: 1}
+	result, err := TrackCommunities(snapshots, cfg, TemporalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[EventKind]int)
+	for _, e := range result.Events {
+		if e.Step == 1 {
+			counts[e.Kind]++
+		}
+	}
+	want := map[EventKind]int{EventMerge: 1, EventDeath: 1, EventContinue: 1}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("события шага 1: %v, ожидались %v", counts, want)
+	}
+	if result.NumTracks != 4 {
+		t.Errorf("треков %d, ожидалось 4 (слияние продолжает один из старых)", result.NumTracks)
+	}
+
+	if _, err := TrackCommunities(snapshots, cfg, TemporalOptions{WarmStart: true}); err == nil {
+		t.Error("тёплый старт Louvain должен быть отклонён")
+	}
+	warm, err := TrackCommunities(snapshots, RunConfig{Algorithm: "hedonic", Alpha: 0.5, MaxIterations: 100, Seed: 1, TargetK: -1},
+		TemporalOptions{WarmStart: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := warm.Snapshots[1]
+	if last.Partition.Len() != second.NumNodes() {
+		t.Errorf("тёплый старт: в разбиении %d узлов, в снимке %d", last.Partition.Len(), second.NumNodes())
+	}
+	for _, comm := range last.Partition.Communities() {
+		if track, ok := last.Tracks[comm]; !ok || track >= warm.NumTracks {
+			t.Errorf("тёплый старт: сообщество %d без трека (%d из %d)", comm, track, warm.NumTracks)
+		}
+	}
+}